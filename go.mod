@@ -14,6 +14,7 @@ require (
 	github.com/rs/zerolog v1.18.0
 	github.com/silenceper/log v0.0.0-20171204144354-e5ac7fa8a76a
 	github.com/sirupsen/logrus v1.6.0
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/stretchr/objx v0.2.0 // indirect
 	github.com/stretchr/testify v1.7.0
 	github.com/tidwall/gjson v1.6.0
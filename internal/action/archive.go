@@ -20,14 +20,22 @@ func (a ActionArchive) Run(ctx context.Context) error {
 	err := xcode.ParseXCodeBuildError(a.archive(ctx))
 	if err != nil {
 		log.Err(err)
+		return err
+	}
+
+	// Packaging the debug symbols of the archive, the symbols command running it again
+	if a.API.Config.Symbols.Skip {
+		return nil
 	}
 
-	return err
+	return NewActionSymbols(a.API).Run(ctx)
 }
 
 func (a ActionArchive) archive(ctx context.Context) error {
 	log.Info().Msg("Archiving")
-	// defer deletion of the keychain
+	// defer deletion of the installed provisioning profiles and of the keychain, the
+	// keychain being deleted first
+	defer a.API.ProvisioningService.Cleanup()
 	defer a.API.KeyChain.Delete(ctx)
 
 	// Resolving signature configuration
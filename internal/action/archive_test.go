@@ -0,0 +1,69 @@
+package action
+
+import (
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/path"
+	"dothething/internal/utiltest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// archiveKeyChain the keychain of the run, only deleted by the archive
+type archiveKeyChain struct{ api.KeyChain }
+
+func (archiveKeyChain) Delete(ctx context.Context) error { return nil }
+
+// archiveProvisioning the installed provisioning profiles, only removed by the archive
+type archiveProvisioning struct{ api.ProvisioningService }
+
+func (archiveProvisioning) Cleanup() error { return nil }
+
+// archiveBuild the project argument of xcodebuild
+type archiveBuild struct{ api.BuildService }
+
+func (archiveBuild) GetArg() string { return "-project" }
+
+func TestArchiveCollectsSymbols(t *testing.T) {
+	tests := []struct {
+		name     string
+		skip     bool
+		expected []string
+	}{
+		{"collect", false, []string{"/project", "/vendor"}},
+		{"skip", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup:
+			exec := new(utiltest.MockExecutor)
+			cmd := new(utiltest.MockExecutorCmd)
+			cmd.On("StdoutPipe").Return("", nil)
+			cmd.On("StderrPipe").Return("", nil)
+			cmd.On("Start").Return(nil)
+			cmd.On("Wait").Return(nil)
+			exec.On("XCodeCommandContext", mock.Anything, mock.Anything).Return(cmd, nil)
+
+			var searchPaths []string
+			a := &api.API{Config: &api.Config{Path: "/project/Dummy.xcodeproj"}, Exec: exec}
+			a.Config.Symbols = api.SymbolsConfig{SearchPaths: []string{"/vendor"}, Skip: tt.skip}
+			a.ArchiveService = symbolsArchives{}
+			a.BuildService = archiveBuild{}
+			a.KeyChain = archiveKeyChain{}
+			a.PathService = path.NewPathService(a)
+			a.ProvisioningService = archiveProvisioning{}
+			a.SignatureService = packSignatureService{}
+			a.SymbolsService = symbolsCollector{&searchPaths}
+
+			// when:
+			err := NewArchive(a).Run(context.Background())
+
+			// then:
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, searchPaths)
+		})
+	}
+}
@@ -3,7 +3,14 @@ package action
 import (
 	"context"
 	"dothething/internal/api"
+	"dothething/internal/ota"
+	"dothething/internal/signature"
 	"dothething/internal/xcode"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -26,25 +33,237 @@ func (a actionPackage) Run(ctx context.Context) error {
 }
 
 func (a actionPackage) pack(ctx context.Context) error {
-	// defer deletion of the keychain
+	// defer deletion of the installed provisioning profiles and of the keychain, the
+	// keychain being deleted first
+	defer a.API.ProvisioningService.Cleanup()
 	defer a.API.KeyChain.Delete(ctx)
 
+	// Checking the archive before resolving anything
+	if err := a.checkArchive(ctx); err != nil {
+		return err
+	}
+
 	// Resolving signature
 	if err := a.API.SignatureService.Run(ctx); err != nil {
 		return err
 	}
 
+	// Exporting to several distribution methods
+	if len(a.API.Config.Methods) > 0 {
+		return a.packMethods(ctx, a.API.Config.Methods)
+	}
+
 	// Compute export options plist
 	if err := a.API.ExportOptionService.Compute(); err != nil {
 		return err
 	}
 
+	if err := a.export(ctx, a.API.PathService.ExportPList(), a.API.PathService.Package()); err != nil {
+		return err
+	}
+
+	if err := a.audit(ctx, a.API.PathService.Package()); err != nil {
+		return err
+	}
+
+	if err := a.measure(ctx, a.API.PathService.Package()); err != nil {
+		return err
+	}
+
+	return a.distribute(ctx, a.targetMethod(), a.API.PathService.ExportPList(), a.API.PathService.Package())
+}
+
+// checkArchive reads the archive to export, warning about the products without debug symbols
+func (a actionPackage) checkArchive(ctx context.Context) error {
+	path := a.API.PathService.Archive()
+	archive, err := a.API.ArchiveService.Read(ctx, path)
+	if err != nil {
+		return fmt.Errorf("invalid archive %v (%v)", path, err)
+	}
+
+	log.Info().
+		Str("BundleIdentifier", archive.ApplicationProperties.BundleIdentifier).
+		Str("Version", archive.ApplicationProperties.ShortVersion).
+		Str("Build", archive.ApplicationProperties.Version).
+		Time("CreationDate", archive.CreationDate).
+		Msg("Exporting archive")
+
+	for _, p := range archive.MissingSymbols() {
+		log.Warn().Str("Product", p).Msg("The archive does not contain the debug symbols of the product")
+	}
+
+	return nil
+}
+
+// targetMethod the distribution method of the provisioning profile resolved for the target
+func (a actionPackage) targetMethod() string {
+	for _, e := range *a.API.SignatureService.GetConfiguration() {
+		if e.TargetName == a.API.Config.Target {
+			return signature.ProvisioningMethod(e.Config.ProvisioningProfile)
+		}
+	}
+
+	return ""
+}
+
+// distribute generates the over-the-air distribution bundle, for the methods supporting it,
+// from the manifest of the merged export options
+func (a actionPackage) distribute(ctx context.Context, method, exportPList, exportPath string) error {
+	if method != "ad-hoc" && method != "enterprise" {
+		return nil
+	}
+
+	m, err := ota.ReadManifest(exportPList)
+	if err != nil {
+		return fmt.Errorf("failed to read the export options %v (%v)", exportPList, err)
+	}
+
+	if m == nil || m.AppURL == "" {
+		log.Warn().
+			Str("Method", method).
+			Msg("Skipping the over-the-air distribution, the manifest appURL is not configured")
+		return nil
+	}
+
+	_, err = a.API.OTAService.Generate(ctx, exportPath, m)
+	return err
+}
+
+// audit checks the code signature of every bundle of the exported packages, failing with a
+// report of the bundles having issues
+func (a actionPackage) audit(ctx context.Context, exportPath string) error {
+	packages, err := filepath.Glob(filepath.Join(exportPath, "*.ipa"))
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, p := range packages {
+		report, err := a.API.BundleAuditor.Audit(ctx, p)
+		if err != nil {
+			return err
+		}
+
+		if !report.Failed() {
+			continue
+		}
+
+		for _, b := range report.Bundles {
+			if len(b.Issues) == 0 {
+				continue
+			}
+
+			for _, issue := range b.Issues {
+				log.Error().
+					Str("Package", filepath.Base(p)).
+					Str("Bundle", b.Path).
+					Str("BundleIdentifier", b.BundleIdentifier).
+					Msg(issue)
+			}
+			failed = append(failed, b.BundleIdentifier)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("code signing audit failed for %v", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// exportResult the outcome of the export of one distribution method
+type exportResult struct {
+	Duration time.Duration
+	Err      error
+	Method   string
+	Path     string
+}
+
+// packMethods exports the archive once per distribution method, the exports being run
+// concurrently
+func (a actionPackage) packMethods(ctx context.Context, methods []string) error {
+	results := make([]exportResult, len(methods))
+
+	// The signing assets are resolved and installed one method after the other, the keychain
+	// not supporting concurrent imports
+	for i, m := range methods {
+		results[i] = exportResult{Method: m, Path: a.API.PathService.PackageFor(m)}
+
+		cfg, err := a.API.SignatureService.ForMethod(ctx, m)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		results[i].Err = a.API.ExportOptionService.ComputeForMethod(m, cfg)
+	}
+
+	var wg sync.WaitGroup
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *exportResult) {
+			defer wg.Done()
+
+			start := time.Now()
+			r.Err = xcode.ParseXCodeBuildError(
+				a.export(ctx, a.API.PathService.ExportPListFor(r.Method), r.Path),
+			)
+			if r.Err == nil {
+				r.Err = a.audit(ctx, r.Path)
+			}
+			if r.Err == nil {
+				r.Err = a.measure(ctx, r.Path)
+			}
+			if r.Err == nil {
+				r.Err = a.distribute(ctx, r.Method, a.API.PathService.ExportPListFor(r.Method), r.Path)
+			}
+			r.Duration = time.Since(start)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	return summarize(results)
+}
+
+// summarize logs the result of each export, failing if any of them failed
+func summarize(results []exportResult) error {
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			log.Error().
+				Str("Method", r.Method).
+				AnErr("Error", r.Err).
+				Msg("Export failed")
+			failed = append(failed, r.Method)
+			continue
+		}
+
+		log.Info().
+			Str("Method", r.Method).
+			Str("Path", r.Path).
+			Dur("Duration", r.Duration).
+			Msg("Export succeeded")
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to export the archive for %v", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// export runs the export of the archive with the export options, to the destination
+func (a actionPackage) export(ctx context.Context, exportPList, dst string) error {
 	// The arguments
 	args := []string{
 		xcode.ActionPackage,
 		xcode.FlagArchivePath, a.API.PathService.Archive(),
-		xcode.FlagExportPath, a.API.PathService.Package(),
-		xcode.FlagExportOptionsPlist, a.API.PathService.ExportPList(),
+		xcode.FlagExportPath, dst,
+		xcode.FlagExportOptionsPlist, exportPList,
 		a.API.PathService.ObjRoot(),
 		// a.API.PathService.SymRoot(),
 	}
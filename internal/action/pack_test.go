@@ -0,0 +1,223 @@
+package action
+
+import (
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/path"
+	"dothething/internal/utiltest"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"howett.net/plist"
+)
+
+type packSuite struct {
+	suite.Suite
+	API      *api.API
+	dir      string
+	exec     *utiltest.MockExecutor
+	computed map[string][]api.TargetSignatureConfig
+	subject  actionPackage
+}
+
+func TestPackSuite(t *testing.T) {
+	suite.Run(t, new(packSuite))
+}
+
+func (s *packSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "pack")
+	s.Require().NoError(err)
+	s.dir = dir
+
+	s.exec = new(utiltest.MockExecutor)
+	s.computed = map[string][]api.TargetSignatureConfig{}
+
+	s.API = &api.API{Config: &api.Config{
+		Path:          filepath.Join(dir, "project", "Dummy.xcodeproj"),
+		Target:        "App",
+		Scheme:        "Dummy",
+		Configuration: "Release",
+	}}
+	s.API.Exec = s.exec
+	s.API.PathService = path.NewPathService(s.API)
+	s.API.SignatureService = packSignatureService{}
+	s.API.ExportOptionService = packExportOptions{s}
+	s.subject = actionPackage{s.API}
+}
+
+func (s *packSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+// packSignatureService resolves the signature configuration of the ad-hoc and development
+// methods only
+type packSignatureService struct{}
+
+func (packSignatureService) Run(ctx context.Context) error                  { return nil }
+func (packSignatureService) GetConfiguration() *[]api.TargetSignatureConfig { return nil }
+func (packSignatureService) ImportCertificate(ctx context.Context, c *api.P12Certificate) error {
+	return nil
+}
+func (packSignatureService) ForMethod(ctx context.Context, method string) ([]api.TargetSignatureConfig, error) {
+	if method == "app-store" {
+		return nil, errors.New("no provisioning profile of the method")
+	}
+
+	return []api.TargetSignatureConfig{{TargetName: "App", BundleIdentifier: "com.dummy." + method}}, nil
+}
+
+// packExportOptions records the signature configuration of each method
+type packExportOptions struct{ s *packSuite }
+
+func (packExportOptions) Compute() error { return nil }
+func (o packExportOptions) ComputeForMethod(method string, cfg []api.TargetSignatureConfig) error {
+	o.s.computed[method] = cfg
+	return o.s.writeExportOptions(o.s.API.PathService.ExportPListFor(method), api.ExportOptions{Method: method})
+}
+
+// writeExportOptions writes the merged export options plist
+func (s *packSuite) writeExportOptions(path string, o api.ExportOptions) error {
+	b, err := plist.Marshal(o, plist.XMLFormat)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// mockExport records the export and plist paths of the exports
+func (s *packSuite) mockExport() map[string]string {
+	var mu sync.Mutex
+	res := map[string]string{}
+
+	cmd := new(utiltest.MockExecutorCmd)
+	cmd.On("StdoutPipe").Return("", nil)
+	cmd.On("StderrPipe").Return("", nil)
+	cmd.On("Start").Return(nil)
+	cmd.On("Wait").Return(nil)
+
+	s.exec.On("XCodeCommandContext", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		a := args.Get(1).([]string)
+		mu.Lock()
+		defer mu.Unlock()
+		res[a[4]] = a[6]
+	}).Return(cmd, nil)
+
+	return res
+}
+
+func (s *packSuite) TestPackMethods() {
+	// setup:
+	exports := s.mockExport()
+
+	// when:
+	err := s.subject.packMethods(context.Background(), []string{"ad-hoc", "development"})
+
+	// then: each method is exported with its own export options to its own folder
+	s.NoError(err)
+	s.Equal(map[string]string{
+		s.API.PathService.PackageFor("ad-hoc"):      s.API.PathService.ExportPListFor("ad-hoc"),
+		s.API.PathService.PackageFor("development"): s.API.PathService.ExportPListFor("development"),
+	}, exports)
+	s.Equal("com.dummy.ad-hoc", s.computed["ad-hoc"][0].BundleIdentifier)
+	s.Equal("com.dummy.development", s.computed["development"][0].BundleIdentifier)
+	s.NotEqual(s.API.PathService.PackageFor("ad-hoc"), s.API.PathService.PackageFor("development"))
+}
+
+func (s *packSuite) TestPackMethodsWithUnresolvedMethod() {
+	// setup:
+	exports := s.mockExport()
+
+	// when:
+	err := s.subject.packMethods(context.Background(), []string{"app-store", "ad-hoc"})
+
+	// then: the other methods are still exported
+	s.EqualError(err, "failed to export the archive for app-store")
+	s.NotContains(s.computed, "app-store")
+	s.Equal(map[string]string{
+		s.API.PathService.PackageFor("ad-hoc"): s.API.PathService.ExportPListFor("ad-hoc"),
+	}, exports)
+}
+
+// packAuditor reports an issue for the bundles of the failing packages
+type packAuditor struct{ failing string }
+
+func (a packAuditor) Audit(ctx context.Context, path string) (*api.AuditReport, error) {
+	res := &api.AuditReport{Path: path, Bundles: []api.BundleAudit{{BundleIdentifier: "com.dummy.app"}}}
+	if filepath.Base(path) == a.failing {
+		res.Bundles = append(res.Bundles, api.BundleAudit{
+			BundleIdentifier: "com.dummy.app.widget",
+			Issues:           []string{"invalid signature"},
+		})
+	}
+
+	return res, nil
+}
+
+func (s *packSuite) TestAudit() {
+	// setup:
+	dir, err := ioutil.TempDir("", "pack")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+
+	for _, f := range []string{"Dummy.ipa", "Other.ipa"} {
+		s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, f), nil, 0600))
+	}
+
+	// when:
+	s.API.BundleAuditor = packAuditor{}
+	err = s.subject.audit(context.Background(), dir)
+
+	// then:
+	s.NoError(err)
+
+	// when:
+	s.API.BundleAuditor = packAuditor{failing: "Other.ipa"}
+	err = s.subject.audit(context.Background(), dir)
+
+	// then: only the bundles with issues are reported
+	s.EqualError(err, "code signing audit failed for com.dummy.app.widget")
+}
+
+// packOTAService records the manifests of the generations
+type packOTAService struct{ manifests *[]api.ExportManifest }
+
+func (o packOTAService) Generate(ctx context.Context, exportPath string, m *api.ExportManifest) (string, error) {
+	*o.manifests = append(*o.manifests, *m)
+	return filepath.Join(exportPath, "ota"), nil
+}
+
+func (s *packSuite) TestDistribute() {
+	// setup: the manifest of the base export options, merged into the export plist
+	var manifests []api.ExportManifest
+	s.API.OTAService = packOTAService{&manifests}
+	manifest := api.ExportManifest{AppURL: "https://example.com/Dummy.ipa"}
+	exportPList := s.API.PathService.ExportPListFor("ad-hoc")
+	s.Require().NoError(s.writeExportOptions(exportPList, api.ExportOptions{Method: "ad-hoc", Manifest: &manifest}))
+
+	// when:
+	err := s.subject.distribute(context.Background(), "ad-hoc", exportPList, s.API.PathService.PackageFor("ad-hoc"))
+
+	// then:
+	s.NoError(err)
+	s.Equal([]api.ExportManifest{manifest}, manifests)
+
+	// when: the export options without manifest
+	exportPList = s.API.PathService.ExportPListFor("enterprise")
+	s.Require().NoError(s.writeExportOptions(exportPList, api.ExportOptions{Method: "enterprise"}))
+	err = s.subject.distribute(context.Background(), "enterprise", exportPList, s.API.PathService.PackageFor("enterprise"))
+
+	// then: the distribution is skipped
+	s.NoError(err)
+	s.Len(manifests, 1)
+}
@@ -21,6 +21,9 @@ func NewActionRunTest(api *api.API) api.Action {
 func (a actionRunTest) Run(ctx context.Context) error {
 	log.Info().Msg("Running unit tests")
 
+	// defer removal of the provisioning profiles installed by the signature resolution
+	defer a.API.ProvisioningService.Cleanup()
+
 	if err := a.API.SignatureService.Run(ctx); err != nil {
 		return err
 	}
@@ -0,0 +1,103 @@
+package action
+
+import (
+	"context"
+	"dothething/internal/api"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SizeReportSuffix the suffix of the size report written next to each package
+const SizeReportSuffix = "-size.json"
+
+// measure writes the size report of the exported packages next to them, failing when the
+// growth compared to the baseline exceeds the threshold
+func (a actionPackage) measure(ctx context.Context, exportPath string) error {
+	packages, err := filepath.Glob(filepath.Join(exportPath, "*.ipa"))
+	if err != nil {
+		return err
+	}
+
+	cfg := a.API.Config.Size
+	var baseline *api.SizeReport
+	if cfg.Baseline != "" {
+		if baseline, err = readSizeReport(cfg.Baseline); err != nil {
+			return fmt.Errorf("invalid size baseline %v (%v)", cfg.Baseline, err)
+		}
+	}
+
+	var failed []string
+	for _, p := range packages {
+		report, err := a.API.SizeAnalyzer.Analyze(ctx, p)
+		if err != nil {
+			return err
+		}
+
+		if err := writeSizeReport(report, strings.TrimSuffix(p, ".ipa")+SizeReportSuffix); err != nil {
+			return err
+		}
+
+		log.Info().
+			Str("Package", filepath.Base(p)).
+			Int64("CompressedSize", report.Compressed).
+			Int64("UncompressedSize", report.Uncompressed).
+			Msg("Package size")
+
+		if baseline == nil {
+			continue
+		}
+
+		diff := report.Diff(*baseline)
+		for _, i := range diff.Items {
+			log.Info().
+				Str("Kind", i.Kind).
+				Str("Name", i.Name).
+				Int64("CompressedDelta", i.Compressed).
+				Int64("UncompressedDelta", i.Uncompressed).
+				Msg("Size changed since the baseline")
+		}
+
+		if cfg.Threshold > 0 && diff.Growth > cfg.Threshold {
+			log.Error().
+				Str("Package", filepath.Base(p)).
+				Float64("Growth", diff.Growth).
+				Float64("Threshold", cfg.Threshold).
+				Msg("The package grew beyond the threshold")
+			failed = append(failed, filepath.Base(p))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("size growth exceeds %v%% for %v", cfg.Threshold, strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+func readSizeReport(path string) (*api.SizeReport, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var res api.SizeReport
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func writeSizeReport(report *api.SizeReport, path string) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0644)
+}
@@ -0,0 +1,80 @@
+package action
+
+import (
+	"context"
+	"dothething/internal/api"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// NewActionSymbols create the action collecting and packaging the debug symbols of the archive
+func NewActionSymbols(api *api.API) api.Action {
+	return actionSymbols{api}
+}
+
+type actionSymbols struct {
+	*api.API
+}
+
+func (a actionSymbols) Run(ctx context.Context) error {
+	err := a.symbols(ctx)
+	if err != nil {
+		log.Error().AnErr("Error", err).Msg("Failed to collect the debug symbols")
+	}
+
+	return err
+}
+
+func (a actionSymbols) symbols(ctx context.Context) error {
+	path := a.API.PathService.Archive()
+	archive, err := a.API.ArchiveService.Read(ctx, path)
+	if err != nil {
+		return fmt.Errorf("invalid archive %v (%v)", path, err)
+	}
+
+	// The vendored frameworks dSYMs are usually found into the project folder
+	var searchPaths []string
+	if a.API.Config.Path != "" {
+		searchPaths = append(searchPaths, filepath.Dir(a.API.Config.Path))
+	}
+	searchPaths = append(searchPaths, a.API.Config.Symbols.SearchPaths...)
+	report, err := a.API.SymbolsService.Collect(ctx, archive, searchPaths)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, m := range report.Missing {
+		for arch, uuid := range m.UUIDs {
+			log.Warn().
+				Str("Product", m.Path).
+				Str("Arch", arch).
+				Str("UUID", uuid).
+				Msg("No dSYM found for the binary")
+		}
+		missing = append(missing, m.Path)
+	}
+
+	if a.API.Config.Symbols.Strict && len(missing) > 0 {
+		return fmt.Errorf("no dSYM found for %v", strings.Join(missing, ", "))
+	}
+
+	dst := a.API.PathService.Symbols(
+		archive.ApplicationProperties.ShortVersion,
+		archive.ApplicationProperties.Version,
+	)
+	if err := a.API.SymbolsService.Package(ctx, report, dst); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("Path", dst).
+		Int("DSYMs", len(report.DSYMs)).
+		Int("Missing", len(report.Missing)).
+		Msg("Packaged the debug symbols")
+
+	return nil
+}
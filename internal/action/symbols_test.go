@@ -0,0 +1,61 @@
+package action
+
+import (
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// symbolsArchives reads the same empty archive from any path
+type symbolsArchives struct{}
+
+func (symbolsArchives) List(ctx context.Context, root string) ([]*api.Archive, error) {
+	return nil, nil
+}
+func (symbolsArchives) Read(ctx context.Context, path string) (*api.Archive, error) {
+	return &api.Archive{Path: path}, nil
+}
+
+// symbolsCollector records the search paths of the collection
+type symbolsCollector struct{ searchPaths *[]string }
+
+func (c symbolsCollector) Collect(ctx context.Context, archive *api.Archive, searchPaths []string) (*api.SymbolsReport, error) {
+	*c.searchPaths = searchPaths
+	return &api.SymbolsReport{}, nil
+}
+func (symbolsCollector) Package(ctx context.Context, report *api.SymbolsReport, dst string) error {
+	return nil
+}
+
+func TestSymbolsSearchPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{"project", "/project/Dummy.xcodeproj", []string{"/project", "/vendor"}},
+		{"no project", "", []string{"/vendor"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup:
+			var searchPaths []string
+			a := &api.API{Config: &api.Config{Path: tt.path}}
+			a.Config.Symbols.SearchPaths = []string{"/vendor"}
+			a.ArchiveService = symbolsArchives{}
+			a.SymbolsService = symbolsCollector{&searchPaths}
+			a.PathService = path.NewPathService(a)
+
+			// when:
+			err := actionSymbols{a}.symbols(context.Background())
+
+			// then:
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, searchPaths)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"crypto"
 	"crypto/x509"
 	"io"
 )
@@ -17,7 +18,13 @@ type API struct {
 	ActionPack          Action
 	ActionRun           Action
 	ActionRunTest       Action
+	ActionSymbols       Action
+	ArchiveService      ArchiveService
+	BinaryAnalyzer      BinaryAnalyzer
 	BuildService        BuildService
+	BundleAuditor       BundleAuditor
+	BundleInspector     BundleInspector
+	BundleResigner      BundleResigner
 	CertificateService  CertificateService
 	Config              *Config
 	FileService         FileService
@@ -25,22 +32,48 @@ type API struct {
 	Exec                Executor
 	ExportOptionService ExportOptionsService
 	KeyChain
+	OTAService          OTAService
 	PathService         PathService
 	PlistBuddyService   PListBuddyService
 	ProvisioningService ProvisioningService
 	SignatureResolver   SignatureResolver
 	SignatureService    SignatureService
+	SigningAssetSource  SigningAssetSource
+	SigningRepository   SigningRepository
+	SizeAnalyzer        SizeAnalyzer
+	SymbolsService      SymbolsService
+	Symbolicator        Symbolicator
 	XcodeListService    ListService
 	XCodeProjectService ProjectService
 	XcodeSelectService  SelectService
+	XcodeVerifyService  VerifyService
 }
 
 type Action interface {
 	Run(ctx context.Context) error
 }
 
+// IdentityType the kind of signing identity held by a certificate
+type IdentityType string
+
+const (
+	IdentityAppleDevelopment   IdentityType = "Apple Development"
+	IdentityAppleDistribution  IdentityType = "Apple Distribution"
+	IdentityDeveloperID        IdentityType = "Developer ID"
+	IdentityIPhoneDeveloper    IdentityType = "iPhone Developer"
+	IdentityIPhoneDistribution IdentityType = "iPhone Distribution"
+	IdentityUnknown            IdentityType = "Unknown"
+)
+
 // P12Certificate is a more convenient alias
 type P12Certificate struct {
 	*x509.Certificate
+	// Content of the identity to import when it does not come from a file
+	Content  []byte
 	FilePath string
+	// InKeyChain the identity is already into the keychain, thus is not imported
+	InKeyChain bool
+	Password   string
+	PrivateKey crypto.PrivateKey
+	Type       IdentityType
 }
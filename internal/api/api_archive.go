@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// ArchiveService reads the archives produced by the archive action
+type ArchiveService interface {
+	// List reads the archives found into the folder, the most recent versions first
+	List(ctx context.Context, root string) ([]*Archive, error)
+	// Read reads the archive at path, its products and debug symbols
+	Read(ctx context.Context, path string) (*Archive, error)
+}
+
+// Archive the content of a .xcarchive, as described by its Info.plist
+type Archive struct {
+	ApplicationProperties ArchiveApplication `plist:"ApplicationProperties" json:"applicationProperties"`
+	CreationDate          time.Time          `plist:"CreationDate" json:"creationDate"`
+	DSYMs                 []ArchiveBinary    `plist:"-" json:"dSYMs,omitempty"`
+	Name                  string             `plist:"Name" json:"name"`
+	Path                  string             `plist:"-" json:"path"`
+	Products              []ArchiveBinary    `plist:"-" json:"products,omitempty"`
+	SchemeName            string             `plist:"SchemeName" json:"scheme"`
+	Version               int                `plist:"ArchiveVersion" json:"archiveVersion"`
+}
+
+// ArchiveApplication the archived application
+type ArchiveApplication struct {
+	ApplicationPath  string   `plist:"ApplicationPath" json:"applicationPath"`
+	Architectures    []string `plist:"Architectures" json:"architectures,omitempty"`
+	BundleIdentifier string   `plist:"CFBundleIdentifier" json:"bundleIdentifier"`
+	ShortVersion     string   `plist:"CFBundleShortVersionString" json:"shortVersion"`
+	SigningIdentity  string   `plist:"SigningIdentity" json:"signingIdentity,omitempty"`
+	Team             string   `plist:"Team" json:"team,omitempty"`
+	Version          string   `plist:"CFBundleVersion" json:"version"`
+}
+
+// ArchiveBinary an executable of the products, or the debug symbols of one of them
+type ArchiveBinary struct {
+	BundleIdentifier string `json:"bundleIdentifier,omitempty"`
+	Path             string `json:"path"`
+	// UUIDs the build UUID of each architecture
+	UUIDs map[string]string `json:"uuids"`
+}
+
+// MissingSymbols returns the products having an architecture without debug symbols
+func (a Archive) MissingSymbols() []string {
+	symbols := map[string]bool{}
+	for _, d := range a.DSYMs {
+		for _, uuid := range d.UUIDs {
+			symbols[uuid] = true
+		}
+	}
+
+	var res []string
+	for _, p := range a.Products {
+		for _, uuid := range p.UUIDs {
+			if !symbols[uuid] {
+				res = append(res, p.Path)
+				break
+			}
+		}
+	}
+
+	return res
+}
+
+// SymbolsService collects the debug symbols of the archived products
+type SymbolsService interface {
+	// Collect gathers the dSYMs of the archive and of the search paths, matching their UUIDs
+	// against the ones of the archived products
+	Collect(ctx context.Context, archive *Archive, searchPaths []string) (*SymbolsReport, error)
+	// Package zips the collected dSYMs to the file
+	Package(ctx context.Context, report *SymbolsReport, dst string) error
+}
+
+// SymbolsReport the debug symbols of the archived products
+type SymbolsReport struct {
+	// DSYMs the dSYMs matching the products, with their absolute path
+	DSYMs []ArchiveBinary `json:"dSYMs"`
+	// Missing the products having architectures without dSYM, with the UUIDs of these ones
+	Missing []ArchiveBinary `json:"missing,omitempty"`
+}
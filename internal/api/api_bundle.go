@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// BundleInspector inspects the content of the built products, without requiring Xcode
+type BundleInspector interface {
+	Inspect(ctx context.Context, path string) (*BundleReport, error)
+}
+
+// BundleReport the inspection report of an IPA, an application or an archive
+type BundleReport struct {
+	Applications []BundleInfo `json:"applications"`
+	Path         string       `json:"path"`
+	Type         string       `json:"type"`
+}
+
+// BundleInfo describes a bundle and the bundles it embeds
+type BundleInfo struct {
+	BundleIdentifier    string         `json:"bundleIdentifier"`
+	Errors              []string       `json:"errors,omitempty"`
+	Executable          string         `json:"executable,omitempty"`
+	Kind                string         `json:"kind"`
+	MinimumOSVersion    string         `json:"minimumOSVersion,omitempty"`
+	Name                string         `json:"name,omitempty"`
+	Nested              []BundleInfo   `json:"nested,omitempty"`
+	Path                string         `json:"path"`
+	Platforms           []string       `json:"platforms,omitempty"`
+	ProvisioningProfile *ProfileInfo   `json:"provisioningProfile,omitempty"`
+	ShortVersion        string         `json:"shortVersion,omitempty"`
+	Signature           *SignatureInfo `json:"signature,omitempty"`
+	Version             string         `json:"version,omitempty"`
+}
+
+// ProfileInfo summary of the embedded provisioning profile
+type ProfileInfo struct {
+	AppID                string    `json:"appID"`
+	Devices              int       `json:"devices"`
+	Expiration           time.Time `json:"expiration"`
+	Method               string    `json:"method"`
+	Name                 string    `json:"name"`
+	ProvisionsAllDevices bool      `json:"provisionsAllDevices,omitempty"`
+	TeamID               string    `json:"teamID"`
+	TeamName             string    `json:"teamName"`
+	UUID                 string    `json:"uuid"`
+}
+
+// SignatureInfo summary of the code signature of the executable
+type SignatureInfo struct {
+	Architectures []string               `json:"architectures"`
+	Entitlements  map[string]interface{} `json:"entitlements,omitempty"`
+	Identifier    string                 `json:"identifier,omitempty"`
+	Signer        string                 `json:"signer,omitempty"`
+	SignerExpiry  *time.Time             `json:"signerExpiration,omitempty"`
+	TeamID        string                 `json:"teamID,omitempty"`
+}
+
+// BundleAuditor checks the code signature of the built products
+type BundleAuditor interface {
+	Audit(ctx context.Context, path string) (*AuditReport, error)
+}
+
+// AuditReport the code signature audit of every bundle of an IPA, an application or an archive
+type AuditReport struct {
+	Bundles []BundleAudit `json:"bundles"`
+	Path    string        `json:"path"`
+}
+
+// BundleAudit the issues found for a bundle
+type BundleAudit struct {
+	BundleIdentifier string   `json:"bundleIdentifier"`
+	Issues           []string `json:"issues,omitempty"`
+	Path             string   `json:"path"`
+}
+
+// Failed is true when an issue has been found for any of the bundles
+func (r AuditReport) Failed() bool {
+	for _, b := range r.Bundles {
+		if len(b.Issues) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BundleResigner re-signs the built products with other signing assets
+type BundleResigner interface {
+	// Resign re-signs the IPA with the provisioning profiles mapped by bundle identifier, to
+	// the output file
+	Resign(ctx context.Context, ipa, output string, profiles map[string]string) error
+}
+
+// BinaryAnalyzer analyzes the Mach-O binaries of the built products, without requiring Xcode
+type BinaryAnalyzer interface {
+	Analyze(ctx context.Context, path string) ([]BinaryReport, error)
+}
+
+// BinaryReport the analysis of a binary and of its architectures
+type BinaryReport struct {
+	Architectures []BinaryArch `json:"architectures"`
+	// Linkage either static or dynamic, the static binaries being linked into their clients
+	Linkage  string   `json:"linkage"`
+	Path     string   `json:"path"`
+	Type     string   `json:"type"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// BinaryArch the build settings of an architecture of a binary
+type BinaryArch struct {
+	Bitcode        string        `json:"bitcode"`
+	CPU            string        `json:"cpu"`
+	Encrypted      bool          `json:"encrypted"`
+	LinkedDylibs   []LinkedDylib `json:"linkedDylibs,omitempty"`
+	MinimumOS      string        `json:"minimumOSVersion,omitempty"`
+	Platform       string        `json:"platform,omitempty"`
+	Rpaths         []string      `json:"rpaths,omitempty"`
+	SDK            string        `json:"sdkVersion,omitempty"`
+	Simulator      bool          `json:"simulator,omitempty"`
+	Swift          string        `json:"swiftVersion,omitempty"`
+	SwiftStableABI bool          `json:"swiftStableABI,omitempty"`
+	UUID           string        `json:"uuid,omitempty"`
+}
+
+// LinkedDylib a dynamic library linked by a binary
+type LinkedDylib struct {
+	Path string `json:"path"`
+	Weak bool   `json:"weak,omitempty"`
+}
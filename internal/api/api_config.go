@@ -1,18 +1,109 @@
 package api
 
+import "time"
+
 type Config struct {
 	Scheme         string
 	Configuration  string
 	Destination    Destination
+	Env            EnvConfig
+	ExportOptions  ExportOptionsConfig
+	Methods        []string
 	Path           string
 	CodeSign       bool
 	CodeSignOption SignConfig
+	Size           SizeConfig
+	Symbols        SymbolsConfig
 	Target         string
 	XCodeVersion   string
+	// XCodeSearchPaths the folders, applications or glob patterns of applications searched
+	// for the Xcode installs, the default ones being used when empty
+	XCodeSearchPaths []string
 }
 
 type SignConfig struct {
-	Path                string
-	CertificatePassword string
-	XCConfig            string
+	Path                     string
+	CertificateFile          string
+	CertificatePassword      string
+	CertificatePasswords     string
+	CertificateExpiryWarning time.Duration
+	// IsolateProfiles installs the provisioning profiles into a home folder of the run, the
+	// xcode commands being pointed to it through their HOME variable. The user caches, Xcode
+	// settings and credentials are linked into it, the other files of the user home being
+	// unavailable to the commands
+	IsolateProfiles bool
+	// KeyChain the keychain holding the identities used to sign
+	KeyChain       KeyChainConfig
+	PrivateKeyFile string
+	// WWDRCertificates a DER or PEM file of the Apple WWDR intermediate certificates imported
+	// into the keychain of the run
+	WWDRCertificates string
+	Repository       RepositoryConfig
+	XCConfig         string
+}
+
+// The keychain modes
+const (
+	// KeyChainExisting a pre-provisioned keychain holding the identities, unlocked by the run
+	KeyChainExisting = "existing"
+	// KeyChainLogin the identities of the user keychains, left untouched
+	KeyChainLogin = "login"
+	// KeyChainTemporary a keychain created by the run, the identities being imported into it
+	KeyChainTemporary = "temporary"
+)
+
+// KeyChainConfig configuration of the keychain holding the identities
+type KeyChainConfig struct {
+	// Mode one of the keychain modes, temporary when empty
+	Mode string
+	// Path the keychain of the existing mode
+	Path string
+}
+
+// EnvConfig configuration of the environment of the commands
+type EnvConfig struct {
+	// Allow the patterns of the inherited variables kept, all of them when empty
+	Allow []string
+	// Deny the patterns of the inherited variables removed
+	Deny []string
+	// Vars the KEY=VALUE variables defined over the inherited ones
+	Vars []string
+}
+
+// RepositoryConfig configuration of the shared signing assets repository
+type RepositoryConfig struct {
+	// Branch of the git repository
+	Branch string
+	// Passphrase used to encrypt the signing assets
+	Passphrase string
+	// URL is either a local directory or a git repository URL
+	URL string
+}
+
+// ExportOptionsConfig configuration of the export options of the archive
+type ExportOptionsConfig struct {
+	// Path of a checked-in exportOptions.plist used as base, over the computed values
+	Path string
+	// Overrides are merged over the base and the computed values
+	Overrides ExportOptions
+}
+
+// SymbolsConfig configuration of the debug symbols collection
+type SymbolsConfig struct {
+	// SearchPaths the folders searched for the dSYMs of the vendored frameworks, in addition to
+	// the archive and the project folder
+	SearchPaths []string
+	// Skip disables the collection at the end of the archive
+	Skip bool
+	// Strict fails the collection when a product has no dSYM
+	Strict bool
+}
+
+// SizeConfig configuration of the size reports of the exported packages
+type SizeConfig struct {
+	// Baseline the path of a previous size report the packages are compared to
+	Baseline string
+	// Threshold the growth of the compressed size, in percent of the baseline, failing the
+	// package. Zero disables the check
+	Threshold float64
 }
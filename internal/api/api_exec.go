@@ -10,10 +10,44 @@ type Executor interface {
 	// CommandContext allow to execute a command with Context
 	CommandContext(ctx context.Context, cmd string, args ...string) Cmd
 
+	// Environment starts the environment of a command from the inherited one, filtered by the
+	// allow and deny lists, then applies the keychain variables and the configured ones
+	Environment() EnvBuilder
+
+	// Redact masks the secrets, like the keychain passwords, in the logged arguments of the
+	// commands
+	Redact(secrets ...string)
+
+	// Report the run report, holding the environment of the last xcode command
+	Report() RunReport
+
+	// SetXCodeEnv defines an environment variable of the xcode commands created afterwards,
+	// over the environment of the process
+	SetXCodeEnv(key, value string)
+
+	// UnsetXCodeEnv removes the variable defined through SetXCodeEnv
+	UnsetXCodeEnv(key string)
+
 	// XCodeCommandContext allow to execute a xcode command with Context
 	XCodeCommandContext(ctx context.Context, args ...string) (*Cmd, error)
 }
 
+// EnvBuilder composes the environment of a command
+type EnvBuilder interface {
+	// Set defines the variable, over the inherited one
+	Set(key, value string) EnvBuilder
+	// Build returns the KEY=VALUE entries of the environment
+	Build() []string
+	// Redacted returns the entries of the environment, the values of the secrets being masked
+	Redacted() []string
+}
+
+// RunReport the report of the run, written next to the build products
+type RunReport struct {
+	// Environment the redacted environment of the last xcode command
+	Environment []string `json:"environment"`
+}
+
 // Cmd is an interface that wrap the Cmd action from os/exec in a more friendly API
 type Cmd interface {
 	// Run runs the command to the completion.
@@ -11,6 +11,7 @@ type FileService interface {
 	OpenAndReadFileContent(abs string) ([]byte, error)
 	Open(path string) (io.ReadCloser, error)
 	IsDir(path string) (bool, error)
+	Stat(path string) (os.FileInfo, error)
 	Walk(ctx context.Context,
 		root string,
 		isValid func(info os.FileInfo) bool,
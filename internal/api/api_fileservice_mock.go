@@ -30,6 +30,11 @@ func (m *MockFileService) IsDir(path string) (bool, error) {
 	return c.Get(0).(bool), c.Error(1)
 }
 
+func (m *MockFileService) Stat(path string) (os.FileInfo, error) {
+	c := m.Called(path)
+	return c.Get(0).(os.FileInfo), c.Error(1)
+}
+
 func (m *MockFileService) Walk(
 	ctx context.Context,
 	root string,
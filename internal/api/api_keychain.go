@@ -1,10 +1,43 @@
 package api
 
-import "context"
+import (
+	"context"
+	"crypto/x509"
+)
 
 type KeyChain interface {
-	Create(ctx context.Context, password string) error
+	// Cleanup deletes the keychains left by the earlier runs, into the search list or the
+	// folders, and returns their paths
+	Cleanup(ctx context.Context, dirs []string) ([]string, error)
+	// Create creates the keychain of the run, protected by a random password, or unlocks the
+	// existing keychain, doing nothing for the login keychain
+	Create(ctx context.Context) error
+	// Delete deletes the keychain of the run, doing nothing for the existing and the login
+	// keychains but restoring the search list
 	Delete(ctx context.Context) error
+	// Identities lists the code signing identities of the keychain, the invalid ones with their reason
+	Identities(ctx context.Context) ([]SigningIdentity, error)
+	// IdentityCertificates returns the certificates of the valid code signing identities of
+	// the keychain
+	IdentityCertificates(ctx context.Context) ([]*x509.Certificate, error)
 	ImportCertificate(ctx context.Context, filePath string, password string, commonName string) error
+	// ImportIntermediates imports the certificates of the DER or PEM file, like the Apple WWDR
+	// intermediates the identities are issued by
+	ImportIntermediates(ctx context.Context, path string) error
+	// GetPath returns the path of the keychain holding the identities, empty for the login
+	// keychain, the user search list being used
 	GetPath() string
 }
+
+// SigningIdentity a code signing identity of the keychain
+type SigningIdentity struct {
+	Name string
+	// Reason why the identity is not valid, like CSSMERR_TP_NOT_TRUSTED
+	Reason string
+	SHA1   string
+}
+
+// Valid is true when the identity can sign
+func (i SigningIdentity) Valid() bool {
+	return i.Reason == ""
+}
@@ -0,0 +1,36 @@
+package api
+
+import "context"
+
+// OTAService generates the over-the-air installation bundle of the exported IPA files
+type OTAService interface {
+	// Generate writes the manifest.plist and index.html install page of the export options
+	// manifest next to a copy of the IPA found into the export folder, returning the path of
+	// the generated folder
+	Generate(ctx context.Context, exportPath string, m *ExportManifest) (string, error)
+}
+
+// OTAManifest the itms-services manifest describing the application to install
+type OTAManifest struct {
+	Items []OTAItem `plist:"items"`
+}
+
+// OTAItem an application of the manifest
+type OTAItem struct {
+	Assets   []OTAAsset  `plist:"assets"`
+	Metadata OTAMetadata `plist:"metadata"`
+}
+
+// OTAAsset a downloadable asset of the application
+type OTAAsset struct {
+	Kind string `plist:"kind"`
+	URL  string `plist:"url"`
+}
+
+// OTAMetadata describes the application to the installer
+type OTAMetadata struct {
+	BundleIdentifier string `plist:"bundle-identifier"`
+	BundleVersion    string `plist:"bundle-version"`
+	Kind             string `plist:"kind"`
+	Title            string `plist:"title"`
+}
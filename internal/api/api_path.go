@@ -4,11 +4,16 @@ type PathService interface {
 	Archive() string
 	DerivedData() string
 	ExportPList() string
+	ExportPListFor(method string) string
 	KeyChain() string
 	ObjRoot() string
 	PBXProj() string
 	Package() string
+	PackageFor(method string) string
+	// RunReport the JSON report of the run
+	RunReport() string
 	SymRoot() string
+	Symbols(version, build string) string
 	XCResult() string
 	XCodeProject() string
 }
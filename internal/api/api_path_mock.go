@@ -18,6 +18,11 @@ func (p *PathMock) ExportPList() string {
 	return c.String(0)
 }
 
+func (p *PathMock) ExportPListFor(method string) string {
+	c := p.Called(method)
+	return c.String(0)
+}
+
 func (p *PathMock) KeyChain() string {
 	c := p.Called()
 	return c.String(0)
@@ -28,6 +33,16 @@ func (p *PathMock) ObjRoot() string {
 	return c.String(0)
 }
 
+func (p *PathMock) PackageFor(method string) string {
+	c := p.Called(method)
+	return c.String(0)
+}
+
+func (p *PathMock) RunReport() string {
+	c := p.Called()
+	return c.String(0)
+}
+
 func (p *PathMock) SymRoot() string {
 	c := p.Called()
 	return c.String(0)
@@ -37,3 +52,8 @@ func (p *PathMock) XCResult() string {
 	c := p.Called()
 	return c.String(0)
 }
+
+func (p *PathMock) Symbols(version, build string) string {
+	c := p.Called(version, build)
+	return c.String(0)
+}
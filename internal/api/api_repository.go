@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// SigningRepository is the shared and encrypted storage of the signing assets
+type SigningRepository interface {
+	// Sync fetches the latest state of the repository
+	Sync(ctx context.Context) error
+
+	// Import encrypts the files and adds them to the repository
+	Import(ctx context.Context, paths []string) ([]RepositoryEntry, error)
+
+	// Entries returns the manifest entries of the repository
+	Entries(ctx context.Context) ([]RepositoryEntry, error)
+
+	// Open decrypts the content of the entry
+	Open(ctx context.Context, e RepositoryEntry) ([]byte, error)
+}
+
+// RepositoryEntry kind of signing assets
+const (
+	RepositoryCertificate = "certificate"
+	RepositoryProfile     = "profile"
+)
+
+// RepositoryEntry is the manifest description of an encrypted signing asset
+type RepositoryEntry struct {
+	BundleIdentifier string    `json:"bundleIdentifier,omitempty"`
+	Expiration       time.Time `json:"expiration"`
+	File             string    `json:"file"`
+	Fingerprint      string    `json:"fingerprint,omitempty"`
+	Kind             string    `json:"kind"`
+	Name             string    `json:"name"`
+	Team             string    `json:"team"`
+	Type             string    `json:"type"`
+	UUID             string    `json:"uuid,omitempty"`
+}
@@ -10,15 +10,25 @@ import (
 type SignatureService interface {
 	Run(ctx context.Context) error
 	GetConfiguration() *[]TargetSignatureConfig
+	// ForMethod resolves, installs and imports the signing assets of the distribution method
+	// for the targets resolved by Run
+	ForMethod(ctx context.Context, method string) ([]TargetSignatureConfig, error)
+	// ImportCertificate imports the certificate into the keychain, once per run
+	ImportCertificate(ctx context.Context, c *P12Certificate) error
 }
 
 type TargetSignatureConfig struct {
-	TargetName string
-	Config     *SignatureConfiguration
+	BundleIdentifier string
+	Config           *SignatureConfiguration
+	Platform         string
+	TargetName       string
 }
 
 // ProvisioningService interface to describe the provisioning service method
 type ProvisioningService interface {
+	// Cleanup removes the provisioning profiles installed by the run, the ones installed
+	// before being kept
+	Cleanup() error
 	Decode(ctx context.Context, r io.Reader) (ProvisioningProfile, error)
 	ResolveProvisioningFilesInFolder(ctx context.Context, root string) []*ProvisioningProfile
 	Install(p *ProvisioningProfile) error
@@ -27,9 +37,10 @@ type ProvisioningService interface {
 // ProvisioningProfile type definition
 type ProvisioningProfile struct {
 	BundleIdentifier     string
-	Certificates         []*x509.Certificate
-	Entitlements         Entitlements `plist:"Entitlements"`
-	ExpirationDate       time.Time    `plist:"ExpirationDate"`
+	Certificates         []*x509.Certificate `json:"-"`
+	Content              []byte              `plist:"-" json:"-"`
+	Entitlements         Entitlements        `plist:"Entitlements"`
+	ExpirationDate       time.Time           `plist:"ExpirationDate"`
 	FilePath             string
 	Name                 string    `plist:"Name"`
 	Platform             []string  `plist:"Platform"`
@@ -49,9 +60,22 @@ type Entitlements struct {
 	GetTaskAllow bool   `plist:"get-task-allow"`
 }
 
+// SigningAssetSource provides the certificates and provisioning profiles candidates
+// to the signature resolution
+type SigningAssetSource interface {
+	Certificates(ctx context.Context) []*P12Certificate
+	ProvisioningProfiles(ctx context.Context) []*ProvisioningProfile
+}
+
 // Resolver is the base interface for the signature result
 type SignatureResolver interface {
 	Resolve(ctx context.Context, bundleIdentifier string, platform string) (*SignatureConfiguration, error)
+	ResolveForMethod(
+		ctx context.Context,
+		bundleIdentifier string,
+		platform string,
+		method string,
+	) (*SignatureConfiguration, error)
 }
 
 type SignatureConfiguration struct {
@@ -60,18 +84,38 @@ type SignatureConfiguration struct {
 	path                string
 }
 
-// exportoptions
+// ExportOptions the xcodebuild -exportArchive options, the optional values being omitted
+// to keep the Xcode defaults
 type ExportOptions struct {
-	Method              string            `plist:"method"`
-	TeamID              string            `plist:"teamID"`
-	UploadBitCode       bool              `plist:"uploadBitCode"`
-	CompileBitCode      bool              `plist:"compileBitCode"`
-	UploadSymbols       bool              `plist:"uploadSymbols"`
-	SigningStyle        string            `plist:"signingStyle"`
-	SigningCertificate  string            `plist:"signingCertificate"`
-	ProvisioningProfile map[string]string `plist:"provisioningProfiles"`
+	CompileBitcode                           *bool             `plist:"compileBitcode,omitempty"`
+	Destination                              string            `plist:"destination,omitempty"`
+	DistributionBundleIdentifier             string            `plist:"distributionBundleIdentifier,omitempty"`
+	EmbedOnDemandResourcesAssetPacksInBundle *bool             `plist:"embedOnDemandResourcesAssetPacksInBundle,omitempty"`
+	ICloudContainerEnvironment               string            `plist:"iCloudContainerEnvironment,omitempty"`
+	ManageAppVersionAndBuildNumber           *bool             `plist:"manageAppVersionAndBuildNumber,omitempty"`
+	Manifest                                 *ExportManifest   `plist:"manifest,omitempty"`
+	Method                                   string            `plist:"method,omitempty"`
+	ProvisioningProfile                      map[string]string `plist:"provisioningProfiles,omitempty"`
+	SigningCertificate                       string            `plist:"signingCertificate,omitempty"`
+	SigningStyle                             string            `plist:"signingStyle,omitempty"`
+	StripSwiftSymbols                        *bool             `plist:"stripSwiftSymbols,omitempty"`
+	TeamID                                   string            `plist:"teamID,omitempty"`
+	TestFlightInternalTestingOnly            *bool             `plist:"testFlightInternalTestingOnly,omitempty"`
+	Thinning                                 string            `plist:"thinning,omitempty"`
+	UploadBitcode                            *bool             `plist:"uploadBitcode,omitempty"`
+	UploadSymbols                            *bool             `plist:"uploadSymbols,omitempty"`
+}
+
+// ExportManifest the over-the-air installation manifest options, for ad-hoc and enterprise
+// distributions
+type ExportManifest struct {
+	AppURL               string `plist:"appURL,omitempty"`
+	AssetPackManifestURL string `plist:"assetPackManifestURL,omitempty"`
+	DisplayImageURL      string `plist:"displayImageURL,omitempty"`
+	FullSizeImageURL     string `plist:"fullSizeImageURL,omitempty"`
 }
 
 type ExportOptionsService interface {
 	Compute() error
+	ComputeForMethod(method string, cfg []TargetSignatureConfig) error
 }
@@ -21,3 +21,12 @@ func (m *SignatureServiceMock) GetConfiguration() *[]TargetSignatureConfig {
 	c := m.Called()
 	return c.Get(0).(*[]TargetSignatureConfig)
 }
+
+func (m *SignatureServiceMock) ForMethod(ctx context.Context, method string) ([]TargetSignatureConfig, error) {
+	c := m.Called(method)
+	return c.Get(0).([]TargetSignatureConfig), c.Error(1)
+}
+
+func (m *SignatureServiceMock) ImportCertificate(ctx context.Context, c *P12Certificate) error {
+	return m.Called(c).Error(0)
+}
@@ -0,0 +1,74 @@
+package api
+
+import "context"
+
+// SizeAnalyzer breaks down the size of the exported packages
+type SizeAnalyzer interface {
+	Analyze(ctx context.Context, ipa string) (*SizeReport, error)
+}
+
+// SizeReport the compressed and uncompressed sizes of the content of a package
+type SizeReport struct {
+	Compressed   int64      `json:"compressedSize"`
+	Items        []SizeItem `json:"items"`
+	Largest      []SizeItem `json:"largestFiles"`
+	Path         string     `json:"path"`
+	Uncompressed int64      `json:"uncompressedSize"`
+}
+
+// SizeItem the size of a part of the package, like a framework, or of a file
+type SizeItem struct {
+	Compressed   int64  `json:"compressedSize"`
+	Kind         string `json:"kind"`
+	Name         string `json:"name"`
+	Uncompressed int64  `json:"uncompressedSize"`
+}
+
+// SizeDiff the growth of a package compared to a baseline, the items whose size did not
+// change being omitted
+type SizeDiff struct {
+	Compressed int64 `json:"compressedSize"`
+	// Growth the growth of the compressed size, in percent of the baseline
+	Growth       float64    `json:"growth"`
+	Items        []SizeItem `json:"items,omitempty"`
+	Uncompressed int64      `json:"uncompressedSize"`
+}
+
+// Diff compares the report to the baseline, the removed items having a negative size
+func (r SizeReport) Diff(baseline SizeReport) SizeDiff {
+	res := SizeDiff{
+		Compressed:   r.Compressed - baseline.Compressed,
+		Uncompressed: r.Uncompressed - baseline.Uncompressed,
+	}
+
+	if baseline.Compressed > 0 {
+		res.Growth = float64(res.Compressed) * 100 / float64(baseline.Compressed)
+	}
+
+	type key struct{ kind, name string }
+	previous := map[key]SizeItem{}
+	for _, i := range baseline.Items {
+		previous[key{i.Kind, i.Name}] = i
+	}
+
+	for _, i := range r.Items {
+		k := key{i.Kind, i.Name}
+		p := previous[k]
+		delete(previous, k)
+
+		i.Compressed -= p.Compressed
+		i.Uncompressed -= p.Uncompressed
+		if i.Compressed != 0 || i.Uncompressed != 0 {
+			res.Items = append(res.Items, i)
+		}
+	}
+
+	for _, p := range baseline.Items {
+		if _, removed := previous[key{p.Kind, p.Name}]; removed {
+			p.Compressed, p.Uncompressed = -p.Compressed, -p.Uncompressed
+			res.Items = append(res.Items, p)
+		}
+	}
+
+	return res
+}
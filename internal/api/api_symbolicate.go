@@ -0,0 +1,13 @@
+package api
+
+import (
+	"context"
+	"io"
+)
+
+// Symbolicator symbolicates the crash reports without requiring Xcode
+type Symbolicator interface {
+	// Symbolicate resolves the frames of the legacy text or JSON .ips crash report against the
+	// dSYMs found into the folders, writing the symbolicated report to w
+	Symbolicate(ctx context.Context, report io.Reader, dsyms []string, w io.Writer) error
+}
@@ -9,12 +9,64 @@ type ListService interface {
 
 // Install xcode installation definition
 type Install struct {
-	DevPath       string
+	// Beta the install is a beta release, its build number being in the beta range
+	Beta bool
+	// Build the build number of version.plist, like 15A240d
+	Build   string
+	DevPath string
+	// GM the install is a release, the golden master or a later update
+	GM            bool
 	Path          string
 	BundleVersion string
 	Version       string
 }
 
+// VerifyService checks the SDKs and the simulator runtimes of an install
+type VerifyService interface {
+	// Requirements resolves the SDKs and the simulator runtimes the project needs, from the
+	// deployment targets of its targets and the configured destination
+	Requirements(ctx context.Context) (InstallRequirements, error)
+	Verify(ctx context.Context, i *Install, r InstallRequirements) (InstallVerification, error)
+}
+
+// InstallRequirements the SDKs and the simulator runtimes required from an install
+type InstallRequirements struct {
+	Runtimes []PlatformRequirement
+	SDKs     []PlatformRequirement
+}
+
+// PlatformRequirement a SDK, like iphoneos, or a runtime platform, like iOS, of a minimum
+// version, any version matching when empty
+type PlatformRequirement struct {
+	MinimumVersion string
+	Name           string
+}
+
+// InstallVerification the result of the checks of an install
+type InstallVerification struct {
+	Checks  []InstallCheck
+	Install *Install
+}
+
+// OK is true when all the requirements are fulfilled
+func (v InstallVerification) OK() bool {
+	for _, c := range v.Checks {
+		if !c.OK {
+			return false
+		}
+	}
+
+	return true
+}
+
+// InstallCheck the check of a requirement, Found being the matching SDK or runtime
+type InstallCheck struct {
+	Found       string
+	Kind        string
+	OK          bool
+	Requirement string
+}
+
 // SelectService The XCode version selection service interface
 type SelectService interface {
 	Find(ctx context.Context) (*Install, error)
@@ -0,0 +1,188 @@
+// Package archive reads the .xcarchive produced by the archive action
+package archive
+
+import (
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/bundle"
+	"dothething/internal/macho"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/rs/zerolog/log"
+	"howett.net/plist"
+)
+
+// Ext the extension of the archives
+const Ext = ".xcarchive"
+
+// ErrNoApplication the archive does not contain any application
+var ErrNoApplication = errors.New("No application found into the archive")
+
+var (
+	productRegexp = regexp.MustCompile(`^Products/(?:.+/)?[^/]+\.(?:app|appex|framework)/Info\.plist$`)
+	dsymRegexp    = regexp.MustCompile(`^dSYMs/[^/]+\.dSYM/Contents/Resources/DWARF/[^/]+$`)
+)
+
+// bundleInfo the values of the Info.plist of a product
+type bundleInfo struct {
+	BundleIdentifier string `plist:"CFBundleIdentifier"`
+	Executable       string `plist:"CFBundleExecutable"`
+}
+
+type archiveService struct {
+	*api.API
+}
+
+// NewArchiveService create a new instance of the archive service
+func NewArchiveService(api *api.API) api.ArchiveService {
+	return archiveService{api}
+}
+
+// Read reads the Info.plist of the archive, then the UUIDs of its products and dSYMs
+func (s archiveService) Read(ctx context.Context, path string) (*api.Archive, error) {
+	fs, err := bundle.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	b, err := fs.ReadFile("Info.plist")
+	if err != nil {
+		return nil, err
+	}
+
+	var res api.Archive
+	if _, err := plist.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+	res.Path = path
+
+	if res.ApplicationProperties.ApplicationPath == "" {
+		return &res, ErrNoApplication
+	}
+
+	for _, f := range fs.Files() {
+		switch {
+		case productRegexp.MatchString(f):
+			if p, ok := readProduct(fs, strings.TrimSuffix(f, "Info.plist")); ok {
+				res.Products = append(res.Products, p)
+			}
+
+		case dsymRegexp.MatchString(f):
+			if uuids, err := readUUIDs(fs, f); err == nil {
+				res.DSYMs = append(res.DSYMs, api.ArchiveBinary{
+					Path:  strings.SplitN(f, "/Contents/", 2)[0],
+					UUIDs: uuids,
+				})
+			}
+		}
+	}
+
+	return &res, nil
+}
+
+// List reads the archives of the folder and its sub folders, like the Xcode ones sorted by
+// date, sorted by descending version then creation date
+func (s archiveService) List(ctx context.Context, root string) ([]*api.Archive, error) {
+	var res []*api.Archive
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() || filepath.Ext(path) != Ext {
+			return nil
+		}
+
+		a, err := s.Read(ctx, path)
+		if err != nil {
+			log.Warn().
+				Str("Path", path).
+				AnErr("Reason", err).
+				Msg("Skipping invalid archive")
+			return filepath.SkipDir
+		}
+
+		res = append(res, a)
+		return filepath.SkipDir
+	})
+
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := res[i].ApplicationProperties, res[j].ApplicationProperties
+		if c := compareVersions(a.ShortVersion, b.ShortVersion); c != 0 {
+			return c > 0
+		}
+
+		if c := compareVersions(a.Version, b.Version); c != 0 {
+			return c > 0
+		}
+
+		return res[i].CreationDate.After(res[j].CreationDate)
+	})
+
+	return res, err
+}
+
+// readProduct reads the UUIDs of the executable of the bundle at root
+func readProduct(fs bundle.FS, root string) (api.ArchiveBinary, bool) {
+	var info bundleInfo
+	b, err := fs.ReadFile(root + "Info.plist")
+	if err != nil {
+		return api.ArchiveBinary{}, false
+	}
+
+	if _, err := plist.Unmarshal(b, &info); err != nil || info.Executable == "" {
+		return api.ArchiveBinary{}, false
+	}
+
+	uuids, err := readUUIDs(fs, root+info.Executable)
+	if err != nil {
+		return api.ArchiveBinary{}, false
+	}
+
+	return api.ArchiveBinary{
+		BundleIdentifier: info.BundleIdentifier,
+		Path:             strings.TrimSuffix(root, "/"),
+		UUIDs:            uuids,
+	}, true
+}
+
+// readUUIDs reads the UUID of each architecture of the binary, from its load commands only
+func readUUIDs(fs bundle.FS, path string) (map[string]string, error) {
+	r, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	f, err := macho.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]string{}
+	for _, a := range f.Archs {
+		if a.UUID != "" {
+			res[a.CPU] = a.UUID
+		}
+	}
+
+	return res, nil
+}
+
+// compareVersions compares the versions, semantically when possible
+func compareVersions(v1, v2 string) int {
+	s1, err1 := semver.ParseTolerant(v1)
+	s2, err2 := semver.ParseTolerant(v2)
+	if err1 == nil && err2 == nil {
+		return s1.Compare(s2)
+	}
+
+	return strings.Compare(v1, v2)
+}
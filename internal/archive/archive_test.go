@@ -0,0 +1,184 @@
+package archive
+
+import (
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/utiltest"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const archiveInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>ApplicationProperties</key>
+	<dict>
+		<key>ApplicationPath</key>
+		<string>Applications/Dummy.app</string>
+		<key>Architectures</key>
+		<array><string>arm64</string></array>
+		<key>CFBundleIdentifier</key>
+		<string>com.dummy.app</string>
+		<key>CFBundleShortVersionString</key>
+		<string>%v</string>
+		<key>CFBundleVersion</key>
+		<string>%v</string>
+		<key>SigningIdentity</key>
+		<string>Apple Distribution: Dummy Ltd (12345ABCDE)</string>
+		<key>Team</key>
+		<string>12345ABCDE</string>
+	</dict>
+	<key>ArchiveVersion</key>
+	<integer>2</integer>
+	<key>CreationDate</key>
+	<date>%v</date>
+	<key>Name</key>
+	<string>Dummy</string>
+	<key>SchemeName</key>
+	<string>Dummy</string>
+</dict>
+</plist>`
+
+const bundleInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>CFBundleIdentifier</key>
+	<string>%v</string>
+	<key>CFBundleExecutable</key>
+	<string>%v</string>
+</dict>
+</plist>`
+
+const arm64 = 0x0100000c
+
+var (
+	appUUID = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	extUUID = []byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+)
+
+type archiveSuite struct {
+	suite.Suite
+	dir     string
+	subject archiveService
+}
+
+func TestArchiveSuite(t *testing.T) {
+	suite.Run(t, new(archiveSuite))
+}
+
+func (s *archiveSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "archive")
+	s.Require().NoError(err)
+	s.dir = dir
+	s.subject = archiveService{&api.API{Config: &api.Config{}}}
+}
+
+func (s *archiveSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+// writeArchive writes an archive of an application embedding an extension, only the
+// application debug symbols being archived
+func writeArchive(r *require.Assertions, path, version, build string, created time.Time) {
+	files := map[string][]byte{
+		"Info.plist": []byte(fmt.Sprintf(archiveInfoPlist, version, build, created.UTC().Format(time.RFC3339))),
+		"Products/Applications/Dummy.app/Info.plist": []byte(fmt.Sprintf(bundleInfoPlist, "com.dummy.app", "Dummy")),
+		"Products/Applications/Dummy.app/Dummy":      utiltest.NewMachOWithUUID(arm64, appUUID, nil),
+		"Products/Applications/Dummy.app/PlugIns/Ext.appex/Info.plist": []byte(
+			fmt.Sprintf(bundleInfoPlist, "com.dummy.app.ext", "Ext"),
+		),
+		"Products/Applications/Dummy.app/PlugIns/Ext.appex/Ext": utiltest.NewMachOWithUUID(arm64, extUUID, nil),
+		"dSYMs/Dummy.app.dSYM/Contents/Info.plist":              []byte(""),
+		"dSYMs/Dummy.app.dSYM/Contents/Resources/DWARF/Dummy":   utiltest.NewMachOWithUUID(arm64, appUUID, nil),
+	}
+
+	for name, content := range files {
+		p := filepath.Join(path, filepath.FromSlash(name))
+		r.NoError(os.MkdirAll(filepath.Dir(p), 0755))
+		r.NoError(ioutil.WriteFile(p, content, 0644))
+	}
+}
+
+func (s *archiveSuite) TestRead() {
+	// setup:
+	path := filepath.Join(s.dir, "Dummy.xcarchive")
+	created := time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC)
+	writeArchive(s.Require(), path, "1.2.0", "42", created)
+
+	// when:
+	a, err := s.subject.Read(context.Background(), path)
+
+	// then:
+	s.Require().NoError(err)
+	s.Equal(path, a.Path)
+	s.Equal("Dummy", a.SchemeName)
+	s.Equal(2, a.Version)
+	s.True(created.Equal(a.CreationDate))
+	s.Equal(api.ArchiveApplication{
+		ApplicationPath:  "Applications/Dummy.app",
+		Architectures:    []string{"arm64"},
+		BundleIdentifier: "com.dummy.app",
+		ShortVersion:     "1.2.0",
+		SigningIdentity:  "Apple Distribution: Dummy Ltd (12345ABCDE)",
+		Team:             "12345ABCDE",
+		Version:          "42",
+	}, a.ApplicationProperties)
+
+	s.Equal([]api.ArchiveBinary{
+		{
+			BundleIdentifier: "com.dummy.app",
+			Path:             "Products/Applications/Dummy.app",
+			UUIDs:            map[string]string{"arm64": "01020304-0506-0708-090A-0B0C0D0E0F10"},
+		},
+		{
+			BundleIdentifier: "com.dummy.app.ext",
+			Path:             "Products/Applications/Dummy.app/PlugIns/Ext.appex",
+			UUIDs:            map[string]string{"arm64": "100F0E0D-0C0B-0A09-0807-060504030201"},
+		},
+	}, a.Products)
+	s.Equal([]api.ArchiveBinary{{
+		Path:  "dSYMs/Dummy.app.dSYM",
+		UUIDs: map[string]string{"arm64": "01020304-0506-0708-090A-0B0C0D0E0F10"},
+	}}, a.DSYMs)
+
+	s.Equal([]string{"Products/Applications/Dummy.app/PlugIns/Ext.appex"}, a.MissingSymbols())
+}
+
+func (s *archiveSuite) TestReadWithoutApplication() {
+	// setup:
+	path := filepath.Join(s.dir, "Dummy.xcarchive")
+	s.Require().NoError(os.MkdirAll(path, 0755))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(path, "Info.plist"), []byte(`<plist version="1.0"><dict/></plist>`), 0644))
+
+	// when:
+	_, err := s.subject.Read(context.Background(), path)
+
+	// then:
+	s.Equal(ErrNoApplication, err)
+}
+
+func (s *archiveSuite) TestList() {
+	// setup:
+	now := time.Now()
+	writeArchive(s.Require(), filepath.Join(s.dir, "2020-06-01", "Dummy 1.9.xcarchive"), "1.9", "1", now)
+	writeArchive(s.Require(), filepath.Join(s.dir, "2020-06-02", "Dummy 1.10.xcarchive"), "1.10", "1", now.Add(-time.Hour))
+	writeArchive(s.Require(), filepath.Join(s.dir, "2020-06-02", "Dummy 1.10 (2).xcarchive"), "1.10", "2", now.Add(-2*time.Hour))
+	s.Require().NoError(os.MkdirAll(filepath.Join(s.dir, "Invalid.xcarchive"), 0755))
+
+	// when:
+	res, err := s.subject.List(context.Background(), s.dir)
+
+	// then:
+	s.Require().NoError(err)
+	s.Require().Len(res, 3)
+	s.Equal(filepath.Join(s.dir, "2020-06-02", "Dummy 1.10 (2).xcarchive"), res[0].Path)
+	s.Equal(filepath.Join(s.dir, "2020-06-02", "Dummy 1.10.xcarchive"), res[1].Path)
+	s.Equal(filepath.Join(s.dir, "2020-06-01", "Dummy 1.9.xcarchive"), res[2].Path)
+}
@@ -0,0 +1,186 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/bundle"
+	"dothething/internal/util"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DSYMExt the extension of the debug symbols bundles
+const DSYMExt = ".dSYM"
+
+type symbolsService struct {
+	*api.API
+}
+
+// NewSymbolsService create a new instance of the debug symbols service
+func NewSymbolsService(api *api.API) api.SymbolsService {
+	return symbolsService{api}
+}
+
+// Collect gathers the dSYMs of the archive, then the ones of the search paths for the
+// products still missing symbols, like the vendored frameworks
+func (s symbolsService) Collect(
+	ctx context.Context,
+	archive *api.Archive,
+	searchPaths []string,
+) (*api.SymbolsReport, error) {
+	// The UUIDs expected, the products being reported as missing symbols until found
+	expected := map[string]bool{}
+	for _, p := range archive.Products {
+		for _, uuid := range p.UUIDs {
+			expected[uuid] = true
+		}
+	}
+
+	res := &api.SymbolsReport{}
+	found := map[string]bool{}
+	for _, root := range append([]string{archive.Path}, searchPaths...) {
+		dsyms, err := findDSYMs(root)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range dsyms {
+			var matching bool
+			for _, uuid := range d.UUIDs {
+				if expected[uuid] && !found[uuid] {
+					matching = true
+				}
+			}
+
+			// Only the dSYMs bringing the symbols of an architecture are kept
+			if !matching {
+				continue
+			}
+
+			for _, uuid := range d.UUIDs {
+				found[uuid] = true
+			}
+			res.DSYMs = append(res.DSYMs, d)
+		}
+	}
+
+	for _, p := range archive.Products {
+		missing := map[string]string{}
+		for arch, uuid := range p.UUIDs {
+			if !found[uuid] {
+				missing[arch] = uuid
+			}
+		}
+
+		if len(missing) > 0 {
+			res.Missing = append(res.Missing, api.ArchiveBinary{
+				BundleIdentifier: p.BundleIdentifier,
+				Path:             p.Path,
+				UUIDs:            missing,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+// Package zips the dSYMs, each one under its name
+func (s symbolsService) Package(ctx context.Context, report *api.SymbolsReport, dst string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Two dSYMs of the same name are only expected for different products of the search paths
+	names := map[string]bool{}
+
+	w := zip.NewWriter(f)
+	for _, d := range report.DSYMs {
+		name := filepath.Base(d.Path)
+		if names[name] {
+			log.Warn().Str("Path", d.Path).Msg("Skipping the dSYM, another one of the same name is packaged")
+			continue
+		}
+		names[name] = true
+
+		if err := util.ZipFolder(w, d.Path, name); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+// findDSYMs reads the UUIDs of the dSYMs found into the folder, sorted by path
+func findDSYMs(root string) ([]api.ArchiveBinary, error) {
+	var res []api.ArchiveBinary
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		// Skipping the version control and other hidden folders
+		if path != root && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		if filepath.Ext(path) != DSYMExt {
+			return nil
+		}
+
+		if d, err := readDSYM(path); err != nil {
+			log.Warn().
+				Str("Path", path).
+				AnErr("Reason", err).
+				Msg("Skipping invalid dSYM")
+		} else {
+			res = append(res, d)
+		}
+
+		return filepath.SkipDir
+	})
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+
+	return res, err
+}
+
+// readDSYM reads the UUIDs of the DWARF files of the dSYM
+func readDSYM(path string) (api.ArchiveBinary, error) {
+	res := api.ArchiveBinary{Path: path, UUIDs: map[string]string{}}
+
+	fs, err := bundle.Open(path)
+	if err != nil {
+		return res, err
+	}
+	defer fs.Close()
+
+	for _, f := range fs.Files() {
+		if !strings.HasPrefix(f, "Contents/Resources/DWARF/") {
+			continue
+		}
+
+		uuids, err := readUUIDs(fs, f)
+		if err != nil {
+			return res, err
+		}
+
+		for arch, uuid := range uuids {
+			res.UUIDs[arch] = uuid
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,108 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/utiltest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type symbolsSuite struct {
+	suite.Suite
+	archive *api.Archive
+	dir     string
+	subject symbolsService
+}
+
+func TestSymbolsSuite(t *testing.T) {
+	suite.Run(t, new(symbolsSuite))
+}
+
+func (s *symbolsSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "symbols")
+	s.Require().NoError(err)
+	s.dir = dir
+
+	a := &api.API{Config: &api.Config{}}
+	s.subject = symbolsService{a}
+
+	path := filepath.Join(s.dir, "Build", "Dummy.xcarchive")
+	writeArchive(s.Require(), path, "1.2.0", "42", time.Now())
+
+	s.archive, err = archiveService{a}.Read(context.Background(), path)
+	s.Require().NoError(err)
+}
+
+func (s *symbolsSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+// writeDSYM writes a dSYM of a single DWARF file
+func (s *symbolsSuite) writeDSYM(path string, uuid []byte) {
+	dwarf := filepath.Join(path, "Contents", "Resources", "DWARF", "Binary")
+	s.Require().NoError(os.MkdirAll(filepath.Dir(dwarf), 0755))
+	s.Require().NoError(ioutil.WriteFile(dwarf, utiltest.NewMachOWithUUID(arm64, uuid, nil), 0644))
+}
+
+func (s *symbolsSuite) TestCollect() {
+	// setup: the extension symbols being vendored, next to unrelated ones
+	vendor := filepath.Join(s.dir, "Carthage")
+	s.writeDSYM(filepath.Join(vendor, "Ext.appex.dSYM"), extUUID)
+	s.writeDSYM(filepath.Join(vendor, "Other.framework.dSYM"), []byte("0123456789abcdef"))
+	s.writeDSYM(filepath.Join(s.dir, ".git", "Ext.appex.dSYM"), extUUID)
+
+	// when:
+	res, err := s.subject.Collect(context.Background(), s.archive, []string{vendor})
+
+	// then:
+	s.Require().NoError(err)
+	s.Empty(res.Missing)
+	s.Require().Len(res.DSYMs, 2)
+	s.Equal(filepath.Join(s.archive.Path, "dSYMs", "Dummy.app.dSYM"), res.DSYMs[0].Path)
+	s.Equal(filepath.Join(vendor, "Ext.appex.dSYM"), res.DSYMs[1].Path)
+}
+
+func (s *symbolsSuite) TestCollectMissing() {
+	// when:
+	res, err := s.subject.Collect(context.Background(), s.archive, nil)
+
+	// then:
+	s.Require().NoError(err)
+	s.Len(res.DSYMs, 1)
+	s.Equal([]api.ArchiveBinary{{
+		BundleIdentifier: "com.dummy.app.ext",
+		Path:             "Products/Applications/Dummy.app/PlugIns/Ext.appex",
+		UUIDs:            map[string]string{"arm64": "100F0E0D-0C0B-0A09-0807-060504030201"},
+	}}, res.Missing)
+}
+
+func (s *symbolsSuite) TestPackage() {
+	// setup:
+	res, err := s.subject.Collect(context.Background(), s.archive, nil)
+	s.Require().NoError(err)
+	dst := filepath.Join(s.dir, "symbols.zip")
+
+	// when:
+	err = s.subject.Package(context.Background(), res, dst)
+
+	// then:
+	s.Require().NoError(err)
+
+	r, err := zip.OpenReader(dst)
+	s.Require().NoError(err)
+	defer r.Close()
+
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	s.Contains(names, "Dummy.app.dSYM/Contents/Resources/DWARF/Dummy")
+	s.Contains(names, "Dummy.app.dSYM/Contents/Info.plist")
+}
@@ -0,0 +1,268 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"dothething/internal/api"
+	"dothething/internal/macho"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mozilla.org/pkcs7"
+	"howett.net/plist"
+)
+
+type auditor struct {
+	*api.API
+}
+
+// NewAuditor create a new instance of the code signature auditor
+func NewAuditor(api *api.API) api.BundleAuditor {
+	return auditor{api}
+}
+
+// profileEntitlements the entitlements granted by the provisioning profile, decoded as a
+// dictionary to compare them against the signed ones
+type profileEntitlements struct {
+	Entitlements map[string]interface{} `plist:"Entitlements"`
+}
+
+// Audit checks the code signature of the applications of the container at path, and of all
+// the bundles they embed
+func (a auditor) Audit(ctx context.Context, path string) (*api.AuditReport, error) {
+	fs, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	roots := applicationRoots(fs, containerType(path))
+	if len(roots) == 0 {
+		return nil, ErrNoApplication
+	}
+
+	res := &api.AuditReport{Path: path}
+	for _, root := range roots {
+		// The team of the application is expected for all its nested bundles
+		var team string
+		if info, err := readInfo(fs, root); err == nil {
+			if _, sig, err := readSignature(fs, root+info.Executable); err == nil {
+				team = sig.TeamID
+			}
+		}
+
+		res.Bundles = append(res.Bundles, a.auditBundle(ctx, fs, root, KindApplication, team, time.Now())...)
+	}
+
+	return res, nil
+}
+
+// auditBundle checks the bundle at root and the bundles it embeds
+func (a auditor) auditBundle(
+	ctx context.Context,
+	fs FS,
+	root string,
+	kind string,
+	team string,
+	now time.Time,
+) []api.BundleAudit {
+	res := api.BundleAudit{Path: strings.TrimSuffix(root, "/")}
+	if res.Path == "" {
+		res.Path = "."
+	}
+	issue := func(format string, args ...interface{}) {
+		res.Issues = append(res.Issues, fmt.Sprintf(format, args...))
+	}
+
+	info, err := readInfo(fs, root)
+	if err != nil {
+		issue("failed to read the Info.plist: %v", err)
+		return []api.BundleAudit{res}
+	}
+	res.BundleIdentifier = info.BundleIdentifier
+
+	_, sig, err := readSignature(fs, root+info.Executable)
+	if err != nil {
+		issue("failed to read the code signature of %v: %v", info.Executable, err)
+	} else if sig.TeamID != team {
+		issue("signed by the team %q, while the application is signed by %q", sig.TeamID, team)
+	}
+
+	// The frameworks do not embed any provisioning profile
+	if kind != KindFramework {
+		if b, err := fs.ReadFile(root + "embedded.mobileprovision"); err != nil {
+			issue("failed to read the embedded.mobileprovision: %v", err)
+		} else if sig != nil {
+			res.Issues = append(res.Issues, a.auditProfile(ctx, b, info.BundleIdentifier, sig, now)...)
+		}
+	}
+
+	audits := []api.BundleAudit{res}
+	for _, n := range nestedRoots(fs, root) {
+		audits = append(audits, a.auditBundle(ctx, fs, n.root, n.kind, team, now)...)
+	}
+
+	return audits
+}
+
+// auditProfile checks the provisioning profile against the signature of the bundle
+func (a auditor) auditProfile(
+	ctx context.Context,
+	b []byte,
+	bundleID string,
+	sig *macho.CodeSignature,
+	now time.Time,
+) []string {
+	pp, err := a.API.ProvisioningService.Decode(ctx, bytes.NewReader(b))
+	if err != nil {
+		return []string{fmt.Sprintf("failed to decode the embedded.mobileprovision: %v", err)}
+	}
+
+	var res []string
+	if !matchWildcard(pp.Entitlements.AppID, pp.Entitlements.TeamID+"."+bundleID) {
+		res = append(res, fmt.Sprintf("the provisioning profile %q does not cover the bundle identifier (%v)",
+			pp.Name, pp.Entitlements.AppID))
+	}
+
+	if pp.Entitlements.TeamID != sig.TeamID {
+		res = append(res, fmt.Sprintf("the provisioning profile %q belongs to the team %q, the signature to %q",
+			pp.Name, pp.Entitlements.TeamID, sig.TeamID))
+	}
+
+	if now.After(pp.ExpirationDate) {
+		res = append(res, fmt.Sprintf("the provisioning profile %q expired on %v",
+			pp.Name, pp.ExpirationDate.Format("2006-01-02")))
+	}
+
+	if signer := sig.Signer(); signer == nil {
+		res = append(res, "the signature does not contain any signing certificate")
+	} else if !containsCertificate(pp.Certificates, signer.Raw) {
+		res = append(res, fmt.Sprintf("the signing certificate %q is not part of the provisioning profile %q",
+			signer.Subject.CommonName, pp.Name))
+	}
+
+	granted, err := decodeProfileEntitlements(b)
+	if err != nil {
+		return append(res, fmt.Sprintf("failed to decode the provisioning profile entitlements: %v", err))
+	}
+
+	signed := map[string]interface{}{}
+	if len(sig.Entitlements) > 0 {
+		if _, err := plist.Unmarshal(sig.Entitlements, &signed); err != nil {
+			return append(res, fmt.Sprintf("failed to decode the signed entitlements: %v", err))
+		}
+	}
+
+	// Sorting the keys to report the issues in a stable order
+	var keys []string
+	for k := range signed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p, ok := granted[k]
+		if !ok {
+			res = append(res, fmt.Sprintf("the entitlement %v is not granted by the provisioning profile", k))
+			continue
+		}
+
+		if !allowed(signed[k], p) {
+			res = append(res, fmt.Sprintf("the entitlement %v value %v is not allowed by the provisioning profile (%v)",
+				k, signed[k], p))
+		}
+	}
+
+	return res
+}
+
+// decodeProfileEntitlements decodes the entitlements of the provisioning profile as a dictionary
+func decodeProfileEntitlements(b []byte) (map[string]interface{}, error) {
+	p7, err := pkcs7.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var res profileEntitlements
+	if _, err := plist.Unmarshal(p7.Content, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Entitlements, nil
+}
+
+func containsCertificate(certs []*x509.Certificate, raw []byte) bool {
+	for _, c := range certs {
+		if bytes.Equal(c.Raw, raw) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowed checks that the signed entitlement value is granted by the profile value, the
+// profile values possibly being wildcards or lists of allowed values
+func allowed(signed, granted interface{}) bool {
+	switch g := granted.(type) {
+	case string:
+		if g == "*" {
+			return true
+		}
+
+		for _, v := range values(signed) {
+			s, ok := v.(string)
+			if !ok || !matchWildcard(g, s) {
+				return false
+			}
+		}
+		return true
+
+	case []interface{}:
+		for _, v := range values(signed) {
+			var found bool
+			for _, e := range g {
+				if allowed(v, e) {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return false
+			}
+		}
+		return true
+
+	case bool:
+		// A capability granted by the profile may be disabled by the application
+		s, ok := signed.(bool)
+		return ok && (g || !s)
+
+	default:
+		return reflect.DeepEqual(signed, granted)
+	}
+}
+
+// values returns the elements of a list, or the value itself
+func values(v interface{}) []interface{} {
+	if l, ok := v.([]interface{}); ok {
+		return l
+	}
+
+	return []interface{}{v}
+}
+
+// matchWildcard matches the value against the pattern, the pattern possibly ending with a
+// wildcard
+func matchWildcard(pattern, v string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(v, strings.TrimSuffix(pattern, "*"))
+	}
+
+	return pattern == v
+}
@@ -0,0 +1,230 @@
+package bundle
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"dothething/internal/api"
+	"dothething/internal/signature"
+	"dothething/internal/utiltest"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.mozilla.org/pkcs7"
+)
+
+const auditProfileTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Name</key>
+	<string>Dummy</string>
+	<key>UUID</key>
+	<string>UUID</string>
+	<key>ExpirationDate</key>
+	<date>%v</date>
+	<key>DeveloperCertificates</key>
+	<array><data>%v</data></array>
+	<key>Entitlements</key>
+	<dict>
+		<key>application-identifier</key>
+		<string>%v</string>
+		<key>com.apple.developer.team-identifier</key>
+		<string>12345ABCDE</string>
+		<key>get-task-allow</key>
+		<false/>
+		<key>keychain-access-groups</key>
+		<array><string>12345ABCDE.*</string></array>
+	</dict>
+</dict>
+</plist>`
+
+const auditInfoTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>CFBundleIdentifier</key>
+	<string>%v</string>
+	<key>CFBundleExecutable</key>
+	<string>Exec</string>
+</dict>
+</plist>`
+
+const auditEntitlementsTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>application-identifier</key>
+	<string>12345ABCDE.%v</string>
+	<key>get-task-allow</key>
+	<false/>
+	<key>keychain-access-groups</key>
+	<array><string>12345ABCDE.%v</string></array>
+	%v
+</dict>
+</plist>`
+
+type auditSuite struct {
+	suite.Suite
+	API     *api.API
+	cert    *x509.Certificate
+	dir     string
+	key     *rsa.PrivateKey
+	subject auditor
+}
+
+func TestAuditSuite(t *testing.T) {
+	suite.Run(t, new(auditSuite))
+}
+
+func (s *auditSuite) SetupSuite() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+
+	tpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Apple Distribution: Dummy Ltd (12345ABCDE)"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tpl, &tpl, &key.PublicKey, key)
+	s.Require().NoError(err)
+
+	s.cert, err = x509.ParseCertificate(der)
+	s.Require().NoError(err)
+	s.key = key
+}
+
+func (s *auditSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "audit")
+	s.Require().NoError(err)
+	s.dir = dir
+
+	s.API = &api.API{Config: &api.Config{}}
+	s.API.ProvisioningService = signature.NewProvisioningService(s.API)
+	s.subject = auditor{s.API}
+}
+
+func (s *auditSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+// auditBundle describes a bundle to write
+type auditBundle struct {
+	appID        string
+	bundleID     string
+	entitlements string
+	expiration   time.Time
+	team         string
+}
+
+func (s *auditSuite) write(root string, b auditBundle) {
+	s.Require().NoError(os.MkdirAll(root, 0755))
+
+	sd, err := pkcs7.NewSignedData([]byte("code directory"))
+	s.Require().NoError(err)
+	s.Require().NoError(sd.AddSigner(s.cert, s.key, pkcs7.SignerInfoConfig{}))
+	sd.Detach()
+	cms, err := sd.Finish()
+	s.Require().NoError(err)
+
+	ent := fmt.Sprintf(auditEntitlementsTemplate, b.bundleID, b.bundleID, b.entitlements)
+	exec := utiltest.NewMachO(utiltest.NewCodeSignature(b.bundleID, b.team, []byte(ent), cms))
+
+	pp, err := pkcs7.NewSignedData([]byte(fmt.Sprintf(
+		auditProfileTemplate,
+		b.expiration.UTC().Format(time.RFC3339),
+		base64.StdEncoding.EncodeToString(s.cert.Raw),
+		b.appID,
+	)))
+	s.Require().NoError(err)
+	profile, err := pp.Finish()
+	s.Require().NoError(err)
+
+	files := map[string][]byte{
+		"Info.plist":               []byte(fmt.Sprintf(auditInfoTemplate, b.bundleID)),
+		"Exec":                     exec,
+		"embedded.mobileprovision": profile,
+	}
+	for name, content := range files {
+		s.Require().NoError(ioutil.WriteFile(filepath.Join(root, name), content, 0644))
+	}
+}
+
+func (s *auditSuite) TestAuditValid() {
+	// setup:
+	root := filepath.Join(s.dir, "Dummy.app")
+	valid := time.Now().Add(time.Hour)
+	s.write(root, auditBundle{"12345ABCDE.com.dummy", "com.dummy", "", valid, "12345ABCDE"})
+	s.write(filepath.Join(root, "PlugIns", "Ext.appex"),
+		auditBundle{"12345ABCDE.com.dummy.*", "com.dummy.ext", "", valid, "12345ABCDE"})
+
+	// when:
+	res, err := s.subject.Audit(context.Background(), root)
+
+	// then:
+	s.Require().NoError(err)
+	s.False(res.Failed())
+	s.Require().Len(res.Bundles, 2)
+	s.Equal("PlugIns/Ext.appex", res.Bundles[1].Path)
+	s.Equal("com.dummy.ext", res.Bundles[1].BundleIdentifier)
+}
+
+func (s *auditSuite) TestAuditMismatches() {
+	// setup:
+	root := filepath.Join(s.dir, "Dummy.app")
+	valid := time.Now().Add(time.Hour)
+	s.write(root, auditBundle{"12345ABCDE.com.dummy", "com.dummy", "", valid, "12345ABCDE"})
+	s.write(filepath.Join(root, "PlugIns", "Ext.appex"), auditBundle{
+		appID:        "12345ABCDE.com.other",
+		bundleID:     "com.dummy.ext",
+		entitlements: "<key>aps-environment</key><string>production</string>",
+		expiration:   time.Now().Add(-time.Hour),
+		team:         "OTHERTEAM1",
+	})
+
+	// when:
+	res, err := s.subject.Audit(context.Background(), root)
+
+	// then:
+	s.Require().NoError(err)
+	s.True(res.Failed())
+	s.Require().Len(res.Bundles, 2)
+	s.Empty(res.Bundles[0].Issues)
+
+	issues := res.Bundles[1].Issues
+	s.Require().Len(issues, 6)
+	s.Contains(issues[0], `signed by the team "OTHERTEAM1"`)
+	s.Contains(issues[1], "does not cover the bundle identifier")
+	s.Contains(issues[2], `belongs to the team "12345ABCDE"`)
+	s.Contains(issues[3], "expired")
+	s.Contains(issues[4], "application-identifier value 12345ABCDE.com.dummy.ext is not allowed")
+	s.Contains(issues[5], "aps-environment is not granted")
+}
+
+func (s *auditSuite) TestAllowed() {
+	cases := []struct {
+		signed  interface{}
+		granted interface{}
+		res     bool
+	}{
+		{"12345ABCDE.com.dummy", "12345ABCDE.*", true},
+		{"12345ABCDE.com.dummy", "12345ABCDE.com.other", false},
+		{[]interface{}{"applinks:dummy.com"}, "*", true},
+		{[]interface{}{"group.a", "group.b"}, []interface{}{"group.a", "group.b", "group.c"}, true},
+		{[]interface{}{"group.d"}, []interface{}{"group.a"}, false},
+		{true, false, false},
+		{false, true, true},
+		{"production", "development", false},
+	}
+
+	for _, c := range cases {
+		s.Equal(c.res, allowed(c.signed, c.granted), "%v / %v", c.signed, c.granted)
+	}
+}
@@ -0,0 +1,188 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/macho"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// The linkages of the binaries
+const (
+	LinkageDynamic = "dynamic"
+	LinkageStatic  = "static"
+)
+
+type binaryAnalyzer struct {
+	*api.API
+}
+
+// NewBinaryAnalyzer create a new instance of the Mach-O binaries analyzer
+func NewBinaryAnalyzer(api *api.API) api.BinaryAnalyzer {
+	return binaryAnalyzer{api}
+}
+
+// Analyze reports the binary at path, or the executables of the IPA, application, framework
+// or archive at path and of the bundles they embed
+func (b binaryAnalyzer) Analyze(ctx context.Context, path string) ([]api.BinaryReport, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []api.BinaryReport
+	fs, err := Open(path)
+	switch {
+	case err == nil:
+		defer fs.Close()
+
+		if res, err = analyzeBundles(fs, path); err != nil {
+			return nil, err
+		}
+
+	case info.Mode().IsRegular():
+		// Not a zip file, thus a plain binary
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := analyzeBinary(content, path)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+
+	default:
+		return nil, err
+	}
+
+	flagSimulatorSlices(res)
+
+	return res, nil
+}
+
+// analyzeBundles analyzes the executables of the bundles of the container, the folders
+// holding an Info.plist being bundles themselves, like the applications and frameworks
+func analyzeBundles(fs FS, path string) ([]api.BinaryReport, error) {
+	roots := applicationRoots(fs, containerType(path))
+	if _, err := fs.ReadFile("Info.plist"); err == nil {
+		roots = []string{""}
+	}
+
+	if len(roots) == 0 {
+		return nil, ErrNoApplication
+	}
+
+	var res []api.BinaryReport
+	for _, root := range roots {
+		reports, err := analyzeBundle(fs, root)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, reports...)
+	}
+
+	return res, nil
+}
+
+// analyzeBundle analyzes the executable of the bundle at root, then the ones of the bundles
+// it embeds
+func analyzeBundle(fs FS, root string) ([]api.BinaryReport, error) {
+	info, err := readInfo(fs, root)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []api.BinaryReport
+	if info.Executable != "" {
+		path := root + info.Executable
+		content, err := fs.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := analyzeBinary(content, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyze %v: %v", path, err)
+		}
+		res = append(res, r)
+	}
+
+	for _, n := range nestedRoots(fs, root) {
+		reports, err := analyzeBundle(fs, n.root)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, reports...)
+	}
+
+	return res, nil
+}
+
+// analyzeBinary reports the architectures of the Mach-O binary or static library
+func analyzeBinary(content []byte, path string) (api.BinaryReport, error) {
+	res := api.BinaryReport{Path: path, Linkage: LinkageDynamic}
+
+	f, err := macho.Parse(bytes.NewReader(content))
+	if err != nil {
+		return res, err
+	}
+
+	res.Type = f.Type
+	if f.Static() {
+		res.Linkage = LinkageStatic
+	}
+
+	for _, a := range f.Archs {
+		arch := api.BinaryArch{
+			Bitcode:        a.Bitcode,
+			CPU:            a.CPU,
+			Encrypted:      a.Encrypted,
+			MinimumOS:      a.MinOS,
+			Platform:       a.Platform,
+			Rpaths:         a.Rpaths,
+			SDK:            a.SDK,
+			Simulator:      a.Simulator(),
+			Swift:          a.Swift,
+			SwiftStableABI: a.SwiftStableABI,
+			UUID:           a.UUID,
+		}
+
+		for _, d := range a.Dylibs {
+			arch.LinkedDylibs = append(arch.LinkedDylibs, api.LinkedDylib{Path: d.Path, Weak: d.Weak})
+		}
+
+		res.Architectures = append(res.Architectures, arch)
+	}
+
+	return res, nil
+}
+
+// flagSimulatorSlices warns about the simulator slices once a device slice has been found,
+// the App Store rejecting them
+func flagSimulatorSlices(reports []api.BinaryReport) {
+	var device bool
+	for _, r := range reports {
+		for _, a := range r.Architectures {
+			device = device || (a.Platform != "" && !a.Simulator)
+		}
+	}
+
+	if !device {
+		return
+	}
+
+	for i, r := range reports {
+		for _, a := range r.Architectures {
+			if a.Simulator {
+				reports[i].Warnings = append(reports[i].Warnings, fmt.Sprintf(
+					"The %v slice targets the %v, it must be stripped from a device build", a.CPU, a.Platform,
+				))
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package bundle
+
+import (
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/macho"
+	"dothething/internal/utiltest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type binarySuite struct {
+	suite.Suite
+	dir     string
+	subject binaryAnalyzer
+}
+
+func TestBinarySuite(t *testing.T) {
+	suite.Run(t, new(binarySuite))
+}
+
+func (s *binarySuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "binary")
+	s.Require().NoError(err)
+	s.dir = dir
+
+	s.subject = binaryAnalyzer{&api.API{Config: &api.Config{}}}
+}
+
+func (s *binarySuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *binarySuite) write(name string, content []byte) string {
+	path := filepath.Join(s.dir, filepath.FromSlash(name))
+	s.Require().NoError(os.MkdirAll(filepath.Dir(path), 0755))
+	s.Require().NoError(ioutil.WriteFile(path, content, 0644))
+
+	return path
+}
+
+func (s *binarySuite) TestAnalyzeApp() {
+	// setup: the framework keeping its simulator slice
+	ios := utiltest.NewBuildVersionLoad(2, 0x000e0000, 0x00100000)
+	sim := utiltest.NewBuildVersionLoad(7, 0x000e0000, 0x00100000)
+	s.write("Dummy.app/Info.plist", []byte(appInfoPlist))
+	s.write("Dummy.app/Dummy", utiltest.NewMachOBinary(0x0100000c, 2, [][]byte{
+		ios,
+		utiltest.NewDylibLoad(0xc, "@rpath/Kit.framework/Kit"),
+		utiltest.NewRpathLoad("@executable_path/Frameworks"),
+	}))
+	s.write("Dummy.app/Frameworks/Kit.framework/Info.plist", []byte(frameworkInfoPlist))
+	s.write("Dummy.app/Frameworks/Kit.framework/Kit", utiltest.NewFatMachO(
+		[]uint32{0x0100000c, 0x01000007},
+		utiltest.NewMachOBinary(0x0100000c, 6, [][]byte{ios}),
+		utiltest.NewMachOBinary(0x01000007, 6, [][]byte{sim}),
+	))
+
+	// when:
+	res, err := s.subject.Analyze(context.Background(), filepath.Join(s.dir, "Dummy.app"))
+
+	// then:
+	s.Require().NoError(err)
+	s.Require().Len(res, 2)
+
+	s.Equal("Dummy", res[0].Path)
+	s.Equal(macho.TypeExecutable, res[0].Type)
+	s.Equal(LinkageDynamic, res[0].Linkage)
+	s.Require().Len(res[0].Architectures, 1)
+	s.Equal("iOS", res[0].Architectures[0].Platform)
+	s.Equal("14.0", res[0].Architectures[0].MinimumOS)
+	s.Equal("16.0", res[0].Architectures[0].SDK)
+	s.Equal([]api.LinkedDylib{{Path: "@rpath/Kit.framework/Kit"}}, res[0].Architectures[0].LinkedDylibs)
+	s.Equal([]string{"@executable_path/Frameworks"}, res[0].Architectures[0].Rpaths)
+	s.Empty(res[0].Warnings)
+
+	s.Equal("Frameworks/Kit.framework/Kit", res[1].Path)
+	s.Equal(macho.TypeDynamicLibrary, res[1].Type)
+	s.Require().Len(res[1].Architectures, 2)
+	s.True(res[1].Architectures[1].Simulator)
+	s.Equal([]string{
+		"The x86_64 slice targets the iOS Simulator, it must be stripped from a device build",
+	}, res[1].Warnings)
+}
+
+func (s *binarySuite) TestAnalyzeStaticLibrary() {
+	// setup: a simulator only build is not flagged
+	path := s.write("libKit.a", utiltest.NewStaticLibrary(
+		utiltest.NewMachOBinary(0x01000007, 1, [][]byte{utiltest.NewBuildVersionLoad(7, 0x000e0000, 0x00100000)}),
+	))
+
+	// when:
+	res, err := s.subject.Analyze(context.Background(), path)
+
+	// then:
+	s.Require().NoError(err)
+	s.Require().Len(res, 1)
+	s.Equal(path, res[0].Path)
+	s.Equal(macho.TypeStaticLibrary, res[0].Type)
+	s.Equal(LinkageStatic, res[0].Linkage)
+	s.True(res[0].Architectures[0].Simulator)
+	s.Empty(res[0].Warnings)
+}
+
+func (s *binarySuite) TestAnalyzeInvalid() {
+	// setup:
+	path := s.write("script.sh", []byte("#!/bin/sh"))
+
+	// when:
+	_, err := s.subject.Analyze(context.Background(), path)
+
+	// then:
+	s.Equal(macho.ErrNotMachO, err)
+}
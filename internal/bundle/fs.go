@@ -0,0 +1,176 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrNotFound the file does not exist into the bundle
+var ErrNotFound = errors.New("File not found into the bundle")
+
+// FS read-only access to the files of a bundle, whatever its container
+type FS interface {
+	// Files returns the slash separated paths of the regular files, sorted
+	Files() []string
+	// ReadFile reads the whole content of the file
+	ReadFile(name string) ([]byte, error)
+	// Open opens the file for reading at any offset, without loading the content of the
+	// files of the folders
+	Open(name string) (File, error)
+	Close() error
+}
+
+// File a file of the bundle, read at any offset
+type File interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// Open opens the folder, or the zip file, at path
+func Open(path string) (FS, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return newDirFS(path)
+	}
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newZipFS(r), nil
+}
+
+// dirFS a bundle stored as a folder, like .app or .xcarchive
+type dirFS struct {
+	root  string
+	files []string
+}
+
+func newDirFS(root string) (dirFS, error) {
+	res := dirFS{root: root}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		res.files = append(res.files, filepath.ToSlash(rel))
+		return nil
+	})
+
+	sort.Strings(res.files)
+
+	return res, err
+}
+
+func (f dirFS) Files() []string {
+	return f.files
+}
+
+func (f dirFS) ReadFile(name string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(f.root, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+
+	return b, err
+}
+
+func (f dirFS) Open(name string) (File, error) {
+	r, err := os.Open(filepath.Join(f.root, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (f dirFS) Close() error {
+	return nil
+}
+
+// zipFS a bundle stored as a zip file, like .ipa
+type zipFS struct {
+	r     *zip.ReadCloser
+	files map[string]*zip.File
+	names []string
+}
+
+func newZipFS(r *zip.ReadCloser) zipFS {
+	res := zipFS{r: r, files: map[string]*zip.File{}}
+	for _, f := range r.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		res.files[f.Name] = f
+		res.names = append(res.names, f.Name)
+	}
+	sort.Strings(res.names)
+
+	return res
+}
+
+func (f zipFS) Files() []string {
+	return f.names
+}
+
+func (f zipFS) ReadFile(name string) ([]byte, error) {
+	zf, ok := f.files[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return ioutil.ReadAll(rc)
+}
+
+// Open reads the content of the file, the compressed entries not being seekable
+func (f zipFS) Open(name string) (File, error) {
+	b, err := f.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return memFile{bytes.NewReader(b)}, nil
+}
+
+func (f zipFS) Close() error {
+	return f.r.Close()
+}
+
+// memFile a file read into memory
+type memFile struct {
+	*bytes.Reader
+}
+
+func (memFile) Close() error {
+	return nil
+}
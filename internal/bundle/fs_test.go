@@ -0,0 +1,67 @@
+package bundle
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	// setup: the same file into a folder and a zip file
+	dir, err := ioutil.TempDir("", "fs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	app := filepath.Join(dir, "Dummy.app")
+	require.NoError(t, os.MkdirAll(app, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(app, "Dummy"), []byte("binary"), 0644))
+
+	ipa := filepath.Join(dir, "Dummy.ipa")
+	f, err := os.Create(ipa)
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	e, err := w.Create("Dummy")
+	require.NoError(t, err)
+	_, err = e.Write([]byte("binary"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	for _, path := range []string{app, ipa} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			fs, err := Open(path)
+			require.NoError(t, err)
+			defer fs.Close()
+
+			// when:
+			r, err := fs.Open("Dummy")
+
+			// then: the content is read at an offset
+			require.NoError(t, err)
+			defer r.Close()
+			b := make([]byte, 3)
+			_, err = r.ReadAt(b, 2)
+			assert.NoError(t, err)
+			assert.Equal(t, "nar", string(b))
+
+			// when:
+			_, err = fs.Open("Missing")
+
+			// then:
+			assert.Equal(t, ErrNotFound, err)
+		})
+	}
+
+	// and: the files of the folders are not loaded into memory
+	fs, err := Open(app)
+	require.NoError(t, err)
+	r, err := fs.Open("Dummy")
+	require.NoError(t, err)
+	defer r.Close()
+	assert.IsType(t, &os.File{}, r)
+}
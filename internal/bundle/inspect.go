@@ -0,0 +1,300 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/macho"
+	"dothething/internal/signature"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"howett.net/plist"
+)
+
+// The containers of the bundles
+const (
+	TypeApp     = "app"
+	TypeArchive = "xcarchive"
+	TypeIPA     = "ipa"
+)
+
+// The kinds of bundles
+const (
+	KindAppClip          = "app-clip"
+	KindAppExtension     = "app-extension"
+	KindApplication      = "application"
+	KindFramework        = "framework"
+	KindWatchApplication = "watch-application"
+)
+
+var (
+	// ErrNoApplication no application has been found into the container
+	ErrNoApplication = errors.New("No application found")
+
+	// ErrNotSigned the executable does not embed a code signature
+	ErrNotSigned = errors.New("Not signed")
+)
+
+var (
+	ipaAppRegexp     = regexp.MustCompile(`^Payload/[^/]+\.app/Info\.plist$`)
+	archiveAppRegexp = regexp.MustCompile(`^Products/Applications/[^/]+\.app/Info\.plist$`)
+)
+
+// nestedFolders the folders of a bundle embedding other bundles
+var nestedFolders = []struct {
+	folder string
+	ext    string
+	kind   string
+}{
+	{"AppClips", ".app", KindAppClip},
+	{"Extensions", ".appex", KindAppExtension},
+	{"Frameworks", ".framework", KindFramework},
+	{"PlugIns", ".appex", KindAppExtension},
+	{"Watch", ".app", KindWatchApplication},
+}
+
+// infoPlist the values of the Info.plist reported
+type infoPlist struct {
+	BundleIdentifier string   `plist:"CFBundleIdentifier"`
+	DisplayName      string   `plist:"CFBundleDisplayName"`
+	Executable       string   `plist:"CFBundleExecutable"`
+	MinimumOSVersion string   `plist:"MinimumOSVersion"`
+	Name             string   `plist:"CFBundleName"`
+	Platforms        []string `plist:"CFBundleSupportedPlatforms"`
+	ShortVersion     string   `plist:"CFBundleShortVersionString"`
+	Version          string   `plist:"CFBundleVersion"`
+}
+
+type inspector struct {
+	*api.API
+}
+
+// NewInspector create a new instance of the bundle inspector
+func NewInspector(api *api.API) api.BundleInspector {
+	return inspector{api}
+}
+
+// Inspect reports the applications of the IPA, application or archive at path
+func (i inspector) Inspect(ctx context.Context, path string) (*api.BundleReport, error) {
+	fs, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	res := &api.BundleReport{Path: path, Type: containerType(path)}
+	for _, root := range applicationRoots(fs, res.Type) {
+		res.Applications = append(res.Applications, i.inspectBundle(ctx, fs, root, KindApplication))
+	}
+
+	if len(res.Applications) == 0 {
+		return nil, ErrNoApplication
+	}
+
+	return res, nil
+}
+
+// containerType guesses the container from the file extension
+func containerType(path string) string {
+	switch strings.ToLower(filepath.Ext(strings.TrimSuffix(path, "/"))) {
+	case ".xcarchive":
+		return TypeArchive
+	case ".app":
+		return TypeApp
+	default:
+		return TypeIPA
+	}
+}
+
+// applicationRoots returns the slash terminated roots of the applications of the container
+func applicationRoots(fs FS, container string) []string {
+	var res []string
+	for _, f := range fs.Files() {
+		switch container {
+		case TypeApp:
+			if f == "Info.plist" {
+				res = append(res, "")
+			}
+		case TypeArchive:
+			if archiveAppRegexp.MatchString(f) {
+				res = append(res, strings.TrimSuffix(f, "Info.plist"))
+			}
+		case TypeIPA:
+			if ipaAppRegexp.MatchString(f) {
+				res = append(res, strings.TrimSuffix(f, "Info.plist"))
+			}
+		}
+	}
+
+	return res
+}
+
+// inspectBundle reports the bundle at root and the bundles it embeds, the failures being
+// reported instead of aborting the inspection
+func (i inspector) inspectBundle(ctx context.Context, fs FS, root, kind string) api.BundleInfo {
+	res := api.BundleInfo{Kind: kind, Path: strings.TrimSuffix(root, "/")}
+	if res.Path == "" {
+		res.Path = "."
+	}
+
+	info, err := readInfo(fs, root)
+	if err != nil {
+		res.Errors = append(res.Errors, "Info.plist: "+err.Error())
+	}
+
+	res.BundleIdentifier = info.BundleIdentifier
+	res.Executable = info.Executable
+	res.MinimumOSVersion = info.MinimumOSVersion
+	res.Name = info.Name
+	if info.DisplayName != "" {
+		res.Name = info.DisplayName
+	}
+	res.Platforms = info.Platforms
+	res.ShortVersion = info.ShortVersion
+	res.Version = info.Version
+
+	// Only the applications and extensions embed a provisioning profile
+	if b, err := fs.ReadFile(root + "embedded.mobileprovision"); err == nil {
+		if res.ProvisioningProfile, err = i.profileInfo(ctx, b); err != nil {
+			res.Errors = append(res.Errors, "embedded.mobileprovision: "+err.Error())
+		}
+	}
+
+	if info.Executable != "" {
+		if res.Signature, err = signatureInfo(fs, root+info.Executable); err != nil {
+			res.Errors = append(res.Errors, info.Executable+": "+err.Error())
+		}
+	}
+
+	for _, n := range nestedRoots(fs, root) {
+		res.Nested = append(res.Nested, i.inspectBundle(ctx, fs, n.root, n.kind))
+	}
+
+	return res
+}
+
+// profileInfo decodes the embedded provisioning profile
+func (i inspector) profileInfo(ctx context.Context, b []byte) (*api.ProfileInfo, error) {
+	pp, err := i.API.ProvisioningService.Decode(ctx, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ProfileInfo{
+		AppID:      pp.Entitlements.AppID,
+		Expiration: pp.ExpirationDate,
+		Method:     signature.ProvisioningMethod(&pp),
+		Name:       pp.Name,
+		TeamID:     pp.Entitlements.TeamID,
+		TeamName:   pp.TeamName,
+		UUID:       pp.UUID,
+	}
+
+	if pp.ProvisionedDevices != nil {
+		res.Devices = len(*pp.ProvisionedDevices)
+	}
+
+	if pp.ProvisionsAllDevices != nil {
+		res.ProvisionsAllDevices = *pp.ProvisionsAllDevices
+	}
+
+	return &res, nil
+}
+
+// readInfo decodes the Info.plist of the bundle at root
+func readInfo(fs FS, root string) (infoPlist, error) {
+	var res infoPlist
+
+	b, err := fs.ReadFile(root + "Info.plist")
+	if err != nil {
+		return res, err
+	}
+
+	_, err = plist.Unmarshal(b, &res)
+	return res, err
+}
+
+// readSignature reads the architectures of the executable and its code signature, the one
+// of the first signed architecture being returned
+func readSignature(fs FS, path string) ([]string, *macho.CodeSignature, error) {
+	r, err := fs.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+
+	f, err := macho.Parse(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var archs []string
+	var sig *macho.CodeSignature
+	for _, a := range f.Archs {
+		archs = append(archs, a.CPU)
+		if sig == nil {
+			sig = a.Signature
+		}
+	}
+
+	if sig == nil {
+		return archs, nil, ErrNotSigned
+	}
+
+	return archs, sig, nil
+}
+
+// signatureInfo reads the code signature of the executable
+func signatureInfo(fs FS, path string) (*api.SignatureInfo, error) {
+	archs, sig, err := readSignature(fs, path)
+	if archs == nil {
+		return nil, err
+	}
+
+	res := api.SignatureInfo{Architectures: archs}
+	if err != nil {
+		return &res, err
+	}
+
+	res.Identifier = sig.Identifier
+	res.TeamID = sig.TeamID
+	if c := sig.Signer(); c != nil {
+		res.Signer = c.Subject.CommonName
+		res.SignerExpiry = &c.NotAfter
+	}
+
+	if len(sig.Entitlements) > 0 {
+		if _, err := plist.Unmarshal(sig.Entitlements, &res.Entitlements); err != nil {
+			return &res, err
+		}
+	}
+
+	return &res, nil
+}
+
+// nestedBundle a bundle embedded into another one
+type nestedBundle struct {
+	kind string
+	root string
+}
+
+// nestedRoots returns the bundles directly embedded into the bundle at root
+func nestedRoots(fs FS, root string) []nestedBundle {
+	var res []nestedBundle
+	for _, n := range nestedFolders {
+		re := regexp.MustCompile(
+			"^" + regexp.QuoteMeta(root+n.folder+"/") + `[^/]+` + regexp.QuoteMeta(n.ext) + `/Info\.plist$`,
+		)
+
+		for _, f := range fs.Files() {
+			if re.MatchString(f) {
+				res = append(res, nestedBundle{kind: n.kind, root: strings.TrimSuffix(f, "Info.plist")})
+			}
+		}
+	}
+
+	return res
+}
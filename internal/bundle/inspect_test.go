@@ -0,0 +1,224 @@
+package bundle
+
+import (
+	"archive/zip"
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/signature"
+	"dothething/internal/utiltest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"go.mozilla.org/pkcs7"
+)
+
+const appInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>CFBundleIdentifier</key>
+	<string>com.dummy.app</string>
+	<key>CFBundleName</key>
+	<string>Dummy</string>
+	<key>CFBundleExecutable</key>
+	<string>Dummy</string>
+	<key>CFBundleShortVersionString</key>
+	<string>1.0</string>
+	<key>CFBundleVersion</key>
+	<string>7</string>
+	<key>CFBundleSupportedPlatforms</key>
+	<array><string>iPhoneOS</string></array>
+</dict>
+</plist>`
+
+const extensionInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>CFBundleIdentifier</key>
+	<string>com.dummy.app.widget</string>
+</dict>
+</plist>`
+
+const frameworkInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>CFBundleIdentifier</key>
+	<string>com.dummy.kit</string>
+	<key>CFBundleExecutable</key>
+	<string>Kit</string>
+</dict>
+</plist>`
+
+const profile = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Name</key>
+	<string>Dummy Ad Hoc</string>
+	<key>UUID</key>
+	<string>B5C2906D-D6EE-476E-AF17-D99AE14644AA</string>
+	<key>TeamName</key>
+	<string>Dummy Ltd</string>
+	<key>ProvisionedDevices</key>
+	<array><string>device</string></array>
+	<key>Entitlements</key>
+	<dict>
+		<key>application-identifier</key>
+		<string>12345ABCDE.com.dummy.app</string>
+		<key>com.apple.developer.team-identifier</key>
+		<string>12345ABCDE</string>
+	</dict>
+</dict>
+</plist>`
+
+const entitlements = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>aps-environment</key><string>production</string></dict></plist>`
+
+type inspectSuite struct {
+	suite.Suite
+	API     *api.API
+	dir     string
+	files   map[string][]byte
+	subject inspector
+}
+
+func TestInspectSuite(t *testing.T) {
+	suite.Run(t, new(inspectSuite))
+}
+
+func (s *inspectSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "bundle")
+	s.Require().NoError(err)
+	s.dir = dir
+
+	s.API = &api.API{Config: &api.Config{}}
+	s.API.ProvisioningService = signature.NewProvisioningService(s.API)
+	s.subject = inspector{s.API}
+
+	p7, err := pkcs7.NewSignedData([]byte(profile))
+	s.Require().NoError(err)
+	embedded, err := p7.Finish()
+	s.Require().NoError(err)
+
+	sig := utiltest.NewCodeSignature("com.dummy.app", "12345ABCDE", []byte(entitlements), nil)
+	s.files = map[string][]byte{
+		"Info.plist":                             []byte(appInfoPlist),
+		"Dummy":                                  utiltest.NewMachO(sig),
+		"embedded.mobileprovision":               embedded,
+		"PlugIns/Widget.appex/Info.plist":        []byte(extensionInfoPlist),
+		"Frameworks/Kit.framework/Info.plist":    []byte(frameworkInfoPlist),
+		"Frameworks/Kit.framework/Kit":           utiltest.NewMachO(nil),
+		"Frameworks/Kit.framework/Headers/Kit.h": []byte(""),
+	}
+}
+
+func (s *inspectSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *inspectSuite) writeApp(root string) {
+	for name, content := range s.files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		s.Require().NoError(os.MkdirAll(filepath.Dir(path), 0755))
+		s.Require().NoError(ioutil.WriteFile(path, content, 0644))
+	}
+}
+
+func (s *inspectSuite) writeIPA(path string) {
+	f, err := os.Create(path)
+	s.Require().NoError(err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range s.files {
+		e, err := w.Create("Payload/Dummy.app/" + name)
+		s.Require().NoError(err)
+		_, err = e.Write(content)
+		s.Require().NoError(err)
+	}
+	s.Require().NoError(w.Close())
+}
+
+func (s *inspectSuite) assertApplication(app api.BundleInfo) {
+	s.Equal("com.dummy.app", app.BundleIdentifier)
+	s.Equal(KindApplication, app.Kind)
+	s.Equal("Dummy", app.Name)
+	s.Equal("1.0", app.ShortVersion)
+	s.Equal("7", app.Version)
+	s.Equal([]string{"iPhoneOS"}, app.Platforms)
+	s.Empty(app.Errors)
+
+	s.Require().NotNil(app.ProvisioningProfile)
+	s.Equal("B5C2906D-D6EE-476E-AF17-D99AE14644AA", app.ProvisioningProfile.UUID)
+	s.Equal("ad-hoc", app.ProvisioningProfile.Method)
+	s.Equal(1, app.ProvisioningProfile.Devices)
+
+	s.Require().NotNil(app.Signature)
+	s.Equal([]string{"arm64"}, app.Signature.Architectures)
+	s.Equal("12345ABCDE", app.Signature.TeamID)
+	s.Equal(map[string]interface{}{"aps-environment": "production"}, app.Signature.Entitlements)
+
+	s.Require().Len(app.Nested, 2)
+	s.Equal(KindFramework, app.Nested[0].Kind)
+	s.Equal("com.dummy.kit", app.Nested[0].BundleIdentifier)
+	s.Equal([]string{"Kit: Not signed"}, app.Nested[0].Errors)
+	s.Equal(KindAppExtension, app.Nested[1].Kind)
+	s.Equal("com.dummy.app.widget", app.Nested[1].BundleIdentifier)
+}
+
+func (s *inspectSuite) TestInspectApp() {
+	// setup:
+	root := filepath.Join(s.dir, "Dummy.app")
+	s.writeApp(root)
+
+	// when:
+	res, err := s.subject.Inspect(context.Background(), root)
+
+	// then:
+	s.Require().NoError(err)
+	s.Equal(TypeApp, res.Type)
+	s.Require().Len(res.Applications, 1)
+	s.Equal(".", res.Applications[0].Path)
+	s.assertApplication(res.Applications[0])
+}
+
+func (s *inspectSuite) TestInspectArchive() {
+	// setup:
+	root := filepath.Join(s.dir, "Dummy.xcarchive")
+	s.writeApp(filepath.Join(root, "Products", "Applications", "Dummy.app"))
+
+	// when:
+	res, err := s.subject.Inspect(context.Background(), root)
+
+	// then:
+	s.Require().NoError(err)
+	s.Equal(TypeArchive, res.Type)
+	s.Require().Len(res.Applications, 1)
+	s.Equal("Products/Applications/Dummy.app", res.Applications[0].Path)
+	s.assertApplication(res.Applications[0])
+}
+
+func (s *inspectSuite) TestInspectIPA() {
+	// setup:
+	path := filepath.Join(s.dir, "Dummy.ipa")
+	s.writeIPA(path)
+
+	// when:
+	res, err := s.subject.Inspect(context.Background(), path)
+
+	// then:
+	s.Require().NoError(err)
+	s.Equal(TypeIPA, res.Type)
+	s.Require().Len(res.Applications, 1)
+	s.Equal("Payload/Dummy.app", res.Applications[0].Path)
+	s.assertApplication(res.Applications[0])
+}
+
+func (s *inspectSuite) TestInspectWithoutApplication() {
+	// when:
+	_, err := s.subject.Inspect(context.Background(), s.dir)
+
+	// then:
+	s.Equal(ErrNoApplication, err)
+}
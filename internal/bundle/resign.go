@@ -0,0 +1,394 @@
+package bundle
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"dothething/internal/api"
+	"dothething/internal/signature"
+	"dothething/internal/util"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"howett.net/plist"
+)
+
+const (
+	// CodeSignUtil the tool signing the bundles
+	CodeSignUtil = "codesign"
+
+	// The entitlements holding the team identifier prefixed wildcards of the profiles
+	EntitlementApplicationIdentifier = "application-identifier"
+	EntitlementKeychainAccessGroups  = "keychain-access-groups"
+)
+
+var dylibRegexp = regexp.MustCompile(`^[^/]+\.dylib$`)
+
+type resigner struct {
+	*api.API
+}
+
+// NewResigner create a new instance of the IPA re-signing service
+func NewResigner(api *api.API) api.BundleResigner {
+	return resigner{api}
+}
+
+// Resign unpacks the IPA, replaces the provisioning profiles of its bundles by the mapped
+// ones, signs them from the innermost to the outermost and packs the result to output
+func (r resigner) Resign(ctx context.Context, ipa, output string, profiles map[string]string) error {
+	work, err := ioutil.TempDir("", "resign")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(work)
+
+	root := filepath.Join(work, "ipa")
+	if err := unpack(ipa, root); err != nil {
+		return err
+	}
+
+	fs, err := newDirFS(root)
+	if err != nil {
+		return err
+	}
+
+	apps := applicationRoots(fs, TypeIPA)
+	if len(apps) == 0 {
+		return ErrNoApplication
+	}
+
+	pps, err := r.mappedProfiles(ctx, profiles)
+	if err != nil {
+		return err
+	}
+
+	if err := r.API.KeyChain.Create(ctx); err != nil {
+		return err
+	}
+	defer r.API.KeyChain.Delete(ctx)
+
+	s := signer{
+		API:        r.API,
+		fs:         fs,
+		identities: map[string]string{},
+		profiles:   pps,
+		root:       root,
+		work:       work,
+	}
+	for _, app := range apps {
+		if err := s.sign(ctx, app, KindApplication, ""); err != nil {
+			return err
+		}
+	}
+
+	return pack(root, output)
+}
+
+// mappedProfiles resolves the provisioning profiles of the mapping by bundle identifier,
+// looking them up in the signing assets and the installed profiles
+func (r resigner) mappedProfiles(
+	ctx context.Context,
+	mapping map[string]string,
+) (map[string]*api.ProvisioningProfile, error) {
+	candidates := r.API.SigningAssetSource.ProvisioningProfiles(ctx)
+	if dir, err := signature.InstalledProfilesDir(); err == nil {
+		if _, err := os.Stat(dir); err == nil {
+			candidates = append(candidates, r.API.ProvisioningService.ResolveProvisioningFilesInFolder(ctx, dir)...)
+		}
+	}
+
+	res := map[string]*api.ProvisioningProfile{}
+	for bundleID, uuid := range mapping {
+		for _, pp := range candidates {
+			if strings.EqualFold(pp.UUID, uuid) {
+				res[bundleID] = pp
+				break
+			}
+		}
+
+		if res[bundleID] == nil {
+			return nil, fmt.Errorf("provisioning profile %v not found for the bundle identifier %v", uuid, bundleID)
+		}
+	}
+
+	return res, nil
+}
+
+// signer signs the bundles of an unpacked IPA
+type signer struct {
+	*api.API
+	fs FS
+	// identities the signing identities, by provisioning profile UUID
+	identities map[string]string
+	profiles   map[string]*api.ProvisioningProfile
+	root       string
+	work       string
+}
+
+// sign signs the bundles embedded into the bundle first, then the bundle itself, the
+// frameworks being signed with the identity of the bundle embedding them
+func (s signer) sign(ctx context.Context, root, kind, identity string) error {
+	info, err := readInfo(s.fs, root)
+	if err != nil {
+		return fmt.Errorf("failed to read the Info.plist of %v (%v)", root, err)
+	}
+
+	var entitlements string
+	if kind != KindFramework {
+		pp, ok := s.profiles[info.BundleIdentifier]
+		if !ok {
+			return fmt.Errorf("no provisioning profile mapped for the bundle identifier %v", info.BundleIdentifier)
+		}
+
+		if identity, err = s.identity(ctx, pp); err != nil {
+			return err
+		}
+
+		if entitlements, err = s.embed(root, info.BundleIdentifier, pp); err != nil {
+			return err
+		}
+	}
+
+	for _, n := range nestedRoots(s.fs, root) {
+		if err := s.sign(ctx, n.root, n.kind, identity); err != nil {
+			return err
+		}
+	}
+
+	// The dynamic libraries, like the Swift runtime ones, are not bundles
+	for _, f := range s.fs.Files() {
+		rel := strings.TrimPrefix(f, root+"Frameworks/")
+		if rel != f && dylibRegexp.MatchString(rel) {
+			if err := s.codesign(ctx, identity, "", f); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.codesign(ctx, identity, entitlements, root)
+}
+
+// identity imports the certificate of the provisioning profile, returning its fingerprint
+func (s signer) identity(ctx context.Context, pp *api.ProvisioningProfile) (string, error) {
+	if id, ok := s.identities[pp.UUID]; ok {
+		return id, nil
+	}
+
+	for _, c := range s.API.SigningAssetSource.Certificates(ctx) {
+		if !containsCertificate(pp.Certificates, c.Raw) {
+			continue
+		}
+
+		if err := s.API.SignatureService.ImportCertificate(ctx, c); err != nil {
+			return "", err
+		}
+
+		sum := sha1.Sum(c.Raw)
+		s.identities[pp.UUID] = strings.ToUpper(hex.EncodeToString(sum[:]))
+
+		return s.identities[pp.UUID], nil
+	}
+
+	return "", fmt.Errorf("no certificate found for the provisioning profile %q", pp.Name)
+}
+
+// embed replaces the provisioning profile of the bundle, and writes the entitlements granted
+// by the profile to sign the bundle with
+func (s signer) embed(root, bundleID string, pp *api.ProvisioningProfile) (string, error) {
+	b := pp.Content
+	if len(b) == 0 {
+		content, err := ioutil.ReadFile(pp.FilePath)
+		if err != nil {
+			return "", err
+		}
+		b = content
+	}
+
+	dst := filepath.Join(s.root, filepath.FromSlash(root), "embedded.mobileprovision")
+	if err := ioutil.WriteFile(dst, b, 0644); err != nil {
+		return "", err
+	}
+
+	ent, err := decodeProfileEntitlements(b)
+	if err != nil {
+		return "", err
+	}
+	resolveWildcards(ent, bundleID)
+
+	res, err := plist.MarshalIndent(ent, plist.XMLFormat, "\t")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(s.work, bundleID+".entitlements")
+
+	return path, ioutil.WriteFile(path, res, 0644)
+}
+
+// resolveWildcards replaces the wildcard application identifier and keychain access groups of
+// the profile, like TEAMID.*, with the ones of the bundle, the wildcards not being valid
+// entitlements of a signed bundle
+func resolveWildcards(ent map[string]interface{}, bundleID string) {
+	resolve := func(v string) string {
+		if i := strings.Index(v, "."); i > 0 && strings.HasSuffix(v, "*") {
+			return v[:i+1] + bundleID
+		}
+
+		return v
+	}
+
+	if v, ok := ent[EntitlementApplicationIdentifier].(string); ok {
+		ent[EntitlementApplicationIdentifier] = resolve(v)
+	}
+
+	if groups, ok := ent[EntitlementKeychainAccessGroups].([]interface{}); ok {
+		for i, g := range groups {
+			if v, ok := g.(string); ok {
+				groups[i] = resolve(v)
+			}
+		}
+	}
+}
+
+// codesign signs the file or bundle at path, relative to the IPA root
+func (s signer) codesign(ctx context.Context, identity, entitlements, path string) error {
+	args := []string{"--force", "--sign", identity}
+	// The login keychain being looked up through the search list
+	if kc := s.API.KeyChain.GetPath(); kc != "" {
+		args = append(args, "--keychain", kc)
+	}
+	if entitlements != "" {
+		args = append(args, "--entitlements", entitlements)
+	}
+	args = append(args, filepath.Join(s.root, filepath.FromSlash(strings.TrimSuffix(path, "/"))))
+
+	b, err := s.API.Exec.CommandContext(ctx, CodeSignUtil, args...).CombinedOutput()
+	if err != nil {
+		log.Error().
+			Str("Path", path).
+			Bytes("Output", b).
+			Msg("Failed to sign")
+		return fmt.Errorf("failed to sign %v (%v)", path, err)
+	}
+
+	return nil
+}
+
+// unpack extracts the zip file to the folder
+func unpack(src, dst string) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		path := filepath.Join(dst, filepath.FromSlash(f.Name))
+		if !isInside(dst, path) {
+			return fmt.Errorf("invalid file path %v", f.Name)
+		}
+
+		// Writing through a symbolic link of the archive would escape the folder
+		if err := checkParents(dst, path); err != nil {
+			return err
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := extract(f, dst, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isInside is true when the path is below the folder
+func isInside(dst, path string) bool {
+	return strings.HasPrefix(filepath.Clean(path), filepath.Clean(dst)+string(os.PathSeparator))
+}
+
+// checkParents fails when one of the parent folders of the path, below dst, is a symbolic link
+func checkParents(dst, path string) error {
+	root := filepath.Clean(dst)
+	for p := filepath.Dir(path); isInside(root, p); p = filepath.Dir(p) {
+		fi, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("invalid file path %v, writing through the symbolic link %v", path, p)
+		}
+	}
+
+	return nil
+}
+
+// extract writes the zip file entry to path, keeping its permissions and the symbolic links
+// resolving inside the folder dst
+func extract(f *zip.File, dst, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if f.Mode()&os.ModeSymlink != 0 {
+		b, err := ioutil.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+
+		target := string(b)
+		if filepath.IsAbs(target) || !isInside(dst, filepath.Join(filepath.Dir(path), target)) {
+			return fmt.Errorf("invalid symbolic link %v to %v", f.Name, target)
+		}
+
+		return os.Symlink(target, path)
+	}
+
+	w, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, f.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+// pack zips the content of the folder to the file
+func pack(src, dst string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	if err := util.ZipFolder(w, src, ""); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
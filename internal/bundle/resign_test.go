@@ -0,0 +1,372 @@
+package bundle
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"dothething/internal/api"
+	"dothething/internal/path"
+	"dothething/internal/signature"
+	"dothething/internal/utiltest"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mozilla.org/pkcs7"
+	"howett.net/plist"
+)
+
+const resignProfileTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Name</key>
+	<string>Dummy Ad Hoc</string>
+	<key>UUID</key>
+	<string>B5C2906D</string>
+	<key>DeveloperCertificates</key>
+	<array><data>%v</data></array>
+	<key>Entitlements</key>
+	<dict>
+		<key>application-identifier</key>
+		<string>12345ABCDE.com.dummy.*</string>
+		<key>get-task-allow</key>
+		<false/>
+		<key>keychain-access-groups</key>
+		<array><string>12345ABCDE.*</string><string>12345ABCDE.com.dummy.shared</string></array>
+	</dict>
+</dict>
+</plist>`
+
+type resignSuite struct {
+	suite.Suite
+	API      *api.API
+	cert     *api.P12Certificate
+	dir      string
+	exec     *utiltest.MockExecutor
+	profile  []byte
+	imported []*api.P12Certificate
+	signed   []string
+	subject  resigner
+}
+
+func TestResignSuite(t *testing.T) {
+	suite.Run(t, new(resignSuite))
+}
+
+func (s *resignSuite) SetupSuite() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+
+	tpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Apple Distribution: Dummy Ltd (12345ABCDE)"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tpl, &tpl, &key.PublicKey, key)
+	s.Require().NoError(err)
+
+	c, err := x509.ParseCertificate(der)
+	s.Require().NoError(err)
+	s.cert = &api.P12Certificate{Certificate: c}
+
+	p7, err := pkcs7.NewSignedData([]byte(fmt.Sprintf(resignProfileTemplate, base64.StdEncoding.EncodeToString(der))))
+	s.Require().NoError(err)
+	s.profile, err = p7.Finish()
+	s.Require().NoError(err)
+}
+
+func (s *resignSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "resign")
+	s.Require().NoError(err)
+	s.dir = dir
+	s.imported = nil
+	s.signed = nil
+
+	s.exec = new(utiltest.MockExecutor)
+	s.API = &api.API{Config: &api.Config{Path: filepath.Join(dir, "Dummy.xcodeproj")}}
+	s.API.Exec = s.exec
+	s.API.KeyChain = fakeKeyChain{}
+	s.API.PathService = path.NewPathService(s.API)
+	s.API.ProvisioningService = signature.NewProvisioningService(s.API)
+	s.API.SignatureService = fakeSignatureService{s}
+	s.API.SigningAssetSource = fakeAssetSource{s}
+	s.subject = resigner{s.API}
+}
+
+func (s *resignSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+// fakeKeyChain a keychain doing nothing
+type fakeKeyChain struct{}
+
+func (fakeKeyChain) Cleanup(ctx context.Context, dirs []string) ([]string, error) { return nil, nil }
+func (fakeKeyChain) Create(ctx context.Context) error                             { return nil }
+func (fakeKeyChain) Delete(ctx context.Context) error                             { return nil }
+func (fakeKeyChain) Identities(ctx context.Context) ([]api.SigningIdentity, error) {
+	return nil, nil
+}
+func (fakeKeyChain) IdentityCertificates(ctx context.Context) ([]*x509.Certificate, error) {
+	return nil, nil
+}
+func (fakeKeyChain) ImportIntermediates(ctx context.Context, path string) error { return nil }
+func (fakeKeyChain) ImportCertificate(ctx context.Context, path, password, name string) error {
+	return nil
+}
+func (fakeKeyChain) GetPath() string { return "/tmp/dummy.keychain" }
+
+// fakeSignatureService records the imported certificates
+type fakeSignatureService struct {
+	s *resignSuite
+}
+
+func (f fakeSignatureService) Run(ctx context.Context) error                  { return nil }
+func (f fakeSignatureService) GetConfiguration() *[]api.TargetSignatureConfig { return nil }
+func (f fakeSignatureService) ForMethod(ctx context.Context, method string) ([]api.TargetSignatureConfig, error) {
+	return nil, nil
+}
+func (f fakeSignatureService) ImportCertificate(ctx context.Context, c *api.P12Certificate) error {
+	f.s.imported = append(f.s.imported, c)
+	return nil
+}
+
+// fakeAssetSource provides the certificate and the provisioning profile of the suite
+type fakeAssetSource struct {
+	s *resignSuite
+}
+
+func (f fakeAssetSource) Certificates(ctx context.Context) []*api.P12Certificate {
+	return []*api.P12Certificate{f.s.cert}
+}
+
+func (f fakeAssetSource) ProvisioningProfiles(ctx context.Context) []*api.ProvisioningProfile {
+	return []*api.ProvisioningProfile{{
+		Certificates: []*x509.Certificate{f.s.cert.Certificate},
+		Content:      f.s.profile,
+		Name:         "Dummy Ad Hoc",
+		UUID:         "B5C2906D",
+	}}
+}
+
+// writeIPA writes an application embedding an extension, a framework and a library
+func (s *resignSuite) writeIPA(path string) {
+	f, err := os.Create(path)
+	s.Require().NoError(err)
+	defer f.Close()
+
+	files := map[string]string{
+		"Payload/Dummy.app/Info.plist":                          fmt.Sprintf(auditInfoTemplate, "com.dummy.app"),
+		"Payload/Dummy.app/embedded.mobileprovision":            "enterprise",
+		"Payload/Dummy.app/PlugIns/Ext.appex/Info.plist":        fmt.Sprintf(auditInfoTemplate, "com.dummy.ext"),
+		"Payload/Dummy.app/Frameworks/Kit.framework/Info.plist": fmt.Sprintf(auditInfoTemplate, "com.dummy.kit"),
+		"Payload/Dummy.app/Frameworks/libswiftCore.dylib":       "",
+	}
+
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		e, err := w.Create(name)
+		s.Require().NoError(err)
+		_, err = e.Write([]byte(content))
+		s.Require().NoError(err)
+	}
+
+	h := &zip.FileHeader{Name: "Payload/Dummy.app/Exec"}
+	h.SetMode(0755)
+	e, err := w.CreateHeader(h)
+	s.Require().NoError(err)
+	_, err = e.Write([]byte("executable"))
+	s.Require().NoError(err)
+
+	s.Require().NoError(w.Close())
+}
+
+// mockCodeSign records the signed paths, checking the entitlements when provided
+func (s *resignSuite) mockCodeSign() {
+	cmd := new(utiltest.MockExecutorCmd)
+	cmd.On("CombinedOutput").Return("", nil)
+
+	s.exec.
+		On("CommandContext", mock.Anything, CodeSignUtil, mock.Anything).
+		Run(func(args mock.Arguments) {
+			a := args.Get(2).([]string)
+			s.Equal([]string{"--force", "--sign", s.fingerprint(), "--keychain", "/tmp/dummy.keychain"}, a[:5])
+
+			if a[5] == "--entitlements" {
+				b, err := ioutil.ReadFile(a[6])
+				s.Require().NoError(err)
+
+				var ent map[string]interface{}
+				_, err = plist.Unmarshal(b, &ent)
+				s.Require().NoError(err)
+				// The wildcards of the profile are resolved for the bundle
+				bundleID := strings.TrimSuffix(filepath.Base(a[6]), ".entitlements")
+				s.Equal("12345ABCDE."+bundleID, ent[EntitlementApplicationIdentifier])
+				s.Equal(
+					[]interface{}{"12345ABCDE." + bundleID, "12345ABCDE.com.dummy.shared"},
+					ent[EntitlementKeychainAccessGroups],
+				)
+			}
+
+			p := a[len(a)-1]
+			s.signed = append(s.signed, p[strings.Index(p, "Payload/"):])
+		}).
+		Return(cmd)
+}
+
+func (s *resignSuite) fingerprint() string {
+	return fmt.Sprintf("%X", sha1.Sum(s.cert.Raw))
+}
+
+func (s *resignSuite) TestResign() {
+	// setup:
+	ipa := filepath.Join(s.dir, "Dummy.ipa")
+	output := filepath.Join(s.dir, "Dummy-resigned.ipa")
+	s.writeIPA(ipa)
+	s.mockCodeSign()
+
+	// when:
+	err := s.subject.Resign(context.Background(), ipa, output, map[string]string{
+		"com.dummy.app": "B5C2906D",
+		"com.dummy.ext": "b5c2906d",
+	})
+
+	// then: the bundles should be signed inside-out, importing the certificate once
+	s.Require().NoError(err)
+	s.Equal([]string{
+		"Payload/Dummy.app/Frameworks/Kit.framework",
+		"Payload/Dummy.app/PlugIns/Ext.appex",
+		"Payload/Dummy.app/Frameworks/libswiftCore.dylib",
+		"Payload/Dummy.app",
+	}, s.signed)
+	s.Len(s.imported, 1)
+
+	// and: the provisioning profiles should be replaced, keeping the permissions
+	r, err := zip.OpenReader(output)
+	s.Require().NoError(err)
+	defer r.Close()
+
+	fs := newZipFS(r)
+	b, err := fs.ReadFile("Payload/Dummy.app/embedded.mobileprovision")
+	s.Require().NoError(err)
+	s.Equal(s.profile, b)
+
+	b, err = fs.ReadFile("Payload/Dummy.app/PlugIns/Ext.appex/embedded.mobileprovision")
+	s.Require().NoError(err)
+	s.Equal(s.profile, b)
+
+	s.Equal(os.FileMode(0755), fs.files["Payload/Dummy.app/Exec"].Mode().Perm())
+}
+
+func (s *resignSuite) TestResignWithoutMapping() {
+	// setup:
+	ipa := filepath.Join(s.dir, "Dummy.ipa")
+	s.writeIPA(ipa)
+	s.mockCodeSign()
+
+	// when:
+	err := s.subject.Resign(context.Background(), ipa, filepath.Join(s.dir, "out.ipa"), map[string]string{
+		"com.dummy.app": "B5C2906D",
+	})
+
+	// then:
+	s.EqualError(err, "no provisioning profile mapped for the bundle identifier com.dummy.ext")
+	s.NoFileExists(filepath.Join(s.dir, "out.ipa"))
+}
+
+func (s *resignSuite) TestResignWithUnknownProfile() {
+	// setup:
+	ipa := filepath.Join(s.dir, "Dummy.ipa")
+	s.writeIPA(ipa)
+
+	// when:
+	err := s.subject.Resign(context.Background(), ipa, filepath.Join(s.dir, "out.ipa"), map[string]string{
+		"com.dummy.app": "UNKNOWN",
+	})
+
+	// then:
+	s.EqualError(err, "provisioning profile UNKNOWN not found for the bundle identifier com.dummy.app")
+}
+
+// writeZip writes the entries, the ones of the links map being symbolic links to their target
+func (s *resignSuite) writeZip(path string, links map[string]string, files ...string) {
+	f, err := os.Create(path)
+	s.Require().NoError(err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, target := range links {
+		h := &zip.FileHeader{Name: name}
+		h.SetMode(os.ModeSymlink | 0755)
+		e, err := w.CreateHeader(h)
+		s.Require().NoError(err)
+		_, err = e.Write([]byte(target))
+		s.Require().NoError(err)
+	}
+
+	for _, name := range files {
+		e, err := w.Create(name)
+		s.Require().NoError(err)
+		_, err = e.Write([]byte("content"))
+		s.Require().NoError(err)
+	}
+
+	s.Require().NoError(w.Close())
+}
+
+func (s *resignSuite) TestUnpackKeepsInnerLinks() {
+	// setup:
+	src := filepath.Join(s.dir, "links.zip")
+	dst := filepath.Join(s.dir, "out")
+	s.writeZip(src, map[string]string{"Payload/Kit.framework/Kit": "Versions/A/Kit"})
+
+	// when:
+	err := unpack(src, dst)
+
+	// then:
+	s.Require().NoError(err)
+	target, err := os.Readlink(filepath.Join(dst, "Payload/Kit.framework/Kit"))
+	s.Require().NoError(err)
+	s.Equal("Versions/A/Kit", target)
+}
+
+func (s *resignSuite) TestUnpackRejectsEscapingLinks() {
+	for _, target := range []string{"/", "../../..", "../../Other"} {
+		// setup:
+		src := filepath.Join(s.dir, "links.zip")
+		s.writeZip(src, map[string]string{"Payload/x": target})
+
+		// when:
+		err := unpack(src, filepath.Join(s.dir, "out"))
+
+		// then:
+		s.EqualError(err, fmt.Sprintf("invalid symbolic link Payload/x to %v", target))
+	}
+}
+
+func (s *resignSuite) TestUnpackRejectsWritingThroughLinks() {
+	// setup: a link to a folder of the archive, then a file written through it
+	src := filepath.Join(s.dir, "links.zip")
+	dst := filepath.Join(s.dir, "out")
+	s.writeZip(src, map[string]string{"Payload/x": "y"}, "Payload/x/file")
+
+	// when:
+	err := unpack(src, dst)
+
+	// then:
+	s.Error(err)
+	s.Contains(err.Error(), "writing through the symbolic link")
+	s.NoFileExists(filepath.Join(dst, "Payload/y/file"))
+}
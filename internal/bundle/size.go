@@ -0,0 +1,146 @@
+package bundle
+
+import (
+	"archive/zip"
+	"context"
+	"dothething/internal/api"
+	"path"
+	"sort"
+	"strings"
+)
+
+// The kinds of the size report items, in addition to the kinds of the embedded bundles
+const (
+	SizeKindAssetCatalog = "asset-catalog"
+	SizeKindExecutable   = "executable"
+	SizeKindLibrary      = "library"
+	SizeKindLocalization = "localization"
+	SizeKindOther        = "other"
+	SizeKindResource     = "resource"
+)
+
+// largestFiles the count of files reported as the largest ones
+const largestFiles = 10
+
+type sizeAnalyzer struct {
+	*api.API
+}
+
+// NewSizeAnalyzer create a new instance of the package size analyzer
+func NewSizeAnalyzer(api *api.API) api.SizeAnalyzer {
+	return sizeAnalyzer{api}
+}
+
+// Analyze sums the sizes of the files of the IPA by embedded bundle, asset catalog,
+// localization and resource type, the files outside of the application, like SwiftSupport,
+// being summed by top folder
+func (s sizeAnalyzer) Analyze(ctx context.Context, ipa string) (*api.SizeReport, error) {
+	r, err := zip.OpenReader(ipa)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := newZipFS(r)
+	defer fs.Close()
+
+	roots := applicationRoots(fs, TypeIPA)
+	if len(roots) == 0 {
+		return nil, ErrNoApplication
+	}
+
+	info, err := readInfo(fs, roots[0])
+	if err != nil {
+		return nil, err
+	}
+
+	res := &api.SizeReport{Path: ipa}
+	items := map[string]*api.SizeItem{}
+	var files []api.SizeItem
+	for _, name := range fs.Files() {
+		f := fs.files[name]
+
+		var kind, item string
+		if rel := strings.TrimPrefix(name, roots[0]); rel != name {
+			kind, item = sizeItem(rel, info.Executable)
+		} else {
+			kind, item = SizeKindOther, strings.SplitN(name, "/", 2)[0]
+		}
+
+		compressed, uncompressed := int64(f.CompressedSize64), int64(f.UncompressedSize64)
+		res.Compressed += compressed
+		res.Uncompressed += uncompressed
+
+		i, ok := items[kind+"/"+item]
+		if !ok {
+			i = &api.SizeItem{Kind: kind, Name: item}
+			items[kind+"/"+item] = i
+		}
+		i.Compressed += compressed
+		i.Uncompressed += uncompressed
+
+		files = append(files, api.SizeItem{
+			Compressed:   compressed,
+			Kind:         kind,
+			Name:         name,
+			Uncompressed: uncompressed,
+		})
+	}
+
+	for _, i := range items {
+		res.Items = append(res.Items, *i)
+	}
+	sortSizeItems(res.Items)
+
+	sortSizeItems(files)
+	if len(files) > largestFiles {
+		files = files[:largestFiles]
+	}
+	res.Largest = files
+
+	return res, nil
+}
+
+// sizeItem resolves the item the file of the application belongs to, rel being relative to
+// the application root
+func sizeItem(rel, executable string) (string, string) {
+	parts := strings.Split(rel, "/")
+	for _, n := range nestedFolders {
+		if len(parts) > 2 && parts[0] == n.folder && strings.HasSuffix(parts[1], n.ext) {
+			return n.kind, parts[0] + "/" + parts[1]
+		}
+	}
+
+	switch {
+	case rel == executable:
+		return SizeKindExecutable, rel
+	case parts[0] == "Frameworks" && len(parts) == 2:
+		// The Swift runtime and the other libraries not wrapped into a framework
+		return SizeKindLibrary, rel
+	case path.Base(rel) == "Assets.car":
+		return SizeKindAssetCatalog, rel
+	}
+
+	for _, p := range parts[:len(parts)-1] {
+		if path.Ext(p) == ".lproj" {
+			return SizeKindLocalization, p
+		}
+	}
+
+	ext := strings.ToLower(path.Ext(rel))
+	if ext == "" {
+		ext = "(none)"
+	}
+
+	return SizeKindResource, ext
+}
+
+// sortSizeItems sorts the items by decreasing compressed size, then by name
+func sortSizeItems(items []api.SizeItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Compressed != items[j].Compressed {
+			return items[i].Compressed > items[j].Compressed
+		}
+
+		return items[i].Name < items[j].Name
+	})
+}
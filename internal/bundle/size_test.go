@@ -0,0 +1,133 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"dothething/internal/api"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type sizeSuite struct {
+	suite.Suite
+	dir     string
+	subject sizeAnalyzer
+}
+
+func TestSizeSuite(t *testing.T) {
+	suite.Run(t, new(sizeSuite))
+}
+
+func (s *sizeSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "size")
+	s.Require().NoError(err)
+	s.dir = dir
+
+	s.subject = sizeAnalyzer{&api.API{Config: &api.Config{}}}
+}
+
+func (s *sizeSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+// writeIPA stores the files uncompressed, for the compressed sizes to be predictable
+func (s *sizeSuite) writeIPA(files map[string]int) string {
+	path := filepath.Join(s.dir, "Dummy.ipa")
+	f, err := os.Create(path)
+	s.Require().NoError(err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, size := range files {
+		e, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		s.Require().NoError(err)
+
+		content := bytes.Repeat([]byte{'a'}, size)
+		if name == "Payload/Dummy.app/Info.plist" {
+			content = []byte(appInfoPlist)
+		}
+		_, err = e.Write(content)
+		s.Require().NoError(err)
+	}
+	s.Require().NoError(w.Close())
+
+	return path
+}
+
+func (s *sizeSuite) TestAnalyze() {
+	// setup:
+	path := s.writeIPA(map[string]int{
+		"Payload/Dummy.app/Info.plist":                           0,
+		"Payload/Dummy.app/Dummy":                                500,
+		"Payload/Dummy.app/Assets.car":                           400,
+		"Payload/Dummy.app/en.lproj/Localizable.strings":         30,
+		"Payload/Dummy.app/en.lproj/Main.storyboardc/Info.plist": 20,
+		"Payload/Dummy.app/logo.PNG":                             60,
+		"Payload/Dummy.app/icon.png":                             40,
+		"Payload/Dummy.app/Frameworks/Kit.framework/Kit":         300,
+		"Payload/Dummy.app/Frameworks/Kit.framework/Info.plist":  10,
+		"Payload/Dummy.app/Frameworks/libswiftCore.dylib":        200,
+		"Payload/Dummy.app/PlugIns/Widget.appex/Widget":          150,
+		"SwiftSupport/iphoneos/libswiftCore.dylib":               200,
+	})
+
+	// when:
+	res, err := s.subject.Analyze(context.Background(), path)
+
+	// then:
+	s.Require().NoError(err)
+	s.Equal(path, res.Path)
+	s.Equal(res.Compressed, res.Uncompressed)
+
+	sizes := map[string]int64{}
+	for _, i := range res.Items {
+		sizes[i.Kind+" "+i.Name] = i.Compressed
+	}
+	s.Equal(int64(500), sizes["executable Dummy"])
+	s.Equal(int64(400), sizes["asset-catalog Assets.car"])
+	s.Equal(int64(50), sizes["localization en.lproj"])
+	s.Equal(int64(100), sizes["resource .png"])
+	s.Equal(int64(310), sizes["framework Frameworks/Kit.framework"])
+	s.Equal(int64(200), sizes["library Frameworks/libswiftCore.dylib"])
+	s.Equal(int64(150), sizes["app-extension PlugIns/Widget.appex"])
+	s.Equal(int64(200), sizes["other SwiftSupport"])
+
+	s.Equal("executable", res.Items[0].Kind)
+	s.Require().Len(res.Largest, largestFiles)
+	s.Equal("Payload/Dummy.app/Dummy", res.Largest[0].Name)
+}
+
+func (s *sizeSuite) TestDiff() {
+	// setup:
+	baseline := api.SizeReport{
+		Compressed: 1000,
+		Items: []api.SizeItem{
+			{Kind: SizeKindExecutable, Name: "Dummy", Compressed: 600, Uncompressed: 900},
+			{Kind: SizeKindAssetCatalog, Name: "Assets.car", Compressed: 300, Uncompressed: 300},
+			{Kind: KindFramework, Name: "Frameworks/Old.framework", Compressed: 100, Uncompressed: 200},
+		},
+	}
+	report := api.SizeReport{
+		Compressed: 1100,
+		Items: []api.SizeItem{
+			{Kind: SizeKindExecutable, Name: "Dummy", Compressed: 800, Uncompressed: 1200},
+			{Kind: SizeKindAssetCatalog, Name: "Assets.car", Compressed: 300, Uncompressed: 300},
+		},
+	}
+
+	// when:
+	diff := report.Diff(baseline)
+
+	// then:
+	s.Equal(int64(100), diff.Compressed)
+	s.Equal(10.0, diff.Growth)
+	s.Equal([]api.SizeItem{
+		{Kind: SizeKindExecutable, Name: "Dummy", Compressed: 200, Uncompressed: 300},
+		{Kind: KindFramework, Name: "Frameworks/Old.framework", Compressed: -100, Uncompressed: -200},
+	}, diff.Items)
+}
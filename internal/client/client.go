@@ -3,10 +3,15 @@ package client
 import (
 	"dothething/internal/action"
 	"dothething/internal/api"
+	"dothething/internal/archive"
+	"dothething/internal/bundle"
 	"dothething/internal/destination"
 	"dothething/internal/keychain"
+	"dothething/internal/ota"
 	"dothething/internal/path"
+	"dothething/internal/repository"
 	"dothething/internal/signature"
+	"dothething/internal/symbolicate"
 	"dothething/internal/util"
 	"dothething/internal/xcode"
 	"dothething/internal/xcode/project"
@@ -28,19 +33,32 @@ func NewAPIClient() (*api.API, error) {
 	a.ActionBuild = action.NewBuild(&a)
 	a.ActionPack = action.NewActionPackage(&a)
 	a.ActionRunTest = action.NewActionRunTest(&a)
+	a.ActionSymbols = action.NewActionSymbols(&a)
 
+	a.ArchiveService = archive.NewArchiveService(&a)
+	a.BinaryAnalyzer = bundle.NewBinaryAnalyzer(&a)
 	a.BuildService = xcode.NewService(&a)
+	a.BundleAuditor = bundle.NewAuditor(&a)
+	a.BundleInspector = bundle.NewInspector(&a)
+	a.BundleResigner = bundle.NewResigner(&a)
 	a.CertificateService = signature.NewCertificateService(&a)
 	a.DestinationService = destination.NewDestinationService(&a)
 	a.ExportOptionService = signature.NewExportOptionsService(&a)
 	a.FileService = util.NewFileService()
+	a.OTAService = ota.NewOTAService(&a)
 	a.PathService = path.NewPathService(&a)
 	a.PlistBuddyService = util.NewPListBuddy(&a)
 	a.ProvisioningService = signature.NewProvisioningService(&a)
 	a.SignatureResolver = signature.NewResolver(&a)
 	a.SignatureService = signature.NewSignatureService(&a)
+	a.SigningAssetSource = signature.NewSigningAssetSource(&a)
+	a.SigningRepository = repository.NewSigningRepository(&a)
+	a.SizeAnalyzer = bundle.NewSizeAnalyzer(&a)
+	a.SymbolsService = archive.NewSymbolsService(&a)
+	a.Symbolicator = symbolicate.NewSymbolicator(&a)
 	a.XCodeProjectService = project.NewProjectService(&a)
 	a.XcodeListService = xcode.NewXCodeListService(&a)
 	a.XcodeSelectService = xcode.NewSelectService(&a)
+	a.XcodeVerifyService = xcode.NewVerifyService(&a)
 	return &a, nil
 }
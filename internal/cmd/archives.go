@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (m menu) archivesCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "archives",
+		Usage: "Inspect the archives",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "List the archives of the folder by version, the build folder of the project by default",
+				ArgsUsage: "[folder]",
+				Action:    m.archivesListCommand,
+			},
+			{
+				Name:      "show",
+				Usage:     "Print a JSON report of the archive, its products and debug symbols",
+				ArgsUsage: "<file.xcarchive>",
+				Action:    m.archivesShowCommand,
+			},
+		},
+	}
+}
+
+func (m menu) archivesListCommand(c *cli.Context) error {
+	ctx, cancel := m.context()
+	defer cancel()
+
+	root := c.Args().First()
+	if root == "" {
+		root = filepath.Dir(m.API.PathService.Archive())
+	}
+
+	if _, err := os.Stat(root); err != nil {
+		return err
+	}
+
+	archives, err := m.API.ArchiveService.List(ctx, root)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tBUILD\tBUNDLE ID\tSCHEME\tCREATED\tPATH")
+	for _, a := range archives {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n",
+			a.ApplicationProperties.ShortVersion,
+			a.ApplicationProperties.Version,
+			a.ApplicationProperties.BundleIdentifier,
+			a.SchemeName,
+			a.CreationDate.Local().Format("2006-01-02 15:04"),
+			a.Path)
+	}
+
+	return w.Flush()
+}
+
+func (m menu) archivesShowCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("the archive to show is required")
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	res, err := m.API.ArchiveService.Read(ctx, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(res)
+}
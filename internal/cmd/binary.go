@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (m menu) binaryInfoCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "binary-info",
+		Usage:     "Print a JSON report of the architectures, platforms and linked libraries of the binaries",
+		ArgsUsage: "<binary|file.ipa|file.app|file.framework|file.xcarchive>",
+		Action:    m.binaryInfoAction,
+	}
+}
+
+func (m menu) binaryInfoAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("the binary or bundle to analyze is required")
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	res, err := m.API.BinaryAnalyzer.Analyze(ctx, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(res)
+}
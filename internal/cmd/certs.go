@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (m menu) certsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "certs",
+		Usage: "Manage the shared signing assets repository",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "sync",
+				Usage:  "Fetch the repository and check that every asset can be decrypted",
+				Action: m.certsSyncCommand,
+			},
+			{
+				Name:      "import",
+				Usage:     "Encrypt p12 certificates and provisioning profiles into the repository",
+				ArgsUsage: "<file.p12|file.mobileprovision>...",
+				Action:    m.certsImportCommand,
+			},
+		},
+	}
+}
+
+func (m menu) certsSyncCommand(c *cli.Context) error {
+	ctx, cancel := m.context()
+	defer cancel()
+
+	entries, err := m.API.SigningRepository.Entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tTEAM\tTYPE\tNAME\tBUNDLE ID\tEXPIRATION\tSTATUS")
+
+	var failed int
+	for _, e := range entries {
+		status := "ok"
+		if _, err := m.API.SigningRepository.Open(ctx, e); err != nil {
+			status = err.Error()
+			failed++
+		}
+
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			e.Kind,
+			e.Team,
+			e.Type,
+			e.Name,
+			e.BundleIdentifier,
+			e.Expiration.Format("2006-01-02"),
+			status)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to decrypt %v signing asset(s)", failed)
+	}
+
+	return nil
+}
+
+func (m menu) certsImportCommand(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("at least one file to import is required")
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	entries, err := m.API.SigningRepository.Import(ctx, c.Args().Slice())
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		fmt.Printf("%v\t%v\n", e.File, e.Name)
+	}
+
+	return nil
+}
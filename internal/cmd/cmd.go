@@ -3,10 +3,16 @@ package cmd
 import (
 	"context"
 	"dothething/internal/api"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v2"
 )
 
@@ -30,19 +36,162 @@ func (m menu) Run() error {
 
 	app.Commands = []*cli.Command{
 		{Name: "build", Action: m.buildCommand},
-		{Name: "package", Action: m.packageCommand},
-		{Name: "archive", Action: m.archiveCommand},
+		{
+			Name:   "package",
+			Action: m.packageCommand,
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "methods",
+					Usage: "Distribution methods to export the archive to (app-store, ad-hoc, enterprise, development)",
+				},
+				&cli.PathFlag{
+					Name:        "sizeBaseline",
+					Usage:       "A previous size report to compare the packages to",
+					Destination: &m.API.Config.Size.Baseline,
+				},
+				&cli.Float64Flag{
+					Name:        "sizeThreshold",
+					Usage:       "The growth of the compressed size, in percent of the baseline, failing the package",
+					Destination: &m.API.Config.Size.Threshold,
+				},
+			},
+		},
+		{
+			Name:   "archive",
+			Action: m.archiveCommand,
+			Flags: append(m.symbolsFlags(), &cli.BoolFlag{
+				Name:        "skipSymbols",
+				Usage:       "Do not collect the dSYMs of the archive products",
+				Destination: &m.API.Config.Symbols.Skip,
+			}),
+		},
 		{Name: "test", Action: m.testCommand},
+		{
+			Name:   "symbols",
+			Usage:  "Collect the dSYMs of the archive products and zip them next to the package",
+			Action: m.symbolsCommand,
+			Flags:  m.symbolsFlags(),
+		},
+		m.archivesCommand(),
+		m.binaryInfoCommand(),
+		m.certsCommand(),
+		m.inspectCommand(),
+		m.keychainCommand(),
+		m.profilesCommand(),
+		m.resignCommand(),
+		m.symbolicateCommand(),
+		m.xcodeCommand(),
 	}
 
+	// The manifest is only kept when one of its URLs is provided
+	eo := &m.API.Config.ExportOptions.Overrides
+	eo.Manifest = &api.ExportManifest{}
+
 	app.Flags = []cli.Flag{
 		&cli.PathFlag{Name: "project", Destination: &m.API.Config.Path},
-		&cli.StringFlag{Name: "xcodeVersion", Destination: &m.API.Config.XCodeVersion, EnvVars: []string{"XCODE_VERSION"}},
+		&cli.StringFlag{
+			Name:        "xcodeVersion",
+			Usage:       "The Xcode version range, build number or channel, the .xcode-version file being read when empty",
+			Destination: &m.API.Config.XCodeVersion,
+			EnvVars:     []string{"XCODE_VERSION"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "xcodeSearchPaths",
+			Usage:   "Folders, applications or glob patterns searched for the Xcode installs",
+			EnvVars: []string{"DOTHETHING_XCODE_SEARCH_PATHS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "env",
+			Usage:   "A KEY=VALUE variable of the environment of the commands, over the inherited one",
+			EnvVars: []string{"DOTHETHING_ENV"},
+		},
+		&cli.StringSliceFlag{
+			Name:  "envAllow",
+			Usage: "A pattern of the inherited variables to keep, like AWS_*, all of them being kept when empty",
+		},
+		&cli.StringSliceFlag{
+			Name:  "envDeny",
+			Usage: "A pattern of the inherited variables to remove, like *_TOKEN",
+		},
 		&cli.StringFlag{Name: "buildScheme", Destination: &m.API.Config.Scheme},
 		&cli.StringFlag{Name: "buildConfiguration", Destination: &m.API.Config.Configuration},
 		&cli.StringFlag{Name: "target", Destination: &m.API.Config.Target},
 		&cli.StringFlag{Name: "signatureFilesPath", Destination: &m.API.Config.CodeSignOption.Path},
 		&cli.StringFlag{Name: "certificatePassword", Destination: &m.API.Config.CodeSignOption.CertificatePassword},
+		&cli.PathFlag{Name: "certificatePasswords", Destination: &m.API.Config.CodeSignOption.CertificatePasswords},
+		&cli.StringFlag{Name: "certificateFile", Destination: &m.API.Config.CodeSignOption.CertificateFile},
+		&cli.StringFlag{Name: "privateKeyFile", Destination: &m.API.Config.CodeSignOption.PrivateKeyFile},
+		&cli.PathFlag{
+			Name:        "wwdrCertificates",
+			Usage:       "A DER or PEM file of the Apple WWDR intermediate certificates to trust",
+			Destination: &m.API.Config.CodeSignOption.WWDRCertificates,
+		},
+		&cli.BoolFlag{
+			Name:        "isolateProfiles",
+			Usage:       "Install the provisioning profiles into a home folder of the run instead of the user one, the HOME of every xcodebuild command being moved to it, with links to the user caches, Xcode settings, .netrc and SSH keys",
+			Destination: &m.API.Config.CodeSignOption.IsolateProfiles,
+		},
+		&cli.StringFlag{
+			Name:        "keychainMode",
+			Usage:       "The keychain holding the identities: temporary, existing or login",
+			EnvVars:     []string{"DOTHETHING_KEYCHAIN_MODE"},
+			Value:       api.KeyChainTemporary,
+			Destination: &m.API.Config.CodeSignOption.KeyChain.Mode,
+		},
+		&cli.PathFlag{
+			Name:        "keychainPath",
+			Usage:       "The existing keychain, unlocked with the password of $DOTHETHING_KEYCHAIN_PASSWORD",
+			EnvVars:     []string{"DOTHETHING_KEYCHAIN_PATH"},
+			Destination: &m.API.Config.CodeSignOption.KeyChain.Path,
+		},
+		&cli.StringFlag{
+			Name:        "repository",
+			EnvVars:     []string{"DOTHETHING_REPOSITORY"},
+			Destination: &m.API.Config.CodeSignOption.Repository.URL,
+		},
+		&cli.StringFlag{Name: "repositoryBranch", Destination: &m.API.Config.CodeSignOption.Repository.Branch},
+		&cli.StringFlag{
+			Name:        "repositoryPassphrase",
+			EnvVars:     []string{"DOTHETHING_REPOSITORY_PASSPHRASE"},
+			Destination: &m.API.Config.CodeSignOption.Repository.Passphrase,
+		},
+		&cli.PathFlag{Name: "exportOptions", Destination: &m.API.Config.ExportOptions.Path},
+		&cli.StringFlag{Name: "exportDestination", Destination: &eo.Destination},
+		&cli.StringFlag{Name: "distributionBundleIdentifier", Destination: &eo.DistributionBundleIdentifier},
+		&cli.StringFlag{Name: "iCloudContainerEnvironment", Destination: &eo.ICloudContainerEnvironment},
+		&cli.StringFlag{Name: "thinning", Destination: &eo.Thinning},
+		&cli.StringFlag{Name: "manifestAppURL", Destination: &eo.Manifest.AppURL},
+		&cli.StringFlag{Name: "manifestAssetPackManifestURL", Destination: &eo.Manifest.AssetPackManifestURL},
+		&cli.StringFlag{Name: "manifestDisplayImageURL", Destination: &eo.Manifest.DisplayImageURL},
+		&cli.StringFlag{Name: "manifestFullSizeImageURL", Destination: &eo.Manifest.FullSizeImageURL},
+		&cli.GenericFlag{Name: "compileBitcode", Value: &optionalBool{&eo.CompileBitcode}},
+		&cli.GenericFlag{
+			Name:  "embedOnDemandResourcesAssetPacksInBundle",
+			Value: &optionalBool{&eo.EmbedOnDemandResourcesAssetPacksInBundle},
+		},
+		&cli.GenericFlag{Name: "manageAppVersionAndBuildNumber", Value: &optionalBool{&eo.ManageAppVersionAndBuildNumber}},
+		&cli.GenericFlag{Name: "stripSwiftSymbols", Value: &optionalBool{&eo.StripSwiftSymbols}},
+		&cli.GenericFlag{Name: "testFlightInternalTestingOnly", Value: &optionalBool{&eo.TestFlightInternalTestingOnly}},
+		&cli.GenericFlag{Name: "uploadBitcode", Value: &optionalBool{&eo.UploadBitcode}},
+		&cli.GenericFlag{Name: "uploadSymbols", Value: &optionalBool{&eo.UploadSymbols}},
+		&cli.DurationFlag{
+			Name:        "certificateExpiryWarning",
+			Value:       30 * 24 * time.Hour,
+			Destination: &m.API.Config.CodeSignOption.CertificateExpiryWarning,
+		},
+	}
+
+	app.Before = func(c *cli.Context) error {
+		m.API.Config.XCodeSearchPaths = c.StringSlice("xcodeSearchPaths")
+		m.API.Config.Env = api.EnvConfig{
+			Allow: c.StringSlice("envAllow"),
+			Deny:  c.StringSlice("envDeny"),
+			Vars:  c.StringSlice("env"),
+		}
+		if *eo.Manifest == (api.ExportManifest{}) {
+			eo.Manifest = nil
+		}
+		return nil
 	}
 
 	err := app.Run(os.Args)
@@ -54,6 +203,8 @@ func (m menu) Run() error {
 }
 
 func (m menu) archiveCommand(c *cli.Context) error {
+	m.API.Config.Symbols.SearchPaths = c.StringSlice("dsymSearchPaths")
+
 	return m.runAction(m.API.ActionArchive)
 }
 
@@ -62,16 +213,89 @@ func (m menu) buildCommand(c *cli.Context) error {
 }
 
 func (m menu) packageCommand(c *cli.Context) error {
+	methods, err := parseMethods(c.StringSlice("methods"))
+	if err != nil {
+		return err
+	}
+	m.API.Config.Methods = methods
+
 	return m.runAction(m.API.ActionPack)
 }
 
+// symbolsFlags the flags of the debug symbols collection
+func (m menu) symbolsFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "dsymSearchPaths",
+			Usage: "Folders to search for the dSYMs of the vendored frameworks",
+		},
+		&cli.BoolFlag{
+			Name:        "strict",
+			Usage:       "Fail when a product has no dSYM",
+			Destination: &m.API.Config.Symbols.Strict,
+		},
+	}
+}
+
+func (m menu) symbolsCommand(c *cli.Context) error {
+	m.API.Config.Symbols.SearchPaths = c.StringSlice("dsymSearchPaths")
+
+	return m.runAction(m.API.ActionSymbols)
+}
+
 func (m menu) testCommand(c *cli.Context) error {
 	return m.runAction(m.API.ActionRunTest)
 }
 
 func (m menu) runAction(action api.Action) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	ctx, cancel := m.context()
 	defer cancel() // The cancel should be deferred so resources are cleaned up
+	defer m.writeReport()
 
 	return action.Run(ctx)
 }
+
+// writeReport writes the run report, holding the redacted environment of the xcode commands,
+// next to the build products
+func (m menu) writeReport() {
+	r := m.API.Exec.Report()
+	if len(r.Environment) == 0 {
+		return
+	}
+
+	dst := m.API.PathService.RunReport()
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(dst), 0755); err == nil {
+			err = ioutil.WriteFile(dst, append(b, '\n'), 0644)
+		}
+	}
+
+	if err != nil {
+		log.Error().Err(err).Str("Path", dst).Msg("Failed to write the run report")
+		return
+	}
+
+	log.Info().Str("Path", dst).Msg("Run report written")
+}
+
+// context the context of a command, cancelled on SIGINT and SIGTERM for the deferred
+// cleanups, like the deletion of the keychain, to run before exiting
+func (m menu) context() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(sig)
+
+		select {
+		case s := <-sig:
+			log.Warn().Str("Signal", s.String()).Msg("Interrupted, cleaning up")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// methods the supported distribution methods
+var methods = map[string]bool{
+	"ad-hoc":      true,
+	"app-store":   true,
+	"development": true,
+	"enterprise":  true,
+}
+
+// optionalBool a boolean flag value keeping track of whether it has been provided, the
+// destination being left nil otherwise
+type optionalBool struct {
+	dst **bool
+}
+
+func (b *optionalBool) Set(v string) error {
+	res, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+
+	*b.dst = &res
+	return nil
+}
+
+func (b *optionalBool) String() string {
+	if b == nil || b.dst == nil || *b.dst == nil {
+		return ""
+	}
+
+	return strconv.FormatBool(**b.dst)
+}
+
+// IsBoolFlag allows the flag to be provided without value
+func (b *optionalBool) IsBoolFlag() bool {
+	return true
+}
+
+// parseMethods splits the comma separated distribution methods, rejecting the unknown ones
+func parseMethods(values []string) ([]string, error) {
+	var res []string
+	seen := map[string]bool{}
+	for _, v := range values {
+		for _, m := range strings.Split(v, ",") {
+			m = strings.TrimSpace(m)
+			if m == "" || seen[m] {
+				continue
+			}
+
+			if !methods[m] {
+				return nil, fmt.Errorf("unsupported distribution method %v", m)
+			}
+
+			seen[m] = true
+			res = append(res, m)
+		}
+	}
+
+	return res, nil
+}
+
+// parseProfileMap parses the comma separated bundleid=uuid pairs
+func parseProfileMap(values []string) (map[string]string, error) {
+	res := map[string]string{}
+	for _, v := range values {
+		for _, e := range strings.Split(v, ",") {
+			e = strings.TrimSpace(e)
+			if e == "" {
+				continue
+			}
+
+			kv := strings.SplitN(e, "=", 2)
+			if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+				return nil, fmt.Errorf("invalid profile mapping %v, expecting bundleid=uuid", e)
+			}
+
+			res[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+
+	return res, nil
+}
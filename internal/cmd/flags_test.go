@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMethods(t *testing.T) {
+	cases := []struct {
+		name   string
+		values []string
+		res    []string
+		err    string
+	}{
+		{name: "empty"},
+		{name: "repeated flags", values: []string{"ad-hoc", "app-store"}, res: []string{"ad-hoc", "app-store"}},
+		{name: "comma separated", values: []string{"ad-hoc, enterprise,,ad-hoc"}, res: []string{"ad-hoc", "enterprise"}},
+		{name: "unknown", values: []string{"ad-hoc,developer-id"}, err: "unsupported distribution method developer-id"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// when:
+			res, err := parseMethods(c.values)
+
+			// then:
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.res, res)
+		})
+	}
+}
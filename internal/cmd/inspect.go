@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (m menu) inspectCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "inspect",
+		Usage:     "Print a JSON report of the content of an IPA, an application or an archive",
+		ArgsUsage: "<file.ipa|file.app|file.xcarchive>",
+		Action:    m.inspectAction,
+	}
+}
+
+func (m menu) inspectAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("the IPA, application or archive to inspect is required")
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	res, err := m.API.BundleInspector.Inspect(ctx, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(res)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (m menu) keychainCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "keychain",
+		Usage: "Manage the temporary keychains of the builds",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "cleanup",
+				Usage:     "Delete the keychains left by interrupted runs, from the search list and the folders",
+				ArgsUsage: "[folder...]",
+				Action:    m.keychainCleanupCommand,
+			},
+		},
+	}
+}
+
+func (m menu) keychainCleanupCommand(c *cli.Context) error {
+	// Defaults to the temporary folder and to the build folder of the project
+	dirs := c.Args().Slice()
+	if len(dirs) == 0 {
+		dirs = []string{os.TempDir(), filepath.Dir(m.API.PathService.KeyChain())}
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	removed, err := m.API.KeyChain.Cleanup(ctx, dirs)
+	for _, r := range removed {
+		fmt.Println(r)
+	}
+
+	return err
+}
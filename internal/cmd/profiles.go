@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/signature"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (m menu) profilesCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "profiles",
+		Usage: "Inspect the provisioning profiles",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "List the provisioning profiles of the folder, the installed ones by default",
+				ArgsUsage: "[folder]",
+				Action:    m.profilesListCommand,
+			},
+			{
+				Name:      "show",
+				Usage:     "Print the details of a provisioning profile",
+				ArgsUsage: "<uuid|file.mobileprovision>",
+				Action:    m.profilesShowCommand,
+			},
+			{
+				Name:  "prune",
+				Usage: "Delete the expired installed provisioning profiles",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "dry-run", Usage: "Only print the profiles which would be deleted"},
+				},
+				Action: m.profilesPruneCommand,
+			},
+		},
+	}
+}
+
+func (m menu) profilesListCommand(c *cli.Context) error {
+	ctx, cancel := m.context()
+	defer cancel()
+
+	root := c.Args().First()
+	if root == "" {
+		dir, err := signature.InstalledProfilesDir()
+		if err != nil {
+			return err
+		}
+		root = dir
+	}
+
+	profiles, err := m.profilesInFolder(ctx, root)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "UUID\tNAME\tTEAM\tAPP ID\tDEVICES\tEXPIRATION\tMETHOD")
+	for _, pp := range profiles {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			pp.UUID,
+			pp.Name,
+			pp.Entitlements.TeamID,
+			pp.Entitlements.AppID,
+			devicesCount(pp),
+			expiration(pp),
+			signature.ProvisioningMethod(pp))
+	}
+
+	return w.Flush()
+}
+
+func (m menu) profilesShowCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("a provisioning profile UUID or file is required")
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	pp, err := m.findProfile(ctx, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "UUID:\t%v\n", pp.UUID)
+	fmt.Fprintf(w, "Name:\t%v\n", pp.Name)
+	fmt.Fprintf(w, "File:\t%v\n", pp.FilePath)
+	fmt.Fprintf(w, "Team:\t%v (%v)\n", pp.TeamName, pp.Entitlements.TeamID)
+	fmt.Fprintf(w, "App ID:\t%v\n", pp.Entitlements.AppID)
+	fmt.Fprintf(w, "Platforms:\t%v\n", strings.Join(pp.Platform, ", "))
+	fmt.Fprintf(w, "Method:\t%v\n", signature.ProvisioningMethod(pp))
+	fmt.Fprintf(w, "Expiration:\t%v\n", expiration(pp))
+	fmt.Fprintf(w, "Devices:\t%v\n", devicesCount(pp))
+	if pp.ProvisionedDevices != nil {
+		for _, d := range *pp.ProvisionedDevices {
+			fmt.Fprintf(w, "\t%v\n", d)
+		}
+	}
+
+	fmt.Fprintf(w, "Certificates:\t%v\n", len(pp.Certificates))
+	for _, cert := range pp.Certificates {
+		fmt.Fprintf(w, "\t%v (expires %v)\n", cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"))
+	}
+
+	return w.Flush()
+}
+
+func (m menu) profilesPruneCommand(c *cli.Context) error {
+	ctx, cancel := m.context()
+	defer cancel()
+
+	dir, err := signature.InstalledProfilesDir()
+	if err != nil {
+		return err
+	}
+
+	profiles, err := m.profilesInFolder(ctx, dir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, pp := range profiles {
+		if now.Before(pp.ExpirationDate) {
+			continue
+		}
+
+		fmt.Printf("%v\t%v\t%v\n", pp.UUID, pp.Name, expiration(pp))
+		if c.Bool("dry-run") {
+			continue
+		}
+
+		if err := os.Remove(pp.FilePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// profilesInFolder resolves the provisioning profiles of the folder, sorted by name
+func (m menu) profilesInFolder(ctx context.Context, root string) ([]*api.ProvisioningProfile, error) {
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+
+	profiles := m.API.ProvisioningService.ResolveProvisioningFilesInFolder(ctx, root)
+	sort.Slice(profiles, func(i, j int) bool {
+		if profiles[i].Name == profiles[j].Name {
+			return profiles[i].ExpirationDate.Before(profiles[j].ExpirationDate)
+		}
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	return profiles, nil
+}
+
+// findProfile decodes the provisioning profile file, or looks up the UUID in the installed
+// profiles and the signature files folder
+func (m menu) findProfile(ctx context.Context, arg string) (*api.ProvisioningProfile, error) {
+	if _, err := os.Stat(arg); err == nil {
+		b, err := ioutil.ReadFile(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		pp, err := m.API.ProvisioningService.Decode(ctx, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		pp.FilePath = arg
+
+		return &pp, nil
+	}
+
+	var roots []string
+	if dir, err := signature.InstalledProfilesDir(); err == nil {
+		roots = append(roots, dir)
+	}
+	if p := m.API.Config.CodeSignOption.Path; p != "" {
+		roots = append(roots, p)
+	}
+
+	for _, root := range roots {
+		profiles, err := m.profilesInFolder(ctx, root)
+		if err != nil {
+			continue
+		}
+
+		for _, pp := range profiles {
+			if strings.EqualFold(pp.UUID, arg) {
+				return pp, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("provisioning profile %v not found", arg)
+}
+
+// devicesCount formats the number of devices the profile can be installed on
+func devicesCount(pp *api.ProvisioningProfile) string {
+	if pp.ProvisionsAllDevices != nil && *pp.ProvisionsAllDevices {
+		return "all"
+	}
+
+	if pp.ProvisionedDevices == nil {
+		return "0"
+	}
+
+	return fmt.Sprint(len(*pp.ProvisionedDevices))
+}
+
+// expiration formats the expiration date of the profile
+func expiration(pp *api.ProvisioningProfile) string {
+	res := pp.ExpirationDate.Format("2006-01-02")
+	if time.Now().After(pp.ExpirationDate) {
+		res += " (expired)"
+	}
+
+	return res
+}
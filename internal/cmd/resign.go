@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (m menu) resignCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "resign",
+		Usage: "Re-sign an IPA with other provisioning profiles, for example to distribute it ad-hoc",
+		Flags: []cli.Flag{
+			&cli.PathFlag{Name: "ipa", Usage: "The IPA to re-sign", Required: true},
+			&cli.StringSliceFlag{
+				Name:     "profile-map",
+				Usage:    "The provisioning profile UUID of each bundle, as bundleid=uuid",
+				Required: true,
+			},
+			&cli.PathFlag{Name: "output", Usage: "The re-signed IPA, next to the original one by default"},
+		},
+		Action: m.resignAction,
+	}
+}
+
+func (m menu) resignAction(c *cli.Context) error {
+	profiles, err := parseProfileMap(c.StringSlice("profile-map"))
+	if err != nil {
+		return err
+	}
+
+	ipa := c.Path("ipa")
+	output := c.Path("output")
+	if output == "" {
+		output = strings.TrimSuffix(ipa, filepath.Ext(ipa)) + "-resigned.ipa"
+	}
+
+	if filepath.Clean(output) == filepath.Clean(ipa) {
+		return errors.New("the output should not overwrite the IPA to re-sign")
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	return m.API.BundleResigner.Resign(ctx, ipa, output, profiles)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (m menu) symbolicateCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "symbolicate",
+		Usage:     "Symbolicate a legacy text or JSON .ips crash report with the dSYMs, without Xcode",
+		ArgsUsage: "<crash.ips|crash.crash>",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:     "dsyms",
+				Usage:    "Folders to search for the dSYMs of the report images",
+				Required: true,
+			},
+			&cli.PathFlag{Name: "output", Usage: "The symbolicated report, printed by default"},
+		},
+		Action: m.symbolicateAction,
+	}
+}
+
+func (m menu) symbolicateAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("the crash report to symbolicate is required")
+	}
+
+	r, err := os.Open(c.Args().First())
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w := os.Stdout
+	if p := c.Path("output"); p != "" {
+		if w, err = os.Create(p); err != nil {
+			return err
+		}
+		defer w.Close()
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	return m.API.Symbolicator.Symbolicate(ctx, r, c.StringSlice("dsyms"), w)
+}
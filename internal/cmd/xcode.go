@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"dothething/internal/api"
+	"dothething/internal/xcode"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+)
+
+// xcodeListEntry an install of the list, marked when it is the one selected
+type xcodeListEntry struct {
+	*api.Install
+	Selected bool
+}
+
+func (m menu) xcodeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "xcode",
+		Usage: "Inspect the Xcode installs",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "list",
+				Usage:  "List the Xcode installs, the one selected by the requirement being marked",
+				Flags:  []cli.Flag{&cli.BoolFlag{Name: "json", Usage: "Print a JSON report"}},
+				Action: m.xcodeListCommand,
+			},
+			{
+				Name:      "which",
+				Usage:     "Print the Xcode install selected by the requirement",
+				ArgsUsage: "[requirement]",
+				Action:    m.xcodeWhichCommand,
+			},
+			{
+				Name:      "verify",
+				Usage:     "Check that the selected Xcode install has the SDKs and simulator runtimes the project needs",
+				ArgsUsage: "[requirement]",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "sdk", Usage: "An additional SDK, like \"iphoneos 17.0\""},
+					&cli.StringSliceFlag{Name: "runtime", Usage: "An additional simulator runtime, like \"iOS 17.0\""},
+				},
+				Action: m.xcodeVerifyCommand,
+			},
+		},
+	}
+}
+
+func (m menu) xcodeListCommand(c *cli.Context) error {
+	ctx, cancel := m.context()
+	defer cancel()
+
+	installs, err := m.API.XcodeListService.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	// No install being marked when none matches the requirement
+	selected, _ := m.API.XcodeSelectService.Find(ctx)
+
+	var res []xcodeListEntry
+	for _, i := range installs {
+		res = append(res, xcodeListEntry{Install: i, Selected: selected != nil && selected.Path == i.Path})
+	}
+
+	if c.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(res)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tVERSION\tBUILD\tCHANNEL\tPATH")
+	for _, e := range res {
+		marker := ""
+		if e.Selected {
+			marker = "*"
+		}
+
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", marker, e.Version, e.Build, channel(e.Install), e.Path)
+	}
+
+	return w.Flush()
+}
+
+func (m menu) xcodeWhichCommand(c *cli.Context) error {
+	if c.NArg() > 0 {
+		m.API.Config.XCodeVersion = c.Args().First()
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	i, err := m.API.XcodeSelectService.Find(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%v (%v, %v)\n", i.Path, i.Version, i.Build)
+
+	return nil
+}
+
+func (m menu) xcodeVerifyCommand(c *cli.Context) error {
+	if c.NArg() > 0 {
+		m.API.Config.XCodeVersion = c.Args().First()
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	i, err := m.API.XcodeSelectService.Find(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := m.API.XcodeVerifyService.Requirements(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range c.StringSlice("sdk") {
+		req.SDKs = append(req.SDKs, xcode.ParsePlatformRequirement(s))
+	}
+	for _, r := range c.StringSlice("runtime") {
+		req.Runtimes = append(req.Runtimes, xcode.ParsePlatformRequirement(r))
+	}
+
+	res, err := m.API.XcodeVerifyService.Verify(ctx, i, req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Xcode %v (%v) %v\n", i.Version, i.Build, i.Path)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tREQUIREMENT\tFOUND\tSTATUS")
+	for _, check := range res.Checks {
+		status := "ok"
+		if !check.OK {
+			status = "missing"
+		}
+
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", check.Kind, check.Requirement, check.Found, status)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !res.OK() {
+		return errors.New("the Xcode install misses some of the SDKs or simulator runtimes")
+	}
+
+	return nil
+}
+
+// channel the release channel of the install
+func channel(i *api.Install) string {
+	if i.Beta {
+		return xcode.ChannelBeta
+	}
+
+	return xcode.ChannelRelease
+}
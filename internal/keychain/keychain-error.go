@@ -9,8 +9,12 @@ const (
 	createError    = "Failed to create keychain"
 	deleteError    = "Failed to delete keychain"
 	fileError      = "Keychain file error"
+	identityError  = "Failed to list the signing identities"
 	importError    = "Failed to import certificate int keychain"
+	lockError      = "Failed to lock the keychain search list"
 	partitionError = "Failed to set partition list"
+	restoreError   = "Failed to restore the keychain search list"
+	unlockError    = "Failed to unlock keychain"
 )
 
 type KeyChainError struct {
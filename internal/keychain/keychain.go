@@ -3,7 +3,10 @@ package keychain
 import (
 	"context"
 	"dothething/internal/api"
+	"os"
 	"regexp"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -17,6 +20,7 @@ const (
 	ActionSettings         = "set-keychain-settings"
 	ActionSetPartitionList = "set-key-partition-list"
 	ActionListKeyChains    = "list-keychains"
+	ActionDefaultKeychain  = "default-keychain"
 	FlagAppPath            = "-T"
 	FlagKeychain           = "-k"
 	FlagPartitionList      = "-S"
@@ -27,16 +31,54 @@ const (
 
 var searchListRegexp = regexp.MustCompile(`^(?:\t|(?:\s)+)?"(.*)"`)
 
+// cleanupTimeout bounds the deletion of the keychain, which must succeed even when the
+// context of the action has been cancelled
+const cleanupTimeout = 30 * time.Second
+
 type keychain struct {
 	*api.API
+	session *session
+}
+
+// session the user keychains settings captured before the first change, to be restored,
+// and the secrets of the keychain of the run
+type session struct {
+	mu              sync.Mutex
+	captured        bool
+	defaultKeychain string
+	lock            *os.File
+	password        string
+	searchList      []string
 }
 
 func NewKeyChain(api *api.API) (api.KeyChain, error) {
-	return keychain{API: api}, nil
+	return keychain{API: api, session: &session{}}, nil
 }
 
-// Delete will delete the keychain and remove them from the search list
+// Delete will delete the temporary keychain, then restore the search list and the default
+// keychain captured before its creation, the existing keychain being kept
 func (k keychain) Delete(ctx context.Context) error {
+	// Detaching from the action, the deletion being run once it has been cancelled as well
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	var err error
+	if k.mode() == api.KeyChainTemporary {
+		err = k.deleteKeychain(ctx)
+	}
+
+	rerr := withSearchListLock(func() error {
+		return k.restore(ctx)
+	})
+	if rerr != nil && err == nil {
+		err = rerr
+	}
+	k.unlock()
+
+	return err
+}
+
+func (k keychain) deleteKeychain(ctx context.Context) error {
 	b, err := k.securityCmd(
 		ctx,
 		ActionDeleteKeychain,
@@ -54,17 +96,25 @@ func (k keychain) Delete(ctx context.Context) error {
 	return err
 }
 
-// ImportCertificate Import one item into a keychain
+// ImportCertificate Import one item into a keychain, the login keychain being left untouched
 func (k keychain) ImportCertificate(ctx context.Context, filePath, password, commonName string) error {
+	if k.mode() == api.KeyChainLogin {
+		log.Warn().
+			Str("FilePath", filePath).
+			Msg("Skipping the import of the certificate, the login keychain is left untouched")
+		return nil
+	}
+
 	log.Info().
 		Str("FilePath", filePath).
 		Msg("Importing Certificate")
+	k.API.Exec.Redact(password)
 	b, err := k.securityCmd(
 		ctx,
 		ActionImport,
 		[]string{
 			filePath,
-			FlagKeychain, k.API.PathService.KeyChain(), // Specify keychain into which item(s) will be imported.
+			FlagKeychain, k.GetPath(), // Specify keychain into which item(s) will be imported.
 			FlagPassphase, password, // Specify the unwrapping passphrase immediately.
 			FlagAppPath, "/usr/bin/codesign", // Specify an application which may access the imported key;
 			FlagNonExtractable,
@@ -77,7 +127,7 @@ func (k keychain) ImportCertificate(ctx context.Context, filePath, password, com
 		return CertificateImportError(err)
 	}
 
-	return k.setPartitionList(ctx, "dothething")
+	return k.setPartitionList(ctx, k.password())
 }
 
 // setPartitionList :  Sets the "partition list" for a key. The "partition list" is an extra
@@ -92,7 +142,7 @@ func (k keychain) setPartitionList(ctx context.Context, password string) error {
 			"-s",           // Match keys that can sign
 			"-k", password, // Password for keychain
 			"-t", "private", // We are looking for a private key
-			k.API.PathService.KeyChain(),
+			k.GetPath(),
 		},
 	).Output()
 
@@ -0,0 +1,114 @@
+package keychain
+
+import (
+	"context"
+	"dothething/internal/path"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Cleanup deletes the keychains left by the earlier runs, the ones of the search list and
+// the ones found into the folders or their direct sub folders, and returns their paths. The
+// keychains of the running processes are kept
+func (k keychain) Cleanup(ctx context.Context, dirs []string) ([]string, error) {
+	var res []string
+	err := withSearchListLock(func() error {
+		var err error
+		res, err = k.cleanup(ctx, dirs)
+		return err
+	})
+
+	return res, err
+}
+
+func (k keychain) cleanup(ctx context.Context, dirs []string) ([]string, error) {
+	list, err := k.getSearchList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []string
+	seen := map[string]bool{}
+	add := func(p string) {
+		if !seen[p] && isKeychainOfRun(p) && !inUse(p) {
+			seen[p] = true
+			orphans = append(orphans, p)
+		}
+	}
+
+	for _, l := range list {
+		add(l)
+	}
+
+	// macOS adding the -db suffix to the keychain files
+	for _, d := range dirs {
+		for _, pattern := range []string{
+			path.KeyChainPattern,
+			path.KeyChainPattern + "-db",
+			filepath.Join("*", path.KeyChainPattern),
+			filepath.Join("*", path.KeyChainPattern+"-db"),
+		} {
+			matches, err := filepath.Glob(filepath.Join(d, pattern))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, m := range matches {
+				add(m)
+			}
+		}
+	}
+
+	var res []string
+	for _, o := range orphans {
+		if err := k.deleteOrphan(ctx, o); err != nil {
+			log.Warn().
+				Str("Path", o).
+				AnErr("Reason", err).
+				Msg("Failed to delete the keychain")
+			continue
+		}
+
+		res = append(res, o)
+	}
+
+	// The deleted keychains are removed from the search list, even when their file is gone
+	if cleaned := without(list, orphans...); len(cleaned) != len(list) {
+		if err := k.setSearchList(ctx, cleaned); err != nil {
+			return res, err
+		}
+
+		if def, err := k.getDefaultKeychain(ctx); err == nil && seen[def] && len(cleaned) > 0 {
+			if err := k.setDefaultKeychain(ctx, cleaned[0]); err != nil {
+				return res, err
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// deleteOrphan deletes the keychain, the file being removed when the security tool fails
+// to, like for the corrupted keychains
+func (k keychain) deleteOrphan(ctx context.Context, p string) error {
+	defer os.Remove(runLock(p))
+
+	if _, err := k.securityCmd(ctx, ActionDeleteKeychain, []string{p}).Output(); err == nil {
+		return nil
+	}
+
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// isKeychainOfRun is true for the keychains created by the runs
+func isKeychainOfRun(p string) bool {
+	ok, _ := filepath.Match(path.KeyChainPattern, strings.TrimSuffix(filepath.Base(p), "-db"))
+	return ok
+}
@@ -2,12 +2,58 @@ package keychain
 
 import (
 	"context"
+	"crypto/rand"
+	"dothething/internal/api"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
 )
 
-// Create will create a new temporary keychhain and add it to the search list
-func (k keychain) Create(ctx context.Context, password string) error {
+// Create will create a new temporary keychhain and add it to the search list, the random
+// password being only kept in memory. The existing keychain is unlocked instead, the login
+// keychain being left untouched
+func (k keychain) Create(ctx context.Context) error {
+	switch m := k.mode(); m {
+	case api.KeyChainTemporary:
+		if err := k.createTemporary(ctx); err != nil {
+			return err
+		}
+
+	case api.KeyChainExisting:
+		if err := k.unlockExisting(ctx); err != nil {
+			return err
+		}
+
+	case api.KeyChainLogin:
+		log.Info().Msg("Using the identities of the login keychain")
+		return nil
+
+	default:
+		return UnknownModeError(m)
+	}
+
+	// The identities are only valid once their issuer is trusted
+	if p := k.API.Config.CodeSignOption.WWDRCertificates; p != "" {
+		return k.ImportIntermediates(ctx, p)
+	}
+
+	return nil
+}
+
+func (k keychain) createTemporary(ctx context.Context) error {
+	if err := k.capture(ctx); err != nil {
+		return fmt.Errorf("failed to read the keychain settings (Error: %v", err)
+	}
+
+	password, err := k.lock()
+	if err != nil {
+		return fmt.Errorf("failed to lock keychain (Error: %v", err)
+	}
+
 	if err := k.createKeychain(ctx, password); err != nil {
 		return fmt.Errorf("failed to create keychain (Error: %v", err)
 	}
@@ -16,7 +62,9 @@ func (k keychain) Create(ctx context.Context, password string) error {
 		return fmt.Errorf("failed to configure keychain (Error: %v", err)
 	}
 
-	err := k.addKeyChainToSearchList(ctx)
+	err = withSearchListLock(func() error {
+		return k.addKeyChainToSearchList(ctx)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to add the keychain to the search list (Error: %v", err)
 	}
@@ -24,6 +72,58 @@ func (k keychain) Create(ctx context.Context, password string) error {
 	return nil
 }
 
+// lock takes the lock of the keychain of the run, for the cleanup not to delete it, and
+// generates its password
+func (k keychain) lock() (string, error) {
+	k.session.mu.Lock()
+	defer k.session.mu.Unlock()
+
+	if k.session.lock == nil {
+		path := runLock(k.API.PathService.KeyChain())
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", err
+		}
+
+		f, err := lockFile(path, false)
+		if err != nil {
+			return "", err
+		}
+		k.session.lock = f
+	}
+
+	if k.session.password == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		k.session.password = base64.RawURLEncoding.EncodeToString(b)
+		k.API.Exec.Redact(k.session.password)
+	}
+
+	return k.session.password, nil
+}
+
+// unlock releases the lock of the keychain of the run and forgets its password
+func (k keychain) unlock() {
+	k.session.mu.Lock()
+	defer k.session.mu.Unlock()
+
+	if k.session.lock != nil {
+		unlockFile(k.session.lock)
+		os.Remove(k.session.lock.Name())
+		k.session.lock = nil
+	}
+	k.session.password = ""
+}
+
+// password the password of the keychain of the run
+func (k keychain) password() string {
+	k.session.mu.Lock()
+	defer k.session.mu.Unlock()
+
+	return k.session.password
+}
+
 // createKeychain Create keychain with provided password
 func (k keychain) createKeychain(ctx context.Context, password string) error {
 	if len(password) == 0 {
@@ -0,0 +1,149 @@
+package keychain
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/x509"
+	"dothething/internal/api"
+	"dothething/internal/util"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ActionAddCertificates = "add-certificates"
+	ActionFindIdentity    = "find-identity"
+	FlagPolicy            = "-p"
+	PolicyCodeSigning     = "codesigning"
+)
+
+// ErrNoCertificate the intermediates file holds no certificate
+var ErrNoCertificate = errors.New("No certificate found")
+
+// identityRegexp matches the identities listed by find-identity, like
+// 1) 6B1A7C64B5E6B7F1A0AF3F0D6AB4F0C07D1D53C9 "Apple Distribution: Dummy Ltd (12345ABCDE)",
+// the invalid ones being followed by the reason, like (CSSMERR_TP_NOT_TRUSTED)
+var identityRegexp = regexp.MustCompile(`^\s*\d+\)\s+([0-9A-Fa-f]{40})\s+"(.*)"(?:\s+\((.+)\))?\s*$`)
+
+// Identities lists the code signing identities of the keychain, or of the search list for the
+// login keychain, the invalid ones with the reason they cannot sign
+func (k keychain) Identities(ctx context.Context) ([]api.SigningIdentity, error) {
+	b, err := k.securityCmd(
+		ctx,
+		ActionFindIdentity,
+		append([]string{FlagPolicy, PolicyCodeSigning}, k.pathArgs()...),
+	).Output()
+
+	if err != nil {
+		return nil, KeyChainError{msg: identityError, err: err}
+	}
+
+	return parseIdentities(b), nil
+}
+
+// parseIdentities parses the identities of the output of find-identity, the valid identities
+// being listed again after the matching ones
+func parseIdentities(data []byte) []api.SigningIdentity {
+	var res []api.SigningIdentity
+	found := map[string]bool{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		m := identityRegexp.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+
+		sha1 := strings.ToUpper(m[1])
+		if found[sha1] {
+			continue
+		}
+		found[sha1] = true
+
+		res = append(res, api.SigningIdentity{
+			Name:   m[2],
+			Reason: m[3],
+			SHA1:   sha1,
+		})
+	}
+
+	return res
+}
+
+// ImportIntermediates adds the certificates of the DER or PEM file to the keychain, each
+// certificate being written to its own DER file for the security tool to read them all
+func (k keychain) ImportIntermediates(ctx context.Context, path string) error {
+	log.Info().Str("FilePath", path).Msg("Importing intermediate certificates")
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return CertificateImportError(err)
+	}
+
+	certs, err := parseCertificates(b)
+	if err != nil {
+		return CertificateImportError(err)
+	}
+
+	args := []string{FlagKeychain, k.GetPath()}
+	for _, c := range certs {
+		f, err := util.TempFilePath("intermediate", ".cer")
+		if err != nil {
+			return CertificateImportError(err)
+		}
+		defer os.Remove(f)
+
+		if err := ioutil.WriteFile(f, c.Raw, 0600); err != nil {
+			return CertificateImportError(err)
+		}
+		args = append(args, f)
+	}
+
+	if b, err := k.securityCmd(ctx, ActionAddCertificates, args).CombinedOutput(); err != nil {
+		// Adding a certificate already trusted by the system is reported as a failure
+		if !bytes.Contains(b, []byte("already exists")) {
+			return CertificateImportError(err)
+		}
+	}
+
+	return nil
+}
+
+// parseCertificates decodes the certificates of the PEM blocks, or the DER certificate
+func parseCertificates(b []byte) ([]*x509.Certificate, error) {
+	var res []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+
+	if res != nil {
+		return res, nil
+	}
+
+	if c, err := x509.ParseCertificate(b); err == nil {
+		return []*x509.Certificate{c}, nil
+	}
+
+	return nil, ErrNoCertificate
+}
@@ -0,0 +1,179 @@
+package keychain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"dothething/internal/api"
+	"dothething/internal/utiltest"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIdentities(t *testing.T) {
+	// setup:
+	output := `Policy: Code Signing
+  Matching identities
+  1) 6B1A7C64B5E6B7F1A0AF3F0D6AB4F0C07D1D53C9 "Apple Distribution: Dummy Ltd (12345ABCDE)"
+  2) 0d2b0e6f6c1b4b1e25b6f0a9f1e8f54c3a7d0b11 "iPhone Developer: John Doe (ABCDE12345)" (CSSMERR_TP_NOT_TRUSTED)
+     2 identities found
+
+  Valid identities only
+  1) 6B1A7C64B5E6B7F1A0AF3F0D6AB4F0C07D1D53C9 "Apple Distribution: Dummy Ltd (12345ABCDE)"
+     1 valid identities found`
+
+	// when:
+	res := parseIdentities([]byte(output))
+
+	// then: each identity once, the invalid one with its reason
+	assert.Equal(t, []api.SigningIdentity{
+		{Name: "Apple Distribution: Dummy Ltd (12345ABCDE)", SHA1: "6B1A7C64B5E6B7F1A0AF3F0D6AB4F0C07D1D53C9"},
+		{
+			Name:   "iPhone Developer: John Doe (ABCDE12345)",
+			Reason: "CSSMERR_TP_NOT_TRUSTED",
+			SHA1:   "0D2B0E6F6C1B4B1E25B6F0A9F1E8F54C3A7D0B11",
+		},
+	}, res)
+	assert.True(t, res[0].Valid())
+	assert.False(t, res[1].Valid())
+
+	// when:
+	res = parseIdentities([]byte("     0 valid identities found"))
+
+	// then:
+	assert.Empty(t, res)
+}
+
+func newIntermediate(t *testing.T, name string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tpl := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tpl, &tpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return der
+}
+
+func (s *keychainSuite) TestImportIntermediates() {
+	// setup: a PEM bundle of two certificates
+	var bundle []byte
+	for _, n := range []string{"Apple Worldwide Developer Relations Certification Authority", "Developer ID Certification Authority"} {
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: newIntermediate(s.T(), n)})...)
+	}
+	path := filepath.Join(s.dir, "wwdr.pem")
+	s.Require().NoError(ioutil.WriteFile(path, bundle, 0644))
+
+	kc := s.API.PathService.KeyChain()
+	var files []string
+	cmd := new(utiltest.MockExecutorCmd)
+	cmd.On("CombinedOutput").Return("", nil)
+	s.exec.On("CommandContext", mock.Anything, SecurityUtil, mock.MatchedBy(func(args []string) bool {
+		if len(args) != 5 || args[0] != ActionAddCertificates || args[1] != FlagKeychain || args[2] != kc {
+			return false
+		}
+
+		// The files are removed once imported
+		for _, f := range args[3:] {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				return false
+			}
+			if _, err := x509.ParseCertificate(b); err != nil {
+				return false
+			}
+			files = append(files, f)
+		}
+		return true
+	})).Return(cmd)
+
+	// when:
+	err := s.subject.ImportIntermediates(context.Background(), path)
+
+	// then:
+	s.NoError(err)
+	s.Len(files, 2)
+	for _, f := range files {
+		_, err := os.Stat(f)
+		s.True(os.IsNotExist(err))
+	}
+}
+
+func (s *keychainSuite) TestImportIntermediatesDER() {
+	// setup:
+	path := filepath.Join(s.dir, "AppleWWDRCAG3.cer")
+	s.Require().NoError(ioutil.WriteFile(path, newIntermediate(s.T(), "Apple WWDR G3"), 0644))
+
+	cmd := new(utiltest.MockExecutorCmd)
+	cmd.On("CombinedOutput").Return("The specified item already exists in the keychain.", assert.AnError)
+	s.exec.On("CommandContext", mock.Anything, SecurityUtil, mock.MatchedBy(func(args []string) bool {
+		return len(args) == 4 && args[0] == ActionAddCertificates
+	})).Return(cmd)
+
+	// when:
+	err := s.subject.ImportIntermediates(context.Background(), path)
+
+	// then: the certificates already trusted are skipped
+	s.NoError(err)
+}
+
+func (s *keychainSuite) TestImportIntermediatesInvalid() {
+	// setup:
+	path := filepath.Join(s.dir, "wwdr.pem")
+	s.Require().NoError(ioutil.WriteFile(path, []byte("not a certificate"), 0644))
+
+	// when:
+	err := s.subject.ImportIntermediates(context.Background(), path)
+
+	// then:
+	s.Equal(CertificateImportError(ErrNoCertificate), err)
+}
+
+func (s *keychainSuite) TestIdentityCertificates() {
+	// setup: the login keychain, an identity and an intermediate certificate
+	s.API.Config.CodeSignOption.KeyChain = api.KeyChainConfig{Mode: api.KeyChainLogin}
+	identity := newIntermediate(s.T(), "Apple Distribution: Dummy Ltd (12345ABCDE)")
+	sum := sha1.Sum(identity)
+	fingerprint := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	var certs []byte
+	for _, der := range [][]byte{newIntermediate(s.T(), "Apple WWDR"), identity} {
+		certs = append(certs, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionFindIdentity, FlagPolicy, PolicyCodeSigning},
+		`  1) `+fingerprint+` "Apple Distribution: Dummy Ltd (12345ABCDE)"
+     1 valid identities found`, nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionFindCertificate, FlagAll, FlagPEM}, string(certs), nil)
+
+	// when:
+	res, err := s.subject.IdentityCertificates(context.Background())
+
+	// then: the search list is used, only the certificates of the identities being returned
+	s.NoError(err)
+	s.Require().Len(res, 1)
+	s.Equal(identity, res[0].Raw)
+}
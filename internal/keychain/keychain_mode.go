@@ -0,0 +1,181 @@
+package keychain
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
+	"dothething/internal/api"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// EnvKeyChainPassword password of the existing keychain
+	EnvKeyChainPassword = "DOTHETHING_KEYCHAIN_PASSWORD"
+
+	ActionFindCertificate = "find-certificate"
+	ActionUnlockKeychain  = "unlock-keychain"
+	FlagAll               = "-a"
+	FlagPEM               = "-p"
+)
+
+var (
+	// ErrMissingKeyChainPath the existing mode requires the path of the keychain
+	ErrMissingKeyChainPath = errors.New("The path of the existing keychain is required")
+
+	// ErrMissingKeyChainPassword the existing keychain cannot be unlocked without a password
+	ErrMissingKeyChainPassword = fmt.Errorf("The password of the existing keychain is required, provided by $%v", EnvKeyChainPassword)
+)
+
+// UnknownModeError the keychain mode is not supported
+func UnknownModeError(mode string) error {
+	return fmt.Errorf(
+		"Unknown keychain mode %v, expecting %v, %v or %v",
+		mode,
+		api.KeyChainTemporary,
+		api.KeyChainExisting,
+		api.KeyChainLogin,
+	)
+}
+
+// mode the configured keychain mode, the temporary keychain being the default
+func (k keychain) mode() string {
+	if m := k.API.Config.CodeSignOption.KeyChain.Mode; m != "" {
+		return m
+	}
+
+	return api.KeyChainTemporary
+}
+
+// GetPath returns the path of the keychain of the mode, empty for the login keychain
+func (k keychain) GetPath() string {
+	switch k.mode() {
+	case api.KeyChainExisting:
+		p := k.API.Config.CodeSignOption.KeyChain.Path
+		if res, err := filepath.Abs(p); err == nil {
+			return res
+		}
+
+		return p
+
+	case api.KeyChainLogin:
+		return ""
+	}
+
+	return k.API.PathService.KeyChain()
+}
+
+// unlockExisting unlocks the existing keychain and adds it to the search list for the build
+// to find its identities, its settings being left untouched
+func (k keychain) unlockExisting(ctx context.Context) error {
+	kc := k.GetPath()
+	if k.API.Config.CodeSignOption.KeyChain.Path == "" {
+		return ErrMissingKeyChainPath
+	}
+
+	password := os.Getenv(EnvKeyChainPassword)
+	if password == "" {
+		return ErrMissingKeyChainPassword
+	}
+
+	if err := k.capture(ctx); err != nil {
+		return fmt.Errorf("failed to read the keychain settings (Error: %v", err)
+	}
+
+	k.API.Exec.Redact(password)
+	if _, err := k.securityCmd(ctx, ActionUnlockKeychain, []string{FlagPassword, password, kc}).Output(); err != nil {
+		return KeyChainError{msg: unlockError, err: err}
+	}
+
+	k.session.mu.Lock()
+	k.session.password = password
+	k.session.mu.Unlock()
+
+	err := withSearchListLock(func() error {
+		return k.addKeyChainToSearchList(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add the keychain to the search list (Error: %v", err)
+	}
+
+	return nil
+}
+
+// IdentityCertificates returns the certificates of the valid code signing identities of the
+// keychain, matched by their SHA-1 fingerprint
+func (k keychain) IdentityCertificates(ctx context.Context) ([]*x509.Certificate, error) {
+	identities, err := k.Identities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := k.securityCmd(ctx, ActionFindCertificate, append([]string{FlagAll, FlagPEM}, k.pathArgs()...)).Output()
+	if err != nil {
+		return nil, KeyChainError{msg: identityError, err: err}
+	}
+
+	certs := parsePEMCertificates(b)
+
+	var res []*x509.Certificate
+	for _, i := range identities {
+		if !i.Valid() {
+			continue
+		}
+
+		if c, ok := certs[i.SHA1]; ok {
+			res = append(res, c)
+		}
+	}
+
+	return res, nil
+}
+
+// parsePEMCertificates decodes the certificates of the output of find-certificate, by
+// uppercase SHA-1 fingerprint
+func parsePEMCertificates(data []byte) map[string]*x509.Certificate {
+	res := map[string]*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			log.Debug().AnErr("Reason", err).Msg("Skipping invalid keychain certificate")
+			continue
+		}
+
+		sum := sha1.Sum(c.Raw)
+		res[strings.ToUpper(hex.EncodeToString(sum[:]))] = c
+	}
+
+	return res
+}
+
+// pathArgs the keychain argument of the security commands, the search list being used for
+// the login keychain
+func (k keychain) pathArgs() []string {
+	if kc := k.GetPath(); kc != "" {
+		return []string{kc}
+	}
+
+	return nil
+}
+
+// contains is true when the list holds the keychain
+func contains(list []string, kc string) bool {
+	return len(without(list, kc)) != len(list)
+}
@@ -5,17 +5,101 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/rs/zerolog/log"
 )
 
+// capture saves the search list and the default keychain before the first change, the
+// later creations keeping the original settings
+func (k keychain) capture(ctx context.Context) error {
+	k.session.mu.Lock()
+	defer k.session.mu.Unlock()
+
+	if k.session.captured {
+		return nil
+	}
+
+	list, err := k.getSearchList(ctx)
+	if err != nil {
+		return err
+	}
+
+	def, err := k.getDefaultKeychain(ctx)
+	if err != nil {
+		return err
+	}
+
+	k.session.searchList = list
+	k.session.defaultKeychain = def
+	k.session.captured = true
+
+	return nil
+}
+
+// restore removes the keychain of the run from the search list, unless it was listed before
+// the run, and restores the captured keychains still existing. The keychains added since the
+// capture are kept, like the ones of the concurrent runs, thus the lock of the search list
+// must be held
+func (k keychain) restore(ctx context.Context) error {
+	k.session.mu.Lock()
+	defer k.session.mu.Unlock()
+
+	if !k.session.captured {
+		return nil
+	}
+
+	kc := k.GetPath()
+	current, err := k.getSearchList(ctx)
+	if err != nil {
+		return KeyChainError{msg: restoreError, err: err}
+	}
+
+	list := current
+	if !contains(k.session.searchList, kc) {
+		list = without(current, kc)
+	}
+	for _, c := range without(k.session.searchList, append(list, kc)...) {
+		if _, err := os.Stat(c); err == nil {
+			list = append(list, c)
+		}
+	}
+
+	if err := k.setSearchList(ctx, list); err != nil {
+		return KeyChainError{msg: restoreError, err: err}
+	}
+
+	// The default keychain is only restored when the run changed it
+	def, err := k.getDefaultKeychain(ctx)
+	if err != nil {
+		return KeyChainError{msg: restoreError, err: err}
+	}
+
+	if d := k.session.defaultKeychain; d != "" && d != kc && def == kc {
+		if err := k.setDefaultKeychain(ctx, d); err != nil {
+			return KeyChainError{msg: restoreError, err: err}
+		}
+	}
+
+	k.session.captured = false
+
+	return nil
+}
+
+// addKeyChainToSearchList appends the keychain to the search list, unless it is already
+// listed, like an existing keychain
 func (k keychain) addKeyChainToSearchList(ctx context.Context) error {
 	list, err := k.getSearchList(ctx)
 	if err != nil {
 		return err
 	}
 
-	return k.setSearchList(ctx, append(list, k.API.PathService.KeyChain()))
+	kc := k.GetPath()
+	if contains(list, kc) {
+		return nil
+	}
+
+	return k.setSearchList(ctx, append(list, kc))
 }
 
 func (k keychain) listCall(ctx context.Context, args []string) ([]byte, error) {
@@ -44,6 +128,42 @@ func (k keychain) setSearchList(ctx context.Context, list []string) error {
 	return err
 }
 
+func (k keychain) getDefaultKeychain(ctx context.Context) (string, error) {
+	b, err := k.API.Exec.CommandContext(ctx, SecurityUtil, ActionDefaultKeychain).Output()
+	if err != nil {
+		return "", err
+	}
+
+	if l := parseSearchList(b); len(l) > 0 {
+		return l[0], nil
+	}
+
+	return "", nil
+}
+
+func (k keychain) setDefaultKeychain(ctx context.Context, path string) error {
+	log.Info().Str("Path", path).Msg("Set default keychain")
+	_, err := k.API.Exec.CommandContext(ctx, SecurityUtil, ActionDefaultKeychain, "-s", path).Output()
+	return err
+}
+
+// without returns the keychains of the list but the ones of the paths
+func without(list []string, paths ...string) []string {
+	var res []string
+	for _, l := range list {
+		var found bool
+		for _, p := range paths {
+			found = found || l == p
+		}
+
+		if !found {
+			res = append(res, l)
+		}
+	}
+
+	return res
+}
+
 func parseSearchList(data []byte) []string {
 	var res []string
 
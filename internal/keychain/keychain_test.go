@@ -0,0 +1,230 @@
+package keychain
+
+import (
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/path"
+	"dothething/internal/utiltest"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const login = "/Users/user.name/Library/Keychains/login.keychain-db"
+
+type keychainSuite struct {
+	suite.Suite
+	API     *api.API
+	dir     string
+	exec    *utiltest.MockExecutor
+	subject keychain
+}
+
+func TestKeychainSuite(t *testing.T) {
+	suite.Run(t, new(keychainSuite))
+}
+
+func (s *keychainSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "keychain")
+	s.Require().NoError(err)
+	s.dir = dir
+
+	s.exec = new(utiltest.MockExecutor)
+	s.API = &api.API{Config: &api.Config{Path: filepath.Join(dir, "Dummy.xcodeproj")}, Exec: s.exec}
+	s.API.PathService = path.NewPathService(s.API)
+
+	k, err := NewKeyChain(s.API)
+	s.Require().NoError(err)
+	s.subject = k.(keychain)
+}
+
+func (s *keychainSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *keychainSuite) TestCreateAndDeleteRestoresTheSearchList() {
+	// setup:
+	kc := s.API.PathService.KeyChain()
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionListKeyChains}, `    "`+login+`"`, nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionDefaultKeychain}, `    "`+login+`"`, nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionSettings, kc}, "", nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionListKeyChains, "-s", login, kc}, "", nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionDeleteKeychain, kc}, "", nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionListKeyChains, "-s", login}, "", nil)
+
+	var password string
+	create := new(utiltest.MockExecutorCmd)
+	create.On("Output").Return("", nil)
+	s.exec.On("CommandContext", mock.Anything, SecurityUtil, mock.MatchedBy(func(args []string) bool {
+		if len(args) != 4 || args[0] != ActionCreateKeychain || args[3] != kc {
+			return false
+		}
+		password = args[2]
+		return true
+	})).Return(create)
+
+	// when:
+	s.Require().NoError(s.subject.Create(context.Background()))
+
+	// then: the password is random, and the keychain locked for the cleanup to keep it
+	s.Len(password, 43)
+	s.Equal(password, s.subject.password())
+	s.Equal([]string{password}, s.exec.Secrets)
+	s.True(inUse(kc))
+
+	// and: the action context being cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := s.subject.Delete(ctx)
+
+	// then:
+	s.NoError(err)
+	s.exec.AssertExpectations(s.T())
+	s.False(s.subject.session.captured)
+	s.Empty(s.subject.password())
+	s.NoFileExists(runLock(kc))
+}
+
+func (s *keychainSuite) TestCreateFailsBeforeAnyChange() {
+	// setup:
+	s.exec.MockCommandContextError(SecurityUtil, []string{ActionListKeyChains}, errors.New("denied"))
+
+	// when:
+	err := s.subject.Create(context.Background())
+
+	// then:
+	s.Error(err)
+	s.exec.AssertNumberOfCalls(s.T(), "CommandContext", 1)
+}
+
+func (s *keychainSuite) TestCleanup() {
+	// setup: the keychain of a running process being locked
+	orphan := filepath.Join(s.dir, "do-the-thing-123", "do-the-thing.keychain-db")
+	s.Require().NoError(os.MkdirAll(filepath.Dir(orphan), 0755))
+	s.Require().NoError(ioutil.WriteFile(orphan, []byte("keychain"), 0600))
+
+	running := filepath.Join(s.dir, "Build", "do-the-thing-0123456789abcdef.keychain-db")
+	s.Require().NoError(os.MkdirAll(filepath.Dir(running), 0755))
+	s.Require().NoError(ioutil.WriteFile(running, []byte("keychain"), 0600))
+	f, err := lockFile(runLock(running), false)
+	s.Require().NoError(err)
+	defer unlockFile(f)
+
+	stale := "/private/var/folders/T/do-the-thing-42/do-the-thing.keychain-db"
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionListKeyChains}, `    "`+login+`"
+    "`+stale+`"`, nil)
+	s.exec.MockCommandContextError(SecurityUtil, []string{ActionDeleteKeychain, stale}, errors.New("not found"))
+	s.exec.MockCommandContextError(SecurityUtil, []string{ActionDeleteKeychain, orphan}, errors.New("corrupted"))
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionListKeyChains, "-s", login}, "", nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionDefaultKeychain}, `    "`+login+`"`, nil)
+
+	// when:
+	res, err := s.subject.Cleanup(context.Background(), []string{s.dir})
+
+	// then:
+	s.NoError(err)
+	s.Equal([]string{stale, orphan}, res)
+	s.NoFileExists(orphan)
+	s.FileExists(running)
+	s.exec.AssertExpectations(s.T())
+}
+
+func (s *keychainSuite) TestExistingKeychainIsUnlockedAndKept() {
+	// setup:
+	kc := filepath.Join(s.dir, "signing.keychain-db")
+	s.API.Config.CodeSignOption.KeyChain = api.KeyChainConfig{Mode: api.KeyChainExisting, Path: kc}
+	os.Setenv(EnvKeyChainPassword, "s3cret")
+	defer os.Unsetenv(EnvKeyChainPassword)
+
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionListKeyChains}, `    "`+login+`"`, nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionDefaultKeychain}, `    "`+login+`"`, nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionUnlockKeychain, FlagPassword, "s3cret", kc}, "", nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionListKeyChains, "-s", login, kc}, "", nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionListKeyChains, "-s", login}, "", nil)
+
+	// when:
+	s.Require().NoError(s.subject.Create(context.Background()))
+
+	// then: the password of the environment unlocks the keychain
+	s.Equal(kc, s.subject.GetPath())
+	s.Equal("s3cret", s.subject.password())
+	s.Contains(s.exec.Secrets, "s3cret")
+
+	// when:
+	err := s.subject.Delete(context.Background())
+
+	// then: the search list is restored, the keychain being kept
+	s.NoError(err)
+	s.exec.AssertExpectations(s.T())
+	s.exec.AssertNotCalled(s.T(), "CommandContext", mock.Anything, SecurityUtil, []string{ActionDeleteKeychain, kc})
+	s.Empty(s.subject.password())
+}
+
+func (s *keychainSuite) TestExistingKeychainAlreadyListedIsKeptInTheSearchList() {
+	// setup:
+	kc := filepath.Join(s.dir, "signing.keychain-db")
+	s.API.Config.CodeSignOption.KeyChain = api.KeyChainConfig{Mode: api.KeyChainExisting, Path: kc}
+	os.Setenv(EnvKeyChainPassword, "s3cret")
+	defer os.Unsetenv(EnvKeyChainPassword)
+
+	listed := `    "` + login + `"
+    "` + kc + `"`
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionListKeyChains}, listed, nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionDefaultKeychain}, `    "`+login+`"`, nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionUnlockKeychain, FlagPassword, "s3cret", kc}, "", nil)
+	s.exec.MockCommandContext(SecurityUtil, []string{ActionListKeyChains, "-s", login, kc}, "", nil)
+
+	// when:
+	s.Require().NoError(s.subject.Create(context.Background()))
+	err := s.subject.Delete(context.Background())
+
+	// then: the keychain is still listed
+	s.NoError(err)
+	s.exec.AssertExpectations(s.T())
+	s.exec.AssertNotCalled(s.T(), "CommandContext", mock.Anything, SecurityUtil, []string{ActionListKeyChains, "-s", login})
+}
+
+func (s *keychainSuite) TestExistingKeychainRequiresAPassword() {
+	// setup:
+	s.API.Config.CodeSignOption.KeyChain = api.KeyChainConfig{Mode: api.KeyChainExisting, Path: "signing.keychain-db"}
+	os.Unsetenv(EnvKeyChainPassword)
+
+	// when:
+	err := s.subject.Create(context.Background())
+
+	// then:
+	s.Equal(ErrMissingKeyChainPassword, err)
+	s.exec.AssertNotCalled(s.T(), "CommandContext", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *keychainSuite) TestLoginKeychainIsLeftUntouched() {
+	// setup:
+	s.API.Config.CodeSignOption.KeyChain = api.KeyChainConfig{Mode: api.KeyChainLogin}
+	s.API.Config.CodeSignOption.WWDRCertificates = "wwdr.pem"
+
+	// when:
+	ctx := context.Background()
+	s.NoError(s.subject.Create(ctx))
+	s.NoError(s.subject.ImportCertificate(ctx, "Certificate.p12", "p4ssword", "Dummy"))
+	s.NoError(s.subject.Delete(ctx))
+
+	// then:
+	s.Empty(s.subject.GetPath())
+	s.exec.AssertNotCalled(s.T(), "CommandContext", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *keychainSuite) TestUnknownMode() {
+	// setup:
+	s.API.Config.CodeSignOption.KeyChain = api.KeyChainConfig{Mode: "system"}
+
+	// when:
+	err := s.subject.Create(context.Background())
+
+	// then:
+	s.Equal(UnknownModeError("system"), err)
+}
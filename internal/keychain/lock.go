@@ -0,0 +1,43 @@
+package keychain
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// searchListLock the lock serializing the changes of the search list across the processes
+// of the user, the search list being read then written as a whole
+var searchListLock = filepath.Join(os.TempDir(), "do-the-thing-search-list.lock")
+
+// withSearchListLock runs the change of the search list holding the lock
+func withSearchListLock(fn func() error) error {
+	f, err := lockFile(searchListLock, true)
+	if err != nil {
+		return KeyChainError{msg: lockError, err: err}
+	}
+	defer unlockFile(f)
+
+	return fn()
+}
+
+// runLock the lock held by a run for the lifetime of its keychain
+func runLock(keychain string) string {
+	return strings.TrimSuffix(keychain, "-db") + ".lock"
+}
+
+// inUse is true when a running process holds the lock of the keychain
+func inUse(keychain string) bool {
+	path := runLock(keychain)
+	if _, err := os.Stat(path); err != nil {
+		return false
+	}
+
+	f, err := lockFile(path, false)
+	if err != nil {
+		return true
+	}
+	unlockFile(f)
+
+	return false
+}
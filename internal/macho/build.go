@@ -0,0 +1,150 @@
+package macho
+
+import (
+	gomacho "debug/macho"
+	"fmt"
+	"strings"
+)
+
+// The bitcode embedded into a slice
+const (
+	BitcodeEmbedded = "embedded"
+	// BitcodeMarker the placeholder section of the builds made without -fembed-bitcode
+	BitcodeMarker = "marker"
+	BitcodeNone   = "none"
+)
+
+// cpuSubtypeArm64e the subtype of the arm64 CPUs supporting the pointer authentication
+const cpuSubtypeArm64e = 2
+
+// platforms the names of the platforms of LC_BUILD_VERSION
+var platforms = map[uint32]string{
+	1:  "macOS",
+	2:  "iOS",
+	3:  "tvOS",
+	4:  "watchOS",
+	5:  "bridgeOS",
+	6:  "Mac Catalyst",
+	7:  "iOS Simulator",
+	8:  "tvOS Simulator",
+	9:  "watchOS Simulator",
+	10: "DriverKit",
+	11: "visionOS",
+	12: "visionOS Simulator",
+}
+
+// swiftVersions the Swift versions of the ABI version of the Objective-C image info
+var swiftVersions = map[uint32]string{
+	1: "1.0",
+	2: "1.1",
+	3: "2.0",
+	4: "3.0",
+	5: "4.0",
+	6: "4.2",
+	7: "5.0",
+}
+
+// swiftStableABI the first ABI version of the Objective-C image info being stable
+const swiftStableABI = 7
+
+// Simulator is true for the slices built for a simulator, which the devices cannot run
+func (a Arch) Simulator() bool {
+	return strings.HasSuffix(a.Platform, " Simulator")
+}
+
+func platformName(p uint32) string {
+	if n, ok := platforms[p]; ok {
+		return n
+	}
+
+	return fmt.Sprintf("Platform(%v)", p)
+}
+
+// versionMinPlatform resolves the platform of a LC_VERSION_MIN_* command, the Intel slices
+// of the mobile platforms being the simulator ones
+func versionMinPlatform(cmd gomacho.LoadCmd, cpu gomacho.Cpu) string {
+	var res string
+	switch cmd {
+	case loadCmdVersionMinMacOS:
+		return "macOS"
+	case loadCmdVersionMinIOS:
+		res = "iOS"
+	case loadCmdVersionMinTvOS:
+		res = "tvOS"
+	case loadCmdVersionMinWatch:
+		res = "watchOS"
+	}
+
+	if cpu == gomacho.Cpu386 || cpu == gomacho.CpuAmd64 {
+		res += " Simulator"
+	}
+
+	return res
+}
+
+// formatVersion formats the xxxx.yy.zz nibbles encoded version, the patch being omitted when 0
+func formatVersion(v uint32) string {
+	res := fmt.Sprintf("%v.%v", v>>16, (v>>8)&0xff)
+	if v&0xff != 0 {
+		res += fmt.Sprintf(".%v", v&0xff)
+	}
+
+	return res
+}
+
+// readBitcode looks for the __LLVM,__bundle section, the bitcode marker being a single byte
+func readBitcode(f *gomacho.File) string {
+	for _, s := range f.Sections {
+		if s.Seg != "__LLVM" || s.Name != "__bundle" {
+			continue
+		}
+
+		if s.Size <= 1 {
+			return BitcodeMarker
+		}
+
+		return BitcodeEmbedded
+	}
+
+	return BitcodeNone
+}
+
+// readSwift reads the Swift ABI version of the Objective-C image info, the __swift5_* sections
+// standing for the stable ABI when it is missing
+func readSwift(f *gomacho.File) (string, bool) {
+	var swift5 bool
+	for _, s := range f.Sections {
+		if strings.HasPrefix(s.Name, "__swift5_") {
+			swift5 = true
+		}
+
+		if s.Name != "__objc_imageinfo" {
+			continue
+		}
+
+		// objc_image_info: version, flags, the Swift ABI version being the second byte of
+		// the flags
+		b, err := s.Data()
+		if err != nil || len(b) < 8 {
+			continue
+		}
+
+		abi := (f.ByteOrder.Uint32(b[4:]) >> 8) & 0xff
+		if abi == 0 {
+			continue
+		}
+
+		v, ok := swiftVersions[abi]
+		if !ok {
+			v = swiftVersions[swiftStableABI]
+		}
+
+		return v, abi >= swiftStableABI
+	}
+
+	if swift5 {
+		return swiftVersions[swiftStableABI], true
+	}
+
+	return "", false
+}
@@ -0,0 +1,402 @@
+package macho
+
+import (
+	"bytes"
+	gomacho "debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The load commands not exposed by debug/macho
+const (
+	loadCmdBuildVersion    gomacho.LoadCmd = 0x32
+	loadCmdCodeSignature   gomacho.LoadCmd = 0x1d
+	loadCmdEncryptionInfo  gomacho.LoadCmd = 0x21
+	loadCmdEncryption64    gomacho.LoadCmd = 0x2c
+	loadCmdLazyLoadDylib   gomacho.LoadCmd = 0x20
+	loadCmdLoadUpward      gomacho.LoadCmd = 0x80000023
+	loadCmdLoadWeakDylib   gomacho.LoadCmd = 0x80000018
+	loadCmdReexportDylib   gomacho.LoadCmd = 0x8000001f
+	loadCmdRpath           gomacho.LoadCmd = 0x8000001c
+	loadCmdUUID            gomacho.LoadCmd = 0x1b
+	loadCmdVersionMinIOS   gomacho.LoadCmd = 0x25
+	loadCmdVersionMinMacOS gomacho.LoadCmd = 0x24
+	loadCmdVersionMinTvOS  gomacho.LoadCmd = 0x2f
+	loadCmdVersionMinWatch gomacho.LoadCmd = 0x30
+)
+
+// The types of binaries
+const (
+	TypeBundle         = "bundle"
+	TypeDynamicLibrary = "dynamic-library"
+	TypeExecutable     = "executable"
+	TypeObject         = "object"
+	TypeStaticLibrary  = "static-library"
+)
+
+// maxFatArchs bounds the slices of a universal binary, the Java class files sharing its magic
+const maxFatArchs = 32
+
+// maxSignatureSize bounds the code signature read from a content of unknown size
+const maxSignatureSize = 64 << 20
+
+// ErrNotMachO the content is neither a thin nor a universal Mach-O binary
+var ErrNotMachO = errors.New("Not a Mach-O binary")
+
+// File a Mach-O binary, thin binaries having a single architecture
+type File struct {
+	Archs []Arch
+	// Type the kind of binary, like TypeExecutable or TypeStaticLibrary
+	Type string
+}
+
+// Arch a slice of the binary
+type Arch struct {
+	// Bitcode tells whether the slice embeds the LLVM bitcode, one of BitcodeNone,
+	// BitcodeMarker or BitcodeEmbedded
+	Bitcode   string
+	CPU       string
+	Dylibs    []Dylib
+	Encrypted bool
+	MinOS     string
+	Platform  string
+	Rpaths    []string
+	SDK       string
+	Signature *CodeSignature
+	// Swift the Swift ABI version the slice is built with, empty without Swift code
+	Swift          string
+	SwiftStableABI bool
+	// UUID identifies the build of the slice, matching the one of its debug symbols
+	UUID string
+}
+
+// Dylib a dynamic library linked by the slice
+type Dylib struct {
+	Path string
+	// Weak the library may be missing at launch, like the ones of a more recent OS version
+	Weak bool
+}
+
+// Static is true for the static libraries and the object files, linked into the binaries
+// using them
+func (f File) Static() bool {
+	return f.Type == TypeStaticLibrary || f.Type == TypeObject
+}
+
+// cpuNames the names used by the Apple toolchain
+var cpuNames = map[gomacho.Cpu]string{
+	gomacho.Cpu386:   "i386",
+	gomacho.CpuAmd64: "x86_64",
+	gomacho.CpuArm:   "armv7",
+	gomacho.CpuArm64: "arm64",
+}
+
+// fileTypes the kinds of the Mach-O file types
+var fileTypes = map[gomacho.Type]string{
+	gomacho.TypeBundle: TypeBundle,
+	gomacho.TypeDylib:  TypeDynamicLibrary,
+	gomacho.TypeExec:   TypeExecutable,
+	gomacho.TypeObj:    TypeObject,
+}
+
+// Parse reads the architectures of the thin or universal binary, the static libraries being
+// described by their first object file
+func Parse(r io.ReaderAt) (*File, error) {
+	var magic [8]byte
+	if _, err := r.ReadAt(magic[:], 0); err != nil {
+		return nil, ErrNotMachO
+	}
+
+	// The slices are read by hand, debug/macho rejecting the ones of the static libraries
+	if be.Uint32(magic[:]) == gomacho.MagicFat {
+		return parseFat(r, be.Uint32(magic[4:]))
+	}
+
+	arch, kind, err := parseSlice(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{Archs: []Arch{arch}, Type: kind}, nil
+}
+
+// parseFat reads the n slices of the universal binary
+func parseFat(r io.ReaderAt, n uint32) (*File, error) {
+	if n == 0 || n > maxFatArchs {
+		return nil, ErrNotMachO
+	}
+
+	// fat_arch: cputype, cpusubtype, offset, size, align
+	b := make([]byte, 20*n)
+	if _, err := r.ReadAt(b, 8); err != nil {
+		return nil, ErrNotMachO
+	}
+
+	var res File
+	for i := uint32(0); i < n; i++ {
+		off, size := be.Uint32(b[20*i+8:]), be.Uint32(b[20*i+12:])
+
+		arch, kind, err := parseSlice(io.NewSectionReader(r, int64(off), int64(size)))
+		if err != nil {
+			return nil, err
+		}
+		res.Archs = append(res.Archs, arch)
+		res.Type = kind
+	}
+
+	return &res, nil
+}
+
+// parseSlice reads the thin binary or static library, r being the content of the slice only
+func parseSlice(r io.ReaderAt) (Arch, string, error) {
+	var magic [len(arMagic)]byte
+	if _, err := r.ReadAt(magic[:], 0); err == nil && string(magic[:]) == arMagic {
+		return parseStaticLibrary(r)
+	}
+
+	f, err := gomacho.NewFile(r)
+	if err != nil {
+		return Arch{}, "", ErrNotMachO
+	}
+
+	kind, ok := fileTypes[f.Type]
+	if !ok {
+		kind = f.Type.String()
+	}
+
+	arch, err := parseArch(f, r)
+	return arch, kind, err
+}
+
+// arMagic the global header of the ar archives
+const arMagic = "!<arch>\n"
+
+// parseStaticLibrary reads the first object file of the ar archive, the objects of a slice
+// sharing the same architecture and platform
+func parseStaticLibrary(r io.ReaderAt) (Arch, string, error) {
+	off := int64(len(arMagic))
+	for {
+		// ar_hdr: name[16], date[12], uid[6], gid[6], mode[8], size[10], fmag[2]
+		var h [60]byte
+		if _, err := r.ReadAt(h[:], off); err != nil {
+			return Arch{}, "", ErrNotMachO
+		}
+
+		size, err := strconv.ParseInt(strings.TrimSpace(string(h[48:58])), 10, 64)
+		if err != nil || size < 0 {
+			return Arch{}, "", ErrNotMachO
+		}
+
+		name := strings.TrimSpace(string(h[0:16]))
+		data, content := off+int64(len(h)), size
+
+		// The BSD archives store the long names at the start of the content
+		if strings.HasPrefix(name, "#1/") {
+			n, err := strconv.ParseInt(name[3:], 10, 64)
+			if err != nil || n < 0 || n > size {
+				return Arch{}, "", ErrNotMachO
+			}
+
+			b := make([]byte, n)
+			if _, err := r.ReadAt(b, data); err != nil {
+				return Arch{}, "", ErrNotMachO
+			}
+			name = strings.TrimRight(string(b), "\x00")
+			data, content = data+n, content-n
+		}
+
+		// Skipping the symbol tables
+		if !strings.HasPrefix(name, "__.SYMDEF") {
+			sr := io.NewSectionReader(r, data, content)
+			if f, err := gomacho.NewFile(sr); err == nil {
+				arch, err := parseArch(f, sr)
+				return arch, TypeStaticLibrary, err
+			}
+		}
+
+		// The members are aligned on 2 bytes
+		next := off + int64(len(h)) + size + size%2
+		if next <= off {
+			return Arch{}, "", ErrNotMachO
+		}
+		off = next
+	}
+}
+
+// OpenArch opens the slice of the CPU of the thin or universal binary, the closer releasing
+// the file
+func OpenArch(path, cpu string) (*gomacho.File, io.Closer, error) {
+	if ff, err := gomacho.OpenFat(path); err == nil {
+		for _, a := range ff.Arches {
+			if cpuName(a.Cpu, a.SubCpu) == cpu {
+				return a.File, ff, nil
+			}
+		}
+		ff.Close()
+
+		return nil, nil, fmt.Errorf("no %v architecture found into %v", cpu, path)
+	}
+
+	f, err := gomacho.Open(path)
+	if err != nil {
+		return nil, nil, ErrNotMachO
+	}
+
+	if cpuName(f.Cpu, f.SubCpu) != cpu {
+		f.Close()
+		return nil, nil, fmt.Errorf("no %v architecture found into %v", cpu, path)
+	}
+
+	return f, f, nil
+}
+
+// parseArch reads the slice, r being the content of the slice only
+func parseArch(f *gomacho.File, r io.ReaderAt) (Arch, error) {
+	res := Arch{CPU: cpuName(f.Cpu, f.SubCpu)}
+
+	for _, l := range f.Loads {
+		raw := l.Raw()
+		if len(raw) < 8 {
+			continue
+		}
+
+		cmd := gomacho.LoadCmd(f.ByteOrder.Uint32(raw))
+		switch cmd {
+		case loadCmdUUID:
+			// uuid_command: cmd, cmdsize, uuid
+			if len(raw) >= 24 {
+				res.UUID = formatUUID(raw[8:24])
+			}
+
+		case loadCmdCodeSignature:
+			sig, err := readCodeSignature(f, raw, r)
+			if err != nil {
+				return res, err
+			}
+			res.Signature = sig
+
+		case loadCmdBuildVersion:
+			// build_version_command: cmd, cmdsize, platform, minos, sdk, ntools
+			if len(raw) >= 20 {
+				res.Platform = platformName(f.ByteOrder.Uint32(raw[8:]))
+				res.MinOS = formatVersion(f.ByteOrder.Uint32(raw[12:]))
+				res.SDK = formatVersion(f.ByteOrder.Uint32(raw[16:]))
+			}
+
+		case loadCmdVersionMinIOS, loadCmdVersionMinMacOS, loadCmdVersionMinTvOS, loadCmdVersionMinWatch:
+			// version_min_command: cmd, cmdsize, version, sdk, the older binaries having no
+			// LC_BUILD_VERSION
+			if len(raw) >= 16 && res.Platform == "" {
+				res.Platform = versionMinPlatform(cmd, f.Cpu)
+				res.MinOS = formatVersion(f.ByteOrder.Uint32(raw[8:]))
+				res.SDK = formatVersion(f.ByteOrder.Uint32(raw[12:]))
+			}
+
+		case gomacho.LoadCmdDylib, loadCmdLoadWeakDylib, loadCmdReexportDylib, loadCmdLazyLoadDylib, loadCmdLoadUpward:
+			// dylib_command: cmd, cmdsize, name offset, timestamp, versions
+			if name, ok := loadString(f, raw); ok {
+				res.Dylibs = append(res.Dylibs, Dylib{Path: name, Weak: cmd == loadCmdLoadWeakDylib})
+			}
+
+		case loadCmdRpath:
+			// rpath_command: cmd, cmdsize, path offset
+			if path, ok := loadString(f, raw); ok {
+				res.Rpaths = append(res.Rpaths, path)
+			}
+
+		case loadCmdEncryptionInfo, loadCmdEncryption64:
+			// encryption_info_command: cmd, cmdsize, cryptoff, cryptsize, cryptid
+			if len(raw) >= 20 {
+				res.Encrypted = f.ByteOrder.Uint32(raw[16:]) != 0
+			}
+		}
+	}
+
+	res.Bitcode = readBitcode(f)
+	res.Swift, res.SwiftStableABI = readSwift(f)
+
+	return res, nil
+}
+
+// loadString reads the string of the load command, its offset following the command size
+func loadString(f *gomacho.File, raw []byte) (string, bool) {
+	if len(raw) < 12 {
+		return "", false
+	}
+
+	off := f.ByteOrder.Uint32(raw[8:])
+	if off >= uint32(len(raw)) {
+		return "", false
+	}
+
+	s := raw[off:]
+	if i := bytes.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+
+	return string(s), true
+}
+
+// readCodeSignature reads the code signature the load command points to
+func readCodeSignature(f *gomacho.File, raw []byte, r io.ReaderAt) (*CodeSignature, error) {
+	if len(raw) < 16 {
+		return nil, ErrInvalidSignature
+	}
+
+	// linkedit_data_command: cmd, cmdsize, dataoff, datasize
+	off := f.ByteOrder.Uint32(raw[8:])
+	size := f.ByteOrder.Uint32(raw[12:])
+
+	// Not trusting the size of a corrupted binary before allocating
+	end := int64(off) + int64(size)
+	if n, ok := readerSize(r); (ok && end > n) || (!ok && size > maxSignatureSize) {
+		return nil, ErrInvalidSignature
+	}
+
+	b := make([]byte, size)
+	if _, err := r.ReadAt(b, int64(off)); err != nil {
+		return nil, err
+	}
+
+	return ParseCodeSignature(b)
+}
+
+// readerSize the size of the content of the reader, when it is known
+func readerSize(r io.ReaderAt) (int64, bool) {
+	switch v := r.(type) {
+	case interface{ Size() int64 }:
+		return v.Size(), true
+	case *os.File:
+		if fi, err := v.Stat(); err == nil {
+			return fi.Size(), true
+		}
+	}
+
+	return 0, false
+}
+
+// formatUUID formats the UUID the way the Apple tools print it
+func formatUUID(b []byte) string {
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]))
+}
+
+func cpuName(c gomacho.Cpu, sub uint32) string {
+	// The upper bits of the subtype are capabilities, like the pointer authentication ABI
+	if c == gomacho.CpuArm64 && sub&0x00ffffff == cpuSubtypeArm64e {
+		return "arm64e"
+	}
+
+	if n, ok := cpuNames[c]; ok {
+		return n
+	}
+
+	return c.String()
+}
+
+// be the code signature blobs are always big endian, whatever the binary byte order
+var be = binary.BigEndian
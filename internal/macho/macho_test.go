@@ -0,0 +1,181 @@
+package macho
+
+import (
+	"bytes"
+	"dothething/internal/utiltest"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const entitlements = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>get-task-allow</key><true/></dict></plist>`
+
+func TestParse(t *testing.T) {
+	// setup:
+	sig := utiltest.NewCodeSignature("com.dummy.app", "12345ABCDE", []byte(entitlements), nil)
+
+	// when:
+	f, err := Parse(bytes.NewReader(utiltest.NewMachO(sig)))
+
+	// then:
+	assert.NoError(t, err)
+	assert.Len(t, f.Archs, 1)
+	assert.Equal(t, "arm64", f.Archs[0].CPU)
+	assert.Equal(t, "com.dummy.app", f.Archs[0].Signature.Identifier)
+	assert.Equal(t, "12345ABCDE", f.Archs[0].Signature.TeamID)
+	assert.Equal(t, entitlements, string(f.Archs[0].Signature.Entitlements))
+	assert.Nil(t, f.Archs[0].Signature.Signer())
+}
+
+func TestParseUnsigned(t *testing.T) {
+	// when:
+	f, err := Parse(bytes.NewReader(utiltest.NewMachO(nil)))
+
+	// then:
+	assert.NoError(t, err)
+	assert.Nil(t, f.Archs[0].Signature)
+}
+
+func TestParseInvalid(t *testing.T) {
+	// when:
+	_, err := Parse(bytes.NewReader([]byte("#!/bin/sh")))
+
+	// then:
+	assert.Equal(t, ErrNotMachO, err)
+
+	// when: the superblob is truncated
+	sig := utiltest.NewCodeSignature("com.dummy.app", "12345ABCDE", nil, nil)
+	_, err = ParseCodeSignature(sig[:20])
+
+	// then:
+	assert.Equal(t, ErrInvalidSignature, err)
+}
+
+func TestParseOutOfRangeSignature(t *testing.T) {
+	// setup: the datasize of LC_CODE_SIGNATURE pointing past the end of the binary
+	b := utiltest.NewMachO(utiltest.NewCodeSignature("com.dummy.app", "12345ABCDE", nil, nil))
+	binary.LittleEndian.PutUint32(b[44:], 0xfffffff0)
+
+	// when:
+	_, err := Parse(bytes.NewReader(b))
+
+	// then:
+	assert.Equal(t, ErrInvalidSignature, err)
+}
+
+func TestParseUniversalUUIDs(t *testing.T) {
+	// setup:
+	uuid := []byte{0xde, 0xad, 0xbe, 0xef, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	fat := utiltest.NewFatMachO(
+		[]uint32{0x0100000c, 0x01000007},
+		utiltest.NewMachOWithUUID(0x0100000c, uuid, nil),
+		utiltest.NewMachOWithUUID(0x01000007, nil, nil),
+	)
+
+	// when:
+	f, err := Parse(bytes.NewReader(fat))
+
+	// then:
+	assert.NoError(t, err)
+	assert.Len(t, f.Archs, 2)
+	assert.Equal(t, "arm64", f.Archs[0].CPU)
+	assert.Equal(t, "DEADBEEF-0001-0203-0405-060708090A0B", f.Archs[0].UUID)
+	assert.Equal(t, "x86_64", f.Archs[1].CPU)
+	assert.Empty(t, f.Archs[1].UUID)
+}
+
+func TestParseBuild(t *testing.T) {
+	// setup: the flags of objc_image_info holding the Swift ABI version 7
+	imageInfo := []byte{0, 0, 0, 0, 0x40, 0x07, 0, 0}
+	bin := utiltest.NewMachOBinary(
+		0x0100000c,
+		6,
+		[][]byte{
+			utiltest.NewBuildVersionLoad(2, 0x000d0000, 0x00100201),
+			utiltest.NewDylibLoad(0xc, "/usr/lib/libSystem.B.dylib"),
+			utiltest.NewDylibLoad(0x80000018, "/System/Library/Frameworks/AppTrackingTransparency.framework/AppTrackingTransparency"),
+			utiltest.NewRpathLoad("@executable_path/Frameworks"),
+			utiltest.NewEncryptionLoad(1),
+		},
+		utiltest.MachOSection{Segment: "__DATA_CONST", Name: "__objc_imageinfo", Data: imageInfo},
+		utiltest.MachOSection{Segment: "__LLVM", Name: "__bundle", Data: []byte{0}},
+	)
+
+	// when:
+	f, err := Parse(bytes.NewReader(bin))
+
+	// then:
+	assert.NoError(t, err)
+	assert.Equal(t, TypeDynamicLibrary, f.Type)
+	assert.False(t, f.Static())
+
+	a := f.Archs[0]
+	assert.Equal(t, "iOS", a.Platform)
+	assert.False(t, a.Simulator())
+	assert.Equal(t, "13.0", a.MinOS)
+	assert.Equal(t, "16.2.1", a.SDK)
+	assert.Equal(t, []Dylib{
+		{Path: "/usr/lib/libSystem.B.dylib"},
+		{Path: "/System/Library/Frameworks/AppTrackingTransparency.framework/AppTrackingTransparency", Weak: true},
+	}, a.Dylibs)
+	assert.Equal(t, []string{"@executable_path/Frameworks"}, a.Rpaths)
+	assert.True(t, a.Encrypted)
+	assert.Equal(t, BitcodeMarker, a.Bitcode)
+	assert.Equal(t, "5.0", a.Swift)
+	assert.True(t, a.SwiftStableABI)
+}
+
+func TestParseStaticLibrary(t *testing.T) {
+	// setup: a universal static library with a simulator slice
+	device := utiltest.NewMachOBinary(0x0100000c, 1, [][]byte{utiltest.NewBuildVersionLoad(2, 0x000c0000, 0x000e0000)})
+	simulator := utiltest.NewMachOBinary(0x01000007, 1, [][]byte{utiltest.NewBuildVersionLoad(7, 0x000c0000, 0x000e0000)})
+	fat := utiltest.NewFatMachO(
+		[]uint32{0x0100000c, 0x01000007},
+		utiltest.NewStaticLibrary(device),
+		utiltest.NewStaticLibrary(simulator, device),
+	)
+
+	// when:
+	f, err := Parse(bytes.NewReader(fat))
+
+	// then:
+	assert.NoError(t, err)
+	assert.Equal(t, TypeStaticLibrary, f.Type)
+	assert.True(t, f.Static())
+	assert.Len(t, f.Archs, 2)
+	assert.Equal(t, "arm64", f.Archs[0].CPU)
+	assert.False(t, f.Archs[0].Simulator())
+	assert.Equal(t, "x86_64", f.Archs[1].CPU)
+	assert.Equal(t, "iOS Simulator", f.Archs[1].Platform)
+	assert.True(t, f.Archs[1].Simulator())
+	assert.Equal(t, BitcodeNone, f.Archs[1].Bitcode)
+	assert.Empty(t, f.Archs[1].Swift)
+}
+
+func TestParseMalformedStaticLibrary(t *testing.T) {
+	header := func(name string, size string) string {
+		return fmt.Sprintf("!<arch>\n%-16s%-12d%-6d%-6d%-8o%-10s`\n", name, 0, 0, 0, 0644, size)
+	}
+
+	cases := map[string]string{
+		"negative name length":     header("#1/-1", "8") + "object.o",
+		"name longer than content": header("#1/16", "8") + "object.o",
+		"negative size":            header("object.o", "-60"),
+		"invalid size":             header("object.o", "size"),
+		"truncated":                header("object.o", "8"),
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			// when:
+			_, _, err := parseStaticLibrary(strings.NewReader(data))
+
+			// then:
+			assert.Equal(t, ErrNotMachO, err)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package macho
+
+import (
+	"crypto/x509"
+	"errors"
+
+	"go.mozilla.org/pkcs7"
+)
+
+// The code signature blobs magic numbers
+const (
+	magicEmbeddedSignature = 0xfade0cc0
+	magicCodeDirectory     = 0xfade0c02
+	magicEntitlements      = 0xfade7171
+	magicDEREntitlements   = 0xfade7172
+	magicBlobWrapper       = 0xfade0b01
+)
+
+// The code signature slots
+const (
+	slotCodeDirectory   = 0
+	slotEntitlements    = 5
+	slotDEREntitlements = 7
+	slotSignature       = 0x10000
+)
+
+// The code directory versions adding the fields read
+const (
+	versionSupportsTeamID = 0x20200
+)
+
+var (
+	// ErrInvalidSignature the code signature superblob is malformed
+	ErrInvalidSignature = errors.New("Invalid code signature")
+)
+
+// CodeSignature the content of the embedded code signature
+type CodeSignature struct {
+	// Entitlements the XML property list of the entitlements
+	Entitlements []byte
+	// DEREntitlements the DER encoded entitlements
+	DEREntitlements []byte
+	Identifier      string
+	// Certificates the certificates of the CMS signature, empty for ad-hoc signatures
+	Certificates []*x509.Certificate
+	TeamID       string
+}
+
+// Signer the leaf certificate of the signature, if any
+func (s CodeSignature) Signer() *x509.Certificate {
+	for _, c := range s.Certificates {
+		if !c.IsCA {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// ParseCodeSignature decodes the embedded signature superblob
+func ParseCodeSignature(b []byte) (*CodeSignature, error) {
+	if len(b) < 12 || be.Uint32(b) != magicEmbeddedSignature {
+		return nil, ErrInvalidSignature
+	}
+
+	var res CodeSignature
+	count := be.Uint32(b[8:])
+	for i := uint32(0); i < count; i++ {
+		idx := 12 + int(i)*8
+		if idx+8 > len(b) {
+			return nil, ErrInvalidSignature
+		}
+
+		slot := be.Uint32(b[idx:])
+		blob, err := readBlob(b, be.Uint32(b[idx+4:]))
+		if err != nil {
+			return nil, err
+		}
+
+		switch slot {
+		case slotCodeDirectory:
+			if err := res.parseCodeDirectory(blob); err != nil {
+				return nil, err
+			}
+
+		case slotEntitlements:
+			if be.Uint32(blob) == magicEntitlements {
+				res.Entitlements = blob[8:]
+			}
+
+		case slotDEREntitlements:
+			if be.Uint32(blob) == magicDEREntitlements {
+				res.DEREntitlements = blob[8:]
+			}
+
+		case slotSignature:
+			// Ad-hoc signatures have an empty wrapper
+			if be.Uint32(blob) == magicBlobWrapper && len(blob) > 8 {
+				p7, err := pkcs7.Parse(blob[8:])
+				if err != nil {
+					return nil, err
+				}
+				res.Certificates = p7.Certificates
+			}
+		}
+	}
+
+	return &res, nil
+}
+
+// readBlob returns the blob at offset, bounded by its length header
+func readBlob(b []byte, offset uint32) ([]byte, error) {
+	if int(offset)+8 > len(b) {
+		return nil, ErrInvalidSignature
+	}
+
+	length := be.Uint32(b[offset+4:])
+	if length < 8 || int(offset)+int(length) > len(b) {
+		return nil, ErrInvalidSignature
+	}
+
+	return b[offset : offset+length], nil
+}
+
+// parseCodeDirectory reads the identifier and the team identifier of the code directory
+func (s *CodeSignature) parseCodeDirectory(cd []byte) error {
+	if len(cd) < 44 || be.Uint32(cd) != magicCodeDirectory {
+		return ErrInvalidSignature
+	}
+
+	version := be.Uint32(cd[8:])
+	s.Identifier = cString(cd, be.Uint32(cd[20:]))
+
+	if version >= versionSupportsTeamID && len(cd) >= 52 {
+		if off := be.Uint32(cd[48:]); off != 0 {
+			s.TeamID = cString(cd, off)
+		}
+	}
+
+	return nil
+}
+
+// cString reads the NUL terminated string at offset
+func cString(b []byte, offset uint32) string {
+	if int(offset) >= len(b) {
+		return ""
+	}
+
+	for i := int(offset); i < len(b); i++ {
+		if b[i] == 0 {
+			return string(b[offset:i])
+		}
+	}
+
+	return string(b[offset:])
+}
@@ -0,0 +1,232 @@
+package ota
+
+import (
+	"archive/zip"
+	"context"
+	"dothething/internal/api"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"howett.net/plist"
+)
+
+// Folder name of the folder generated into the export folder
+const Folder = "ota"
+
+var (
+	// ErrMissingAppURL the manifest application URL is required to know where the bundle is hosted
+	ErrMissingAppURL = errors.New("The manifest appURL export option is required for over-the-air distribution")
+
+	// ErrInvalidAppURL the manifest application URL must locate the IPA, not its folder
+	ErrInvalidAppURL = errors.New("The manifest appURL export option must be the URL of the IPA")
+
+	// ErrNoPackage no IPA has been found into the export folder
+	ErrNoPackage = errors.New("No IPA found into the export folder")
+
+	// ErrInvalidPackage the IPA does not contain an application
+	ErrInvalidPackage = errors.New("No application Info.plist found into the IPA")
+)
+
+// infoPlistRegexp matches the Info.plist of the application, and not the one of its extensions
+var infoPlistRegexp = regexp.MustCompile(`^Payload/[^/]+\.app/Info\.plist$`)
+
+// appInfo the Info.plist values describing the application
+type appInfo struct {
+	BundleIdentifier string `plist:"CFBundleIdentifier"`
+	DisplayName      string `plist:"CFBundleDisplayName"`
+	Name             string `plist:"CFBundleName"`
+	ShortVersion     string `plist:"CFBundleShortVersionString"`
+	Version          string `plist:"CFBundleVersion"`
+}
+
+// Title the name of the application displayed on the home screen
+func (i appInfo) Title() string {
+	if i.DisplayName != "" {
+		return i.DisplayName
+	}
+
+	return i.Name
+}
+
+type otaService struct {
+	*api.API
+}
+
+// NewOTAService create a new instance of the over-the-air distribution service
+func NewOTAService(api *api.API) api.OTAService {
+	return otaService{api}
+}
+
+// ReadManifest reads the over-the-air manifest of the export options plist, nil when the
+// export options have none
+func ReadManifest(exportPList string) (*api.ExportManifest, error) {
+	b, err := ioutil.ReadFile(exportPList)
+	if err != nil {
+		return nil, err
+	}
+
+	var o api.ExportOptions
+	if _, err := plist.Unmarshal(b, &o); err != nil {
+		return nil, err
+	}
+
+	return o.Manifest, nil
+}
+
+// Generate writes a self-contained folder, to be hosted at the location of the manifest appURL,
+// with the IPA, the itms-services manifest and the install page
+func (s otaService) Generate(ctx context.Context, exportPath string, m *api.ExportManifest) (string, error) {
+	if m == nil || m.AppURL == "" {
+		return "", ErrMissingAppURL
+	}
+
+	appURL, err := url.Parse(m.AppURL)
+	if err != nil {
+		return "", err
+	}
+
+	if appURL.Path == "" || strings.HasSuffix(appURL.Path, "/") {
+		return "", ErrInvalidAppURL
+	}
+
+	ipa, err := findPackage(exportPath)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := readAppInfo(ipa)
+	if err != nil {
+		return "", err
+	}
+
+	dst := filepath.Join(exportPath, Folder)
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return "", err
+	}
+
+	// The IPA is named after the appURL for the folder to be hosted as is
+	if err := copyFile(ipa, filepath.Join(dst, path.Base(appURL.Path))); err != nil {
+		return "", err
+	}
+
+	b, err := plist.MarshalIndent(newManifest(info, *m), plist.XMLFormat, "\t")
+	if err != nil {
+		return "", err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dst, "manifest.plist"), b, 0644); err != nil {
+		return "", err
+	}
+
+	manifestURL := appURL.ResolveReference(&url.URL{Path: "manifest.plist"})
+	pageURL := appURL.ResolveReference(&url.URL{Path: "index.html"})
+	if err := writePage(filepath.Join(dst, "index.html"), info, *m, manifestURL, pageURL); err != nil {
+		return "", err
+	}
+
+	log.Info().
+		Str("Path", dst).
+		Str("URL", pageURL.String()).
+		Msg("Generated over-the-air distribution")
+
+	return dst, nil
+}
+
+// newManifest creates the itms-services manifest of the application
+func newManifest(info appInfo, m api.ExportManifest) api.OTAManifest {
+	assets := []api.OTAAsset{{Kind: "software-package", URL: m.AppURL}}
+	if m.DisplayImageURL != "" {
+		assets = append(assets, api.OTAAsset{Kind: "display-image", URL: m.DisplayImageURL})
+	}
+	if m.FullSizeImageURL != "" {
+		assets = append(assets, api.OTAAsset{Kind: "full-size-image", URL: m.FullSizeImageURL})
+	}
+
+	return api.OTAManifest{
+		Items: []api.OTAItem{{
+			Assets: assets,
+			Metadata: api.OTAMetadata{
+				BundleIdentifier: info.BundleIdentifier,
+				BundleVersion:    info.ShortVersion,
+				Kind:             "software",
+				Title:            info.Title(),
+			},
+		}},
+	}
+}
+
+// findPackage returns the IPA exported into the folder
+func findPackage(exportPath string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(exportPath, "*.ipa"))
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) == 0 {
+		return "", ErrNoPackage
+	}
+
+	return matches[0], nil
+}
+
+// readAppInfo decodes the Info.plist of the application packaged into the IPA
+func readAppInfo(ipa string) (appInfo, error) {
+	var res appInfo
+
+	r, err := zip.OpenReader(ipa)
+	if err != nil {
+		return res, err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if !infoPlistRegexp.MatchString(f.Name) {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return res, err
+		}
+		defer rc.Close()
+
+		b, err := ioutil.ReadAll(rc)
+		if err != nil {
+			return res, err
+		}
+
+		// The Info.plist is usually a binary plist once packaged
+		_, err = plist.Unmarshal(b, &res)
+		return res, err
+	}
+
+	return res, ErrInvalidPackage
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
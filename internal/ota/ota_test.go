@@ -0,0 +1,186 @@
+package ota
+
+import (
+	"archive/zip"
+	"context"
+	"dothething/internal/api"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"howett.net/plist"
+)
+
+const infoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleIdentifier</key>
+	<string>com.dummy.app</string>
+	<key>CFBundleName</key>
+	<string>Dummy</string>
+	<key>CFBundleShortVersionString</key>
+	<string>1.2.3</string>
+	<key>CFBundleVersion</key>
+	<string>42</string>
+</dict>
+</plist>`
+
+type otaSuite struct {
+	suite.Suite
+	API      *api.API
+	dir      string
+	manifest *api.ExportManifest
+	subject  otaService
+}
+
+func TestOTASuite(t *testing.T) {
+	suite.Run(t, new(otaSuite))
+}
+
+func (s *otaSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "ota")
+	s.Require().NoError(err)
+	s.dir = dir
+
+	s.API = &api.API{Config: &api.Config{}}
+	s.manifest = &api.ExportManifest{
+		AppURL:          "https://example.com/builds/42/Dummy.ipa",
+		DisplayImageURL: "https://example.com/icon.png",
+	}
+	s.subject = otaService{s.API}
+}
+
+func (s *otaSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+// writePackage creates an IPA containing the files
+func (s *otaSuite) writePackage(files map[string]string) {
+	f, err := os.Create(filepath.Join(s.dir, "App.ipa"))
+	s.Require().NoError(err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		e, err := w.Create(name)
+		s.Require().NoError(err)
+		_, err = e.Write([]byte(content))
+		s.Require().NoError(err)
+	}
+	s.Require().NoError(w.Close())
+}
+
+func (s *otaSuite) TestGenerate() {
+	// setup:
+	s.writePackage(map[string]string{
+		"Payload/Dummy.app/Info.plist":                        infoPlist,
+		"Payload/Dummy.app/PlugIns/Ext.appex/Info.plist":      "invalid",
+		"Payload/Dummy.app/Frameworks/F.framework/Info.plist": "invalid",
+	})
+
+	// when:
+	dst, err := s.subject.Generate(context.Background(), s.dir, s.manifest)
+
+	// then:
+	s.Require().NoError(err)
+	s.Equal(filepath.Join(s.dir, Folder), dst)
+	s.FileExists(filepath.Join(dst, "Dummy.ipa"))
+
+	// and: the manifest should describe the application
+	b, err := ioutil.ReadFile(filepath.Join(dst, "manifest.plist"))
+	s.Require().NoError(err)
+
+	var m api.OTAManifest
+	_, err = plist.Unmarshal(b, &m)
+	s.Require().NoError(err)
+	s.Equal(api.OTAManifest{Items: []api.OTAItem{{
+		Assets: []api.OTAAsset{
+			{Kind: "software-package", URL: "https://example.com/builds/42/Dummy.ipa"},
+			{Kind: "display-image", URL: "https://example.com/icon.png"},
+		},
+		Metadata: api.OTAMetadata{
+			BundleIdentifier: "com.dummy.app",
+			BundleVersion:    "1.2.3",
+			Kind:             "software",
+			Title:            "Dummy",
+		},
+	}}}, m)
+
+	// and: the page should link the manifest and embed the QR code
+	b, err = ioutil.ReadFile(filepath.Join(dst, "index.html"))
+	s.Require().NoError(err)
+	page := string(b)
+	s.Contains(page, "itms-services://?action=download-manifest&amp;url=https%3A%2F%2Fexample.com%2Fbuilds%2F42%2Fmanifest.plist")
+	s.Contains(page, "data:image/png;base64,")
+	s.Contains(page, "https://example.com/builds/42/index.html")
+	s.Contains(page, "1.2.3 (42)")
+}
+
+func (s *otaSuite) TestGenerateWithoutAppURL() {
+	// setup:
+	s.manifest = nil
+
+	// when:
+	_, err := s.subject.Generate(context.Background(), s.dir, s.manifest)
+
+	// then:
+	s.Equal(ErrMissingAppURL, err)
+}
+
+func (s *otaSuite) TestGenerateWithoutPackage() {
+	// when:
+	_, err := s.subject.Generate(context.Background(), s.dir, s.manifest)
+
+	// then:
+	s.Equal(ErrNoPackage, err)
+}
+
+func (s *otaSuite) TestGenerateWithoutApplication() {
+	// setup:
+	s.writePackage(map[string]string{"Payload/Other.txt": ""})
+
+	// when:
+	_, err := s.subject.Generate(context.Background(), s.dir, s.manifest)
+
+	// then:
+	s.Equal(ErrInvalidPackage, err)
+}
+
+func (s *otaSuite) TestGenerateWithFolderAppURL() {
+	// setup:
+	s.manifest.AppURL = "https://example.com/builds/42/"
+
+	// when:
+	_, err := s.subject.Generate(context.Background(), s.dir, s.manifest)
+
+	// then:
+	s.Equal(ErrInvalidAppURL, err)
+}
+
+func (s *otaSuite) TestReadManifest() {
+	// setup:
+	path := filepath.Join(s.dir, "exportOptions.plist")
+	b, err := plist.Marshal(api.ExportOptions{Method: "ad-hoc", Manifest: s.manifest}, plist.XMLFormat)
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(path, b, 0644))
+
+	// when:
+	m, err := ReadManifest(path)
+
+	// then:
+	s.NoError(err)
+	s.Equal(s.manifest, m)
+
+	// when: the export options without manifest
+	b, err = plist.Marshal(api.ExportOptions{Method: "ad-hoc"}, plist.XMLFormat)
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(path, b, 0644))
+	m, err = ReadManifest(path)
+
+	// then:
+	s.NoError(err)
+	s.Nil(m)
+}
@@ -0,0 +1,79 @@
+package ota
+
+import (
+	"dothething/internal/api"
+	"encoding/base64"
+	"html/template"
+	"net/url"
+	"os"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// pageTemplate the install page, the QR code being embedded to keep the folder self-contained
+var pageTemplate = template.Must(template.New("index.html").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>Install {{.Title}}</title>
+	<style>
+		body { font-family: -apple-system, Helvetica, Arial, sans-serif; text-align: center; color: #1d1d1f; margin: 3em 1em; }
+		img.icon { width: 114px; height: 114px; border-radius: 22%; }
+		a.install { display: inline-block; margin: 1.5em 0; padding: .8em 2em; border-radius: 2em; background: #0071e3; color: #fff; text-decoration: none; font-size: 1.2em; }
+		p.details { color: #6e6e73; }
+	</style>
+</head>
+<body>
+	{{if .DisplayImageURL}}<img class="icon" src="{{.DisplayImageURL}}" alt="">{{end}}
+	<h1>{{.Title}}</h1>
+	<p class="details">{{.BundleIdentifier}} &middot; {{.ShortVersion}} ({{.Version}})</p>
+	<a class="install" href="{{.InstallURL}}">Install</a>
+	<p>Or scan this code with the device camera</p>
+	<img class="qr" src="{{.QRCode}}" alt="{{.PageURL}}">
+</body>
+</html>
+`))
+
+// page the values of the install page
+type page struct {
+	BundleIdentifier string
+	DisplayImageURL  string
+	InstallURL       template.URL
+	PageURL          string
+	QRCode           template.URL
+	ShortVersion     string
+	Title            string
+	Version          string
+}
+
+// writePage renders the install page, the QR code pointing to the page itself to be opened
+// from the device
+func writePage(dst string, info appInfo, m api.ExportManifest, manifestURL, pageURL *url.URL) error {
+	png, err := qrcode.Encode(pageURL.String(), qrcode.Medium, 256)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return pageTemplate.Execute(f, page{
+		BundleIdentifier: info.BundleIdentifier,
+		DisplayImageURL:  m.DisplayImageURL,
+		InstallURL:       template.URL(installURL(manifestURL)),
+		PageURL:          pageURL.String(),
+		QRCode:           template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png)),
+		ShortVersion:     info.ShortVersion,
+		Title:            info.Title(),
+		Version:          info.Version,
+	})
+}
+
+// installURL the itms-services link triggering the installation of the manifest
+func installURL(manifestURL *url.URL) string {
+	return "itms-services://?action=download-manifest&url=" + url.QueryEscape(manifestURL.String())
+}
@@ -1,24 +1,41 @@
 package path
 
 import (
+	"crypto/rand"
 	"dothething/internal/api"
+	"encoding/hex"
 	"fmt"
 	"path/filepath"
 	"strings"
 )
 
 const (
-	BuildFolder      = "../Build/"
+	BuildFolder = "../Build/"
+	// KeyChainPattern matches the keychains of every run, the older ones not having a run
+	// identifier
+	KeyChainPattern  = "do-the-thing*.keychain"
 	projectFileExt   = ".xcodeproj"
 	workspaceFileExt = ".xcworkspace"
 )
 
 type pathService struct {
 	*api.API
+	// run identifies the run, for the concurrent runs of a build folder not to share their
+	// keychain
+	run string
 }
 
 func NewPathService(p *api.API) api.PathService {
-	return pathService{API: p}
+	return pathService{API: p, run: newRunID()}
+}
+
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
 }
 
 func (p pathService) buildFolder() string {
@@ -45,8 +62,20 @@ func (p pathService) ExportPList() string {
 	))
 }
 
+// ExportPListFor the export options plist of the distribution method
+func (p pathService) ExportPListFor(method string) string {
+	return filepath.Clean(filepath.Join(
+		p.buildFolder(),
+		fmt.Sprintf("%v-%v-%v-%v-export.plist",
+			p.API.Config.Target,
+			p.API.Config.Scheme,
+			p.API.Config.Configuration,
+			method),
+	))
+}
+
 func (p pathService) KeyChain() string {
-	res, err := filepath.Abs(filepath.Join(p.buildFolder(), "do-the-thing.keychain"))
+	res, err := filepath.Abs(filepath.Join(p.buildFolder(), fmt.Sprintf("do-the-thing-%v.keychain", p.run)))
 	if err != nil {
 		fmt.Printf("Error %v", err)
 	}
@@ -64,6 +93,36 @@ func (p pathService) Package() string {
 	))
 }
 
+// PackageFor the export folder of the distribution method
+func (p pathService) PackageFor(method string) string {
+	return filepath.Clean(filepath.Join(
+		p.buildFolder(),
+		fmt.Sprintf("%v-%v-%v-%v",
+			p.API.Config.Target,
+			p.API.Config.Scheme,
+			p.API.Config.Configuration,
+			method),
+	))
+}
+
+// RunReport the JSON report of the run, unique per run as the keychain
+func (p pathService) RunReport() string {
+	return filepath.Clean(filepath.Join(p.buildFolder(), fmt.Sprintf("do-the-thing-%v-report.json", p.run)))
+}
+
+// Symbols the zip of the debug symbols of the archived version
+func (p pathService) Symbols(version, build string) string {
+	return filepath.Clean(filepath.Join(
+		p.buildFolder(),
+		fmt.Sprintf("%v-%v-%v-%v-%v.dSYM.zip",
+			p.API.Config.Target,
+			p.API.Config.Scheme,
+			p.API.Config.Configuration,
+			version,
+			build),
+	))
+}
+
 func (p pathService) ObjRoot() string {
 	return fmt.Sprintf("OBJROOT=%v", filepath.Join(p.buildFolder(), "obj/"))
 }
@@ -27,7 +27,7 @@ func (s *pathServiceSuite) BeforeTest(suiteName, testName string) {
 			Target:        "targetName",
 		},
 	}
-	s.subject = pathService{s.API}
+	s.subject = pathService{API: s.API, run: "0123456789abcdef"}
 }
 
 func (s *pathServiceSuite) AfterTest(suiteName, testName string) {
@@ -54,7 +54,17 @@ func (s *pathServiceSuite) TestKeyChain() {
 	p := s.subject.KeyChain()
 
 	// then:
-	s.Assert().Equal("/path/to/Build/do-the-thing.keychain", p)
+	s.Assert().Equal("/path/to/Build/do-the-thing-0123456789abcdef.keychain", p)
+}
+
+func (s *pathServiceSuite) TestKeyChainIsUniquePerRun() {
+	// when:
+	a := NewPathService(s.API).KeyChain()
+	b := NewPathService(s.API).KeyChain()
+
+	// then:
+	s.Assert().NotEqual(a, b)
+	s.Assert().Regexp(`^/path/to/Build/do-the-thing-[0-9a-f]{16}\.keychain$`, a)
 }
 
 func (s *pathServiceSuite) TestExportPlist() {
@@ -65,6 +75,30 @@ func (s *pathServiceSuite) TestExportPlist() {
 	s.Assert().Equal("/path/to/Build/targetName-schemeName-configName-export.plist", p)
 }
 
+func (s *pathServiceSuite) TestExportPlistFor() {
+	// when:
+	p := s.subject.ExportPListFor("ad-hoc")
+
+	// then:
+	s.Assert().Equal("/path/to/Build/targetName-schemeName-configName-ad-hoc-export.plist", p)
+}
+
+func (s *pathServiceSuite) TestPackageFor() {
+	// when:
+	p := s.subject.PackageFor("app-store")
+
+	// then:
+	s.Assert().Equal("/path/to/Build/targetName-schemeName-configName-app-store", p)
+}
+
+func (s *pathServiceSuite) TestSymbols() {
+	// when:
+	p := s.subject.Symbols("1.2.0", "42")
+
+	// then:
+	s.Assert().Equal("/path/to/Build/targetName-schemeName-configName-1.2.0-42.dSYM.zip", p)
+}
+
 func (s *pathServiceSuite) TestObjRoot() {
 	// when:
 	p := s.subject.ObjRoot()
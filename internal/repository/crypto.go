@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	saltSize = 16
+	keySize  = 32
+)
+
+// magic identifies the files encrypted by the repository
+var magic = []byte("DTT1")
+
+var (
+	// ErrEmptyPassphrase the passphrase is required to encrypt or decrypt
+	ErrEmptyPassphrase = errors.New("The repository passphrase should not be empty")
+
+	// ErrInvalidPayload the payload was not encrypted by the repository
+	ErrInvalidPayload = errors.New("Invalid encrypted payload")
+
+	// ErrDecryption the payload could not be decrypted, most likely a wrong passphrase
+	ErrDecryption = errors.New("Failed to decrypt payload, check the repository passphrase")
+)
+
+// Encrypt seals the data with AES-GCM, the key being derived from the passphrase with scrypt.
+// The result is laid out as magic | salt | nonce | ciphertext
+func Encrypt(passphrase string, data []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(magic)
+	buf.Write(salt)
+	buf.Write(nonce)
+	buf.Write(gcm.Seal(nil, nonce, data, magic))
+
+	return buf.Bytes(), nil
+}
+
+// Decrypt opens a payload sealed by Encrypt
+func Decrypt(passphrase string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, magic) || len(data) < len(magic)+saltSize {
+		return nil, ErrInvalidPayload
+	}
+
+	data = data[len(magic):]
+	gcm, err := newGCM(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[saltSize:]
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrInvalidPayload
+	}
+
+	res, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], magic)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+
+	return res, nil
+}
+
+// newGCM derives the key from the passphrase and salt
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	// setup:
+	data := []byte("signing identity")
+
+	// when:
+	enc, err := Encrypt("passphrase", data)
+
+	// then:
+	assert.NoError(t, err)
+	assert.NotContains(t, string(enc), string(data))
+
+	// when:
+	dec, err := Decrypt("passphrase", enc)
+
+	// then:
+	assert.NoError(t, err)
+	assert.Equal(t, data, dec)
+}
+
+func TestDecryptionErrors(t *testing.T) {
+	// setup:
+	enc, err := Encrypt("passphrase", []byte("signing identity"))
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		passphrase string
+		data       []byte
+		err        error
+	}{
+		{name: "Wrong passphrase", passphrase: "wrong", data: enc, err: ErrDecryption},
+		{name: "Empty passphrase", passphrase: "", data: enc, err: ErrEmptyPassphrase},
+		{name: "Unknown payload", passphrase: "passphrase", data: []byte("plain"), err: ErrInvalidPayload},
+		{name: "Truncated payload", passphrase: "passphrase", data: enc[:20], err: ErrInvalidPayload},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// when:
+			res, err := Decrypt(c.passphrase, c.data)
+
+			// then:
+			assert.Equal(t, c.err, err)
+			assert.Nil(t, res)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"dothething/internal/api"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+const (
+	// ManifestFile name of the manifest file at the root of the repository
+	ManifestFile = "manifest.json"
+
+	manifestVersion = 1
+)
+
+// Manifest indexes the encrypted signing assets of the repository
+type Manifest struct {
+	Version int                   `json:"version"`
+	Entries []api.RepositoryEntry `json:"entries"`
+}
+
+// readManifest reads the manifest at path, a missing manifest being an empty repository
+func readManifest(path string) (Manifest, error) {
+	res := Manifest{Version: manifestVersion}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return res, nil
+	}
+
+	if err != nil {
+		return res, err
+	}
+
+	return res, json.Unmarshal(b, &res)
+}
+
+// write the manifest at path
+func (m Manifest) write(path string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// upsert adds the entry to the manifest, replacing the entry already stored in the same file
+func (m *Manifest) upsert(e api.RepositoryEntry) {
+	for i, c := range m.Entries {
+		if c.File == e.File {
+			m.Entries[i] = e
+			return
+		}
+	}
+
+	m.Entries = append(m.Entries, e)
+
+	// Keeping the manifest stable to ease the reviews of the repository changes
+	sort.SliceStable(m.Entries, func(i, j int) bool {
+		return m.Entries[i].File < m.Entries[j].File
+	})
+}
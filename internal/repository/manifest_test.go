@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"dothething/internal/api"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestUpsert(t *testing.T) {
+	// setup:
+	var m Manifest
+	dev := api.RepositoryEntry{File: "profiles/TEAM/development/1", Team: "TEAM", Type: "development", BundleIdentifier: "com.app"}
+	adhoc := api.RepositoryEntry{File: "profiles/TEAM/ad-hoc/2", Team: "TEAM", Type: "ad-hoc", BundleIdentifier: "com.app"}
+	other := api.RepositoryEntry{File: "profiles/OTHER/ad-hoc/3", Team: "OTHER", Type: "ad-hoc", BundleIdentifier: "com.other"}
+
+	// when:
+	m.upsert(dev)
+	m.upsert(other)
+	m.upsert(adhoc)
+
+	// then: the entries should be sorted by file
+	assert.Equal(t, []api.RepositoryEntry{other, adhoc, dev}, m.Entries)
+
+	// when: replacing an entry
+	dev.Name = "renamed"
+	m.upsert(dev)
+
+	// then:
+	assert.Equal(t, []api.RepositoryEntry{other, adhoc, dev}, m.Entries)
+}
+
+func TestManifestReadWrite(t *testing.T) {
+	// setup:
+	dir, err := ioutil.TempDir("", "manifest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ManifestFile)
+
+	// when: the manifest does not exist yet
+	m, err := readManifest(path)
+
+	// then:
+	assert.NoError(t, err)
+	assert.Empty(t, m.Entries)
+
+	// when:
+	m.upsert(api.RepositoryEntry{File: "certificates/TEAM/ABC.pem.enc", Kind: api.RepositoryCertificate})
+	assert.NoError(t, m.write(path))
+	res, err := readManifest(path)
+
+	// then:
+	assert.NoError(t, err)
+	assert.Equal(t, m, res)
+}
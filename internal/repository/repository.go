@@ -0,0 +1,331 @@
+// Package repository stores the signing assets encrypted into a directory or a git repository
+// shared between the build agents
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"dothething/internal/api"
+	"dothething/internal/signature"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	git          = "git"
+	encryptedExt = ".enc"
+)
+
+var (
+	// ErrNotConfigured no repository was configured
+	ErrNotConfigured = errors.New("No signing repository configured")
+
+	// ErrInvalidEntry the file of the manifest entry is outside of the repository
+	ErrInvalidEntry = errors.New("The signing repository entry is outside of the repository")
+
+	// ErrUnsupportedFile the file is neither a p12 certificate nor a provisioning profile
+	ErrUnsupportedFile = errors.New("Unsupported signing asset file")
+)
+
+// repository implements the SigningRepository interface
+type repository struct {
+	*api.API
+	sync *syncState
+}
+
+// syncState allows to fetch the repository only once per run
+type syncState struct {
+	once sync.Once
+	err  error
+}
+
+// NewSigningRepository create a new instance of the signing repository
+func NewSigningRepository(api *api.API) api.SigningRepository {
+	return repository{API: api, sync: new(syncState)}
+}
+
+func (r repository) config() api.RepositoryConfig {
+	return r.API.Config.CodeSignOption.Repository
+}
+
+// isRemote do the repository URL targets a git remote
+func isRemote(url string) bool {
+	return strings.Contains(url, "://") || strings.HasPrefix(url, "git@")
+}
+
+// dir resolves the local directory of the repository, remote repositories being cloned into
+// the user cache directory
+func (r repository) dir() (string, error) {
+	url := r.config().URL
+	if url == "" {
+		return "", ErrNotConfigured
+	}
+
+	if !isRemote(url) {
+		return filepath.Abs(url)
+	}
+
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum([]byte(url))
+	return filepath.Join(cache, "do-the-thing", "repositories", hex.EncodeToString(sum[:])), nil
+}
+
+// Sync fetches the latest state of the repository, only once per run
+func (r repository) Sync(ctx context.Context) error {
+	r.sync.once.Do(func() {
+		r.sync.err = r.fetch(ctx)
+	})
+
+	return r.sync.err
+}
+
+func (r repository) fetch(ctx context.Context) error {
+	dir, err := r.dir()
+	if err != nil {
+		return err
+	}
+
+	// Local repositories are managed by the user
+	if !isRemote(r.config().URL) {
+		return os.MkdirAll(dir, 0700)
+	}
+
+	// Already cloned, we only need to pull the latest changes
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		args := []string{"pull", "--ff-only"}
+		if b := r.config().Branch; b != "" {
+			args = append(args, "origin", b)
+		}
+
+		return r.git(ctx, dir, args...)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return err
+	}
+
+	args := []string{"clone", "--depth", "1"}
+	if b := r.config().Branch; b != "" {
+		args = append(args, "--branch", b)
+	}
+
+	return r.git(ctx, "", append(args, r.config().URL, dir)...)
+}
+
+// git runs the git command into the directory
+func (r repository) git(ctx context.Context, dir string, args ...string) error {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+
+	b, err := r.API.Exec.CommandContext(ctx, git, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %v failed: %v (%s)", args, err, bytes.TrimSpace(b))
+	}
+
+	return nil
+}
+
+// Entries returns the manifest entries of the repository
+func (r repository) Entries(ctx context.Context) ([]api.RepositoryEntry, error) {
+	if err := r.Sync(ctx); err != nil {
+		return nil, err
+	}
+
+	dir, err := r.dir()
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := readManifest(filepath.Join(dir, ManifestFile))
+	return m.Entries, err
+}
+
+// Open decrypts the content of the entry
+func (r repository) Open(ctx context.Context, e api.RepositoryEntry) ([]byte, error) {
+	dir, err := r.dir()
+	if err != nil {
+		return nil, err
+	}
+
+	// The manifest being shared, its entries must not read outside of the checkout
+	path := filepath.Join(dir, e.File)
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, ErrInvalidEntry
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decrypt(r.config().Passphrase, b)
+}
+
+// Import encrypts the p12 certificates and provisioning profiles into the repository and
+// publishes them when the repository is remote
+func (r repository) Import(ctx context.Context, paths []string) ([]api.RepositoryEntry, error) {
+	if r.config().Passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+
+	if err := r.Sync(ctx); err != nil {
+		return nil, err
+	}
+
+	dir, err := r.dir()
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := readManifest(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var res []api.RepositoryEntry
+	for _, p := range paths {
+		e, err := r.importFile(ctx, dir, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %v (%v)", p, err)
+		}
+
+		log.Info().
+			Str("Path", p).
+			Str("File", e.File).
+			Msg("Imported signing asset")
+
+		m.upsert(e)
+		res = append(res, e)
+	}
+
+	if err := m.write(filepath.Join(dir, ManifestFile)); err != nil {
+		return nil, err
+	}
+
+	if isRemote(r.config().URL) {
+		if err := r.publish(ctx, dir, len(res)); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// importFile encrypts the file into the repository and returns its manifest entry
+func (r repository) importFile(ctx context.Context, dir, path string) (api.RepositoryEntry, error) {
+	var e api.RepositoryEntry
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return e, err
+	}
+
+	var content []byte
+	switch filepath.Ext(path) {
+	case ".p12":
+		e, content, err = r.certificateEntry(path, data)
+	case ".mobileprovision":
+		e, content, err = r.profileEntry(ctx, data)
+	default:
+		err = ErrUnsupportedFile
+	}
+
+	if err != nil {
+		return e, err
+	}
+
+	enc, err := Encrypt(r.config().Passphrase, content)
+	if err != nil {
+		return e, err
+	}
+
+	dst := filepath.Join(dir, e.File)
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return e, err
+	}
+
+	return e, ioutil.WriteFile(dst, enc, 0600)
+}
+
+// certificateEntry decodes the p12 file with the passwords of the certificates, the identity
+// being stored as a PEM sequence so that the p12 password is not required anymore
+func (r repository) certificateEntry(path string, data []byte) (api.RepositoryEntry, []byte, error) {
+	c, err := signature.DecodeCertificateFile(r.API, path, data)
+	if err != nil {
+		return api.RepositoryEntry{}, nil, err
+	}
+
+	content, err := signature.EncodeIdentity(c.Certificate, c.PrivateKey)
+	if err != nil {
+		return api.RepositoryEntry{}, nil, err
+	}
+
+	team := "unknown"
+	if len(c.Subject.OrganizationalUnit) > 0 {
+		team = c.Subject.OrganizationalUnit[0]
+	}
+
+	sum := sha1.Sum(c.Raw)
+	fingerprint := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	return api.RepositoryEntry{
+		Expiration:  c.NotAfter,
+		File:        filepath.Join("certificates", team, fingerprint+".pem"+encryptedExt),
+		Fingerprint: fingerprint,
+		Kind:        api.RepositoryCertificate,
+		Name:        c.Subject.CommonName,
+		Team:        team,
+		Type:        string(c.Type),
+	}, content, nil
+}
+
+// profileEntry decodes the provisioning profile
+func (r repository) profileEntry(ctx context.Context, data []byte) (api.RepositoryEntry, []byte, error) {
+	pp, err := r.API.ProvisioningService.Decode(ctx, bytes.NewReader(data))
+	if err != nil {
+		return api.RepositoryEntry{}, nil, err
+	}
+
+	method := signature.ProvisioningMethod(&pp)
+	team := pp.Entitlements.TeamID
+
+	return api.RepositoryEntry{
+		BundleIdentifier: pp.BundleIdentifier,
+		Expiration:       pp.ExpirationDate,
+		File:             filepath.Join("profiles", team, method, pp.UUID+".mobileprovision"+encryptedExt),
+		Kind:             api.RepositoryProfile,
+		Name:             pp.Name,
+		Team:             team,
+		Type:             method,
+		UUID:             pp.UUID,
+	}, data, nil
+}
+
+// publish commits and pushes the imported assets
+func (r repository) publish(ctx context.Context, dir string, count int) error {
+	if err := r.git(ctx, dir, "add", "-A"); err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Import %v signing asset(s)", count)
+	if err := r.git(ctx, dir, "commit", "-m", msg); err != nil {
+		return err
+	}
+
+	return r.git(ctx, dir, "push")
+}
@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/signature"
+	"dothething/internal/utiltest"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const remote = "git@example.com:signing.git"
+
+type repositorySuite struct {
+	suite.Suite
+	API     *api.API
+	dir     string
+	env     map[string]string
+	exec    *utiltest.MockExecutor
+	subject repository
+}
+
+func TestRepositorySuite(t *testing.T) {
+	suite.Run(t, new(repositorySuite))
+}
+
+func (s *repositorySuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "repository")
+	s.Require().NoError(err)
+	s.dir = dir
+
+	// The remote repositories being cloned into the user cache folder
+	s.env = map[string]string{}
+	for _, k := range []string{"HOME", "XDG_CACHE_HOME"} {
+		s.env[k] = os.Getenv(k)
+		os.Setenv(k, filepath.Join(dir, "home"))
+	}
+
+	s.exec = new(utiltest.MockExecutor)
+	s.API = &api.API{Config: &api.Config{}, Exec: s.exec}
+	s.API.Config.CodeSignOption.CertificatePassword = "p4ssword"
+	s.API.Config.CodeSignOption.Repository = api.RepositoryConfig{
+		Passphrase: "passphrase",
+		URL:        filepath.Join(dir, "signing"),
+	}
+	s.API.CertificateService = signature.NewCertificateService(s.API)
+	s.API.ProvisioningService = fakeProvisioningService{}
+	s.subject = NewSigningRepository(s.API).(repository)
+}
+
+func (s *repositorySuite) TearDownTest() {
+	for k, v := range s.env {
+		os.Setenv(k, v)
+	}
+	os.RemoveAll(s.dir)
+}
+
+// fakeProvisioningService decodes every file as the same app store profile
+type fakeProvisioningService struct{}
+
+func (fakeProvisioningService) Cleanup() error { return nil }
+func (fakeProvisioningService) Decode(ctx context.Context, r io.Reader) (api.ProvisioningProfile, error) {
+	p := api.ProvisioningProfile{
+		BundleIdentifier: "com.dummy.app",
+		ExpirationDate:   time.Now().Add(time.Hour),
+		Name:             "Dummy App Store",
+		UUID:             "B5C2906D",
+	}
+	p.Entitlements.TeamID = "12345ABCDE"
+
+	return p, nil
+}
+func (fakeProvisioningService) ResolveProvisioningFilesInFolder(ctx context.Context, root string) []*api.ProvisioningProfile {
+	return nil
+}
+func (fakeProvisioningService) Install(p *api.ProvisioningProfile) error { return nil }
+
+// mockGit expects the git command
+func (s *repositorySuite) mockGit(err error, args ...string) {
+	cmd := new(utiltest.MockExecutorCmd)
+	cmd.On("CombinedOutput").Return("output", err)
+
+	s.exec.On("CommandContext", mock.Anything, git, args).Return(cmd).Once()
+}
+
+// remote configures a remote repository, already cloned when the clone is true
+func (s *repositorySuite) remote(cloned bool) string {
+	s.API.Config.CodeSignOption.Repository.URL = remote
+	s.API.Config.CodeSignOption.Repository.Branch = "main"
+
+	dir, err := s.subject.dir()
+	s.Require().NoError(err)
+	s.Require().True(strings.HasPrefix(dir, s.dir))
+
+	if cloned {
+		s.Require().NoError(os.MkdirAll(filepath.Join(dir, ".git"), 0700))
+	}
+
+	return dir
+}
+
+// writeAssets writes the p12 certificate and a provisioning profile to import
+func (s *repositorySuite) writeAssets() (string, string) {
+	data, err := ioutil.ReadFile("../../assets/Certificate.p12")
+	s.Require().NoError(err)
+
+	cert := filepath.Join(s.dir, "Certificate.p12")
+	s.Require().NoError(ioutil.WriteFile(cert, data, 0600))
+
+	profile := filepath.Join(s.dir, "Dummy.mobileprovision")
+	s.Require().NoError(ioutil.WriteFile(profile, []byte("profile"), 0600))
+
+	return cert, profile
+}
+
+func (s *repositorySuite) TestSyncClonesOnce() {
+	// setup:
+	dir := s.remote(false)
+	s.mockGit(nil, "clone", "--depth", "1", "--branch", "main", remote, dir)
+
+	// when:
+	err := s.subject.Sync(context.Background())
+	s.Require().NoError(err)
+	err = s.subject.Sync(context.Background())
+
+	// then:
+	s.NoError(err)
+	s.exec.AssertExpectations(s.T())
+	s.exec.AssertNumberOfCalls(s.T(), "CommandContext", 1)
+}
+
+func (s *repositorySuite) TestSyncPullsTheClone() {
+	// setup:
+	dir := s.remote(true)
+	s.mockGit(nil, "-C", dir, "pull", "--ff-only", "origin", "main")
+
+	// when:
+	err := s.subject.Sync(context.Background())
+
+	// then:
+	s.NoError(err)
+	s.exec.AssertExpectations(s.T())
+}
+
+func (s *repositorySuite) TestSyncFailure() {
+	// setup:
+	dir := s.remote(false)
+	s.mockGit(errors.New("exit status 128"), "clone", "--depth", "1", "--branch", "main", remote, dir)
+
+	// when:
+	err := s.subject.Sync(context.Background())
+
+	// then:
+	s.EqualError(err, "git [clone --depth 1 --branch main "+remote+" "+dir+"] failed: exit status 128 (output)")
+}
+
+func (s *repositorySuite) TestImportAndOpenLocal() {
+	// setup:
+	cert, profile := s.writeAssets()
+
+	// when:
+	res, err := s.subject.Import(context.Background(), []string{cert, profile})
+
+	// then: the assets are indexed by the manifest, without any git command
+	s.Require().NoError(err)
+	s.Require().Len(res, 2)
+	s.Equal(api.RepositoryCertificate, res[0].Kind)
+	s.Equal(
+		api.RepositoryEntry{
+			BundleIdentifier: "com.dummy.app",
+			Expiration:       res[1].Expiration,
+			File:             filepath.Join("profiles", "12345ABCDE", "app-store", "B5C2906D.mobileprovision"+encryptedExt),
+			Kind:             api.RepositoryProfile,
+			Name:             "Dummy App Store",
+			Team:             "12345ABCDE",
+			Type:             "app-store",
+			UUID:             "B5C2906D",
+		},
+		res[1],
+	)
+	s.exec.AssertNotCalled(s.T(), "CommandContext", mock.Anything, mock.Anything, mock.Anything)
+
+	entries, err := s.subject.Entries(context.Background())
+	s.Require().NoError(err)
+	s.Len(entries, 2)
+
+	// when:
+	b, err := s.subject.Open(context.Background(), res[1])
+
+	// then: the content is encrypted at rest only
+	s.NoError(err)
+	s.Equal("profile", string(b))
+
+	stored, err := ioutil.ReadFile(filepath.Join(s.API.Config.CodeSignOption.Repository.URL, res[1].File))
+	s.Require().NoError(err)
+	s.NotContains(string(stored), "profile")
+
+	// when: the identity, stored without its p12 password
+	b, err = s.subject.Open(context.Background(), res[0])
+
+	// then:
+	s.NoError(err)
+	s.Contains(string(b), "-----BEGIN CERTIFICATE-----")
+}
+
+func (s *repositorySuite) TestImportRemotePublishes() {
+	// setup:
+	_, profile := s.writeAssets()
+	dir := s.remote(true)
+	s.mockGit(nil, "-C", dir, "pull", "--ff-only", "origin", "main")
+	s.mockGit(nil, "-C", dir, "add", "-A")
+	s.mockGit(nil, "-C", dir, "commit", "-m", "Import 1 signing asset(s)")
+	s.mockGit(nil, "-C", dir, "push")
+
+	// when:
+	res, err := s.subject.Import(context.Background(), []string{profile})
+
+	// then:
+	s.NoError(err)
+	s.Len(res, 1)
+	s.FileExists(filepath.Join(dir, ManifestFile))
+	s.exec.AssertExpectations(s.T())
+}
+
+func (s *repositorySuite) TestImportFailures() {
+	// setup:
+	_, profile := s.writeAssets()
+	unsupported := filepath.Join(s.dir, "notes.txt")
+	s.Require().NoError(ioutil.WriteFile(unsupported, []byte("notes"), 0600))
+
+	// when:
+	_, err := s.subject.Import(context.Background(), []string{profile, unsupported})
+
+	// then: nothing is written
+	s.EqualError(err, "failed to import "+unsupported+" ("+ErrUnsupportedFile.Error()+")")
+	s.NoFileExists(filepath.Join(s.API.Config.CodeSignOption.Repository.URL, ManifestFile))
+
+	// when:
+	s.API.Config.CodeSignOption.Repository.Passphrase = ""
+	_, err = s.subject.Import(context.Background(), []string{profile})
+
+	// then:
+	s.Equal(ErrEmptyPassphrase, err)
+}
+
+func (s *repositorySuite) TestOpenOutsideTheRepository() {
+	// setup: a file next to the repository
+	secret := filepath.Join(s.dir, "secret")
+	s.Require().NoError(ioutil.WriteFile(secret, []byte("secret"), 0600))
+
+	// when:
+	_, err := s.subject.Open(context.Background(), api.RepositoryEntry{File: filepath.Join("..", "secret")})
+
+	// then:
+	s.Equal(ErrInvalidEntry, err)
+}
+
+func (s *repositorySuite) TestImportWithPasswordOfTheFile() {
+	// setup: the password mapped to the p12 file name only
+	cert, _ := s.writeAssets()
+	s.API.Config.CodeSignOption.CertificatePassword = ""
+	os.Setenv(signature.EnvCertificatePasswordPrefix+"CERTIFICATE_P12", "p4ssword")
+	defer os.Unsetenv(signature.EnvCertificatePasswordPrefix + "CERTIFICATE_P12")
+
+	// when:
+	res, err := s.subject.Import(context.Background(), []string{cert})
+
+	// then:
+	s.NoError(err)
+	s.Require().Len(res, 1)
+	s.Equal(api.RepositoryCertificate, res[0].Kind)
+}
@@ -1,9 +1,14 @@
 package signature
 
 import (
+	"bytes"
 	"context"
+	"crypto"
+	"crypto/sha1"
 	"crypto/x509"
 	"dothething/internal/api"
+	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
@@ -11,15 +16,40 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/rs/zerolog/log"
 	"golang.org/x/crypto/pkcs12"
 )
 
 var (
-	ErrorFailedToReadFile   = errors.New("Failed to read file")
-	ErrorFailedToDecryptPEM = errors.New("Failed to decrypt PEM")
+	ErrorFailedToReadFile        = errors.New("Failed to read file")
+	ErrorFailedToDecryptPEM      = errors.New("Failed to decrypt PEM")
+	ErrorMissingCertificate      = errors.New("No certificate found")
+	ErrorMissingPrivateKey       = errors.New("No private key found")
+	ErrorPrivateKeyMismatch      = errors.New("The private key does not match the certificate")
+	ErrorCertificateExpired      = errors.New("The certificate has expired")
+	ErrorCertificateNotYetValid  = errors.New("The certificate is not yet valid")
+	ErrorMissingCodeSigningUsage = errors.New("The certificate is not allowed for code signing")
+	ErrorIdentityNotFound        = errors.New(
+		"The identity is not a valid signing identity of the keychain, its Apple WWDR intermediate certificate may be missing",
+	)
 )
 
+// identityPrefixes maps the certificate common name prefixes to their identity type
+var identityPrefixes = []struct {
+	prefix string
+	kind   api.IdentityType
+}{
+	{prefix: "Apple Development", kind: api.IdentityAppleDevelopment},
+	{prefix: "Apple Distribution", kind: api.IdentityAppleDistribution},
+	{prefix: "Developer ID", kind: api.IdentityDeveloperID},
+	{prefix: "iPhone Developer", kind: api.IdentityIPhoneDeveloper},
+	{prefix: "iOS Development", kind: api.IdentityIPhoneDeveloper},
+	{prefix: "iPhone Distribution", kind: api.IdentityIPhoneDistribution},
+	{prefix: "iOS Distribution", kind: api.IdentityIPhoneDistribution},
+}
+
 // CertificateService service interface definition
 type certService struct {
 	*api.API
@@ -46,19 +76,270 @@ func (xs certService) DecodeCertificate(r io.Reader, password string) (api.P12Ce
 		return result, ErrorFailedToDecryptPEM
 	}
 
-	// We iterate on all blocks
-	for _, key := range blocks {
-		// We check for certificate type
-		if key.Type == "CERTIFICATE" {
-			// And we parse the single certificate
-			if cert, err := x509.ParseCertificate(key.Bytes); err == nil {
-				result = api.P12Certificate{Certificate: cert}
-				break
+	// We split the blocks between certificates and private keys
+	certs, keys := parseBlocks(blocks)
+	if len(certs) == 0 {
+		return result, ErrorMissingCertificate
+	}
+
+	if len(keys) == 0 {
+		return result, ErrorMissingPrivateKey
+	}
+
+	// And we look for the certificate owning one of the private keys
+	cert, key := matchKeyPair(certs, keys)
+	if cert == nil {
+		return result, ErrorPrivateKeyMismatch
+	}
+
+	result = api.P12Certificate{
+		Certificate: cert,
+		PrivateKey:  key,
+		Type:        identityType(cert),
+	}
+
+	// Finally we check that the identity can be used to sign
+	return result, checkCertificate(cert, time.Now())
+}
+
+// decodeKeyPair decode a certificate and its private key, both PEM or DER encoded, into a
+// P12Certificate holding the PEM sequence to import into the keychain
+func decodeKeyPair(certData, keyData []byte, password string) (api.P12Certificate, error) {
+	var result api.P12Certificate
+
+	cert, err := parseCertificateData(certData)
+	if err != nil {
+		return result, ErrorMissingCertificate
+	}
+
+	key, err := parsePrivateKeyData(keyData, password)
+	if err != nil {
+		return result, ErrorMissingPrivateKey
+	}
+
+	if c, _ := matchKeyPair([]*x509.Certificate{cert}, []crypto.PrivateKey{key}); c == nil {
+		return result, ErrorPrivateKeyMismatch
+	}
+
+	return newPEMIdentity(cert, key)
+}
+
+// decodePEMIdentity decode a PEM sequence holding a certificate and its private key
+func decodePEMIdentity(data []byte) (api.P12Certificate, error) {
+	var blocks []*pem.Block
+	for b, rest := pem.Decode(data); b != nil; b, rest = pem.Decode(rest) {
+		blocks = append(blocks, b)
+	}
+
+	certs, keys := parseBlocks(blocks)
+	if len(certs) == 0 {
+		return api.P12Certificate{}, ErrorMissingCertificate
+	}
+
+	if len(keys) == 0 {
+		return api.P12Certificate{}, ErrorMissingPrivateKey
+	}
+
+	cert, key := matchKeyPair(certs, keys)
+	if cert == nil {
+		return api.P12Certificate{}, ErrorPrivateKeyMismatch
+	}
+
+	return newPEMIdentity(cert, key)
+}
+
+// newPEMIdentity creates the identity to be imported into the keychain as a PEM sequence
+func newPEMIdentity(cert *x509.Certificate, key crypto.PrivateKey) (api.P12Certificate, error) {
+	var result api.P12Certificate
+
+	content, err := EncodeIdentity(cert, key)
+	if err != nil {
+		return result, ErrorMissingPrivateKey
+	}
+
+	result = api.P12Certificate{
+		Certificate: cert,
+		Content:     content,
+		PrivateKey:  key,
+		Type:        identityType(cert),
+	}
+
+	return result, checkCertificate(cert, time.Now())
+}
+
+// EncodeIdentity encodes the certificate and its private key as a PEM sequence, the format
+// expected by the keychain when both are imported at once
+func EncodeIdentity(cert *x509.Certificate, key crypto.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var content bytes.Buffer
+	if err := pem.Encode(&content, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+		return nil, err
+	}
+
+	if err := pem.Encode(&content, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		return nil, err
+	}
+
+	return content.Bytes(), nil
+}
+
+// parseCertificateData parse a PEM or DER encoded certificate
+func parseCertificateData(data []byte) (*x509.Certificate, error) {
+	if b, _ := pem.Decode(data); b != nil {
+		data = b.Bytes
+	}
+
+	return x509.ParseCertificate(data)
+}
+
+// parsePrivateKeyData parse a PEM or DER encoded private key, decrypting legacy encrypted
+// PEM blocks with the provided password
+func parsePrivateKeyData(data []byte, password string) (crypto.PrivateKey, error) {
+	b, _ := pem.Decode(data)
+	if b == nil {
+		return parsePrivateKey(data)
+	}
+
+	der := b.Bytes
+	// Legacy encrypted keys are still exported by some tools
+	if x509.IsEncryptedPEMBlock(b) {
+		d, err := x509.DecryptPEMBlock(b, []byte(password))
+		if err != nil {
+			return nil, err
+		}
+		der = d
+	}
+
+	return parsePrivateKey(der)
+}
+
+// parseBlocks parse the PEM blocks into the certificates and private keys they are holding
+func parseBlocks(blocks []*pem.Block) ([]*x509.Certificate, []crypto.PrivateKey) {
+	var certs []*x509.Certificate
+	var keys []crypto.PrivateKey
+
+	for _, b := range blocks {
+		switch b.Type {
+		case "CERTIFICATE":
+			if cert, err := x509.ParseCertificate(b.Bytes); err == nil {
+				certs = append(certs, cert)
+			}
+
+		case "PRIVATE KEY":
+			if key, err := parsePrivateKey(b.Bytes); err == nil {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return certs, keys
+}
+
+// parsePrivateKey parse the private key, the pkcs12 API encoding RSA keys as PKCS1
+// and ECDSA keys as SEC1
+func parsePrivateKey(der []byte) (crypto.PrivateKey, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	return x509.ParsePKCS8PrivateKey(der)
+}
+
+// matchKeyPair find the first certificate whose public key matches one of the private keys
+func matchKeyPair(certs []*x509.Certificate, keys []crypto.PrivateKey) (*x509.Certificate, crypto.PrivateKey) {
+	for _, c := range certs {
+		pub, err := x509.MarshalPKIXPublicKey(c.PublicKey)
+		if err != nil {
+			continue
+		}
+
+		for _, k := range keys {
+			if bytes.Equal(pub, marshalPublicKey(k)) {
+				return c, k
 			}
 		}
 	}
 
-	return result, nil
+	return nil, nil
+}
+
+// marshalPublicKey returns the PKIX encoding of the public part of the private key
+func marshalPublicKey(key crypto.PrivateKey) []byte {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil
+	}
+
+	b, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil
+	}
+
+	return b
+}
+
+// checkCertificate validates the certificate validity dates and its code signing usage
+func checkCertificate(c *x509.Certificate, now time.Time) error {
+	if now.Before(c.NotBefore) {
+		return ErrorCertificateNotYetValid
+	}
+
+	if now.After(c.NotAfter) {
+		return ErrorCertificateExpired
+	}
+
+	for _, u := range c.ExtKeyUsage {
+		if u == x509.ExtKeyUsageCodeSigning || u == x509.ExtKeyUsageAny {
+			return nil
+		}
+	}
+
+	return ErrorMissingCodeSigningUsage
+}
+
+// checkIdentity ensures the certificate is one of the valid signing identities of the
+// keychain, the missing intermediates being the usual reason it is not
+func checkIdentity(identities []api.SigningIdentity, c *x509.Certificate) error {
+	sum := sha1.Sum(c.Raw)
+	fingerprint := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	for _, i := range identities {
+		if i.SHA1 != fingerprint {
+			continue
+		}
+
+		if !i.Valid() {
+			return fmt.Errorf("the identity \"%v\" (%v) is not valid: %v", i.Name, i.SHA1, i.Reason)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: \"%v\" (%v)", ErrorIdentityNotFound, c.Subject.CommonName, fingerprint)
+}
+
+// identityType classify the certificate from its subject common name
+func identityType(c *x509.Certificate) api.IdentityType {
+	for _, p := range identityPrefixes {
+		if strings.HasPrefix(c.Subject.CommonName, p.prefix) {
+			return p.kind
+		}
+	}
+
+	return api.IdentityUnknown
+}
+
+// expiresWithin check if the certificate will expire in the provided window
+func expiresWithin(c *x509.Certificate, now time.Time, window time.Duration) bool {
+	return window > 0 && c.NotAfter.Before(now.Add(window))
 }
 
 // readFile is a convenient helper to read the content of a reader and returns it's content or an error
@@ -78,36 +359,80 @@ func isCertificateFile(info os.FileInfo) bool {
 		strings.HasSuffix(info.Name(), ".p12") // And has the right extension
 }
 
-func (xs certService) readCertificateFile(path string) (*api.P12Certificate, error) {
-	// Open the file content to a reader
-	f, err := xs.API.FileService.Open(path)
+func (xs certService) readCertificateFile(path string, passwords passwordMap) (*api.P12Certificate, error) {
+	// Read the file content
+	data, err := readSourceFile(xs.API.FileService, path)
 	if err != nil {
 		return nil, err
 	}
 
-	// Defer close the file
-	defer f.Close()
-
-	// Decodes it (temp test password)
-	cert, err := xs.DecodeCertificate(f, xs.Config.CodeSignOption.CertificatePassword)
+	// Decodes it, trying the passwords mapped to the file
+	cert, err := decodeWithCandidates(
+		xs,
+		data,
+		passwords.candidates(path, xs.Config.CodeSignOption.CertificatePassword),
+	)
 	if err != nil {
 		return nil, err
 	}
 
 	cert.FilePath = path
 
+	// Warn early about the identities about to expire
+	if expiresWithin(cert.Certificate, time.Now(), xs.Config.CodeSignOption.CertificateExpiryWarning) {
+		log.Warn().
+			Str("Path", path).
+			Str("Identity", cert.Subject.CommonName).
+			Time("Expiration", cert.NotAfter).
+			Msg("Certificate is about to expire")
+	}
+
 	return &cert, nil
 }
 
-func (xs certService) worker(wg *sync.WaitGroup, paths <-chan string, out *[]*api.P12Certificate) {
+// passwords loads the certificate password map, falling back on the default password only
+func (xs certService) passwords() passwordMap {
+	m, err := loadPasswordMap(xs.API.FileService, xs.Config.CodeSignOption.CertificatePasswords, os.Environ())
+	if err != nil {
+		log.Error().
+			AnErr("Error", err).
+			Str("Path", xs.Config.CodeSignOption.CertificatePasswords).
+			Msg("Failed to load the certificate passwords")
+		return passwordMap{}
+	}
+
+	return m
+}
+
+func (xs certService) worker(
+	wg *sync.WaitGroup,
+	mu *sync.Mutex,
+	paths <-chan string,
+	passwords passwordMap,
+	out *[]*api.P12Certificate,
+) {
 	wg.Add(1)
 	for value := range paths {
-		c, err := xs.readCertificateFile(value)
+		c, err := xs.readCertificateFile(value, passwords)
+		if err == ErrorFailedToDecryptPEM {
+			log.Error().
+				Str("Path", value).
+				Int("Passwords", len(passwords.candidates(value, xs.Config.CodeSignOption.CertificatePassword))).
+				Msg("Failed to decrypt certificate, none of the passwords matched")
+			continue
+		}
+
 		if err != nil {
-			fmt.Println("error", err)
+			log.Warn().
+				Str("Path", value).
+				AnErr("Reason", err).
+				Msg("Skipping invalid signing identity")
+			continue
 		}
 
+		mu.Lock()
 		*out = append(*out, c)
+		mu.Unlock()
 	}
 	wg.Done()
 }
@@ -116,15 +441,17 @@ func (xs certService) worker(wg *sync.WaitGroup, paths <-chan string, out *[]*ap
 func (xs certService) ResolveInFolder(ctx context.Context, root string) []*api.P12Certificate {
 	var res []*api.P12Certificate
 	var wg sync.WaitGroup
+	var mu sync.Mutex
+	passwords := xs.passwords()
 	// Increment waitgroup counter and create go routines
 	paths := make(chan string)
 	for i := 0; i < 8; i++ {
-		go xs.worker(&wg, paths, &res)
+		go xs.worker(&wg, &mu, paths, passwords, &res)
 	}
 
 	err := xs.API.FileService.Walk(ctx, root, isCertificateFile, paths, &wg)
 	if err != nil {
-		fmt.Println("err", err)
+		log.Error().AnErr("Error", err).Str("Path", root).Msg("Failed to walk the certificates folder")
 	}
 
 	wg.Wait()
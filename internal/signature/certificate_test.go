@@ -1,11 +1,15 @@
 package signature
 
 import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"dothething/internal/api"
 	"dothething/internal/utiltest"
 	"errors"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -82,6 +86,10 @@ func TestDecoding(t *testing.T) {
 		assert.Equal(t,
 			"CN=iPhone Distribution: Dummy Name Ltd (12345ABCDE),OU=SELFSIGNED,C=GB",
 			b.Issuer.String())
+
+		// and: the matching private key should have been resolved
+		assert.NotNil(t, b.PrivateKey)
+		assert.Equal(t, api.IdentityIPhoneDistribution, b.Type)
 	})
 
 	t.Run("Reading failure should be reported", func(t *testing.T) {
@@ -132,6 +140,150 @@ func TestFailureToReadFileShouldBeReporter(t *testing.T) {
 	})
 }
 
+func TestCheckCertificate(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		cert x509.Certificate
+		err  error
+	}{
+		{
+			name: "Valid code signing certificate",
+			cert: x509.Certificate{
+				NotBefore:   now.Add(-time.Hour),
+				NotAfter:    now.Add(time.Hour),
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			},
+		},
+		{
+			name: "Any usage should be accepted",
+			cert: x509.Certificate{
+				NotBefore:   now.Add(-time.Hour),
+				NotAfter:    now.Add(time.Hour),
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+			},
+		},
+		{
+			name: "Expired certificate",
+			cert: x509.Certificate{
+				NotBefore:   now.Add(-2 * time.Hour),
+				NotAfter:    now.Add(-time.Hour),
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			},
+			err: ErrorCertificateExpired,
+		},
+		{
+			name: "Not yet valid certificate",
+			cert: x509.Certificate{
+				NotBefore:   now.Add(time.Hour),
+				NotAfter:    now.Add(2 * time.Hour),
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			},
+			err: ErrorCertificateNotYetValid,
+		},
+		{
+			name: "Missing code signing usage",
+			cert: x509.Certificate{
+				NotBefore:   now.Add(-time.Hour),
+				NotAfter:    now.Add(time.Hour),
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+			},
+			err: ErrorMissingCodeSigningUsage,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// when:
+			err := checkCertificate(&c.cert, now)
+
+			// then:
+			assert.Equal(t, c.err, err)
+		})
+	}
+}
+
+func TestCheckIdentity(t *testing.T) {
+	// setup: the SHA-1 of the raw content
+	c := &x509.Certificate{Raw: []byte("certificate"), Subject: pkix.Name{CommonName: "Apple Distribution: Dummy"}}
+	fingerprint := "735AD571C189D7BA84464BF4A9F1D2280175B128"
+	cases := []struct {
+		name       string
+		identities []api.SigningIdentity
+		err        string
+	}{
+		{
+			name:       "Valid identity",
+			identities: []api.SigningIdentity{{SHA1: "0123"}, {SHA1: fingerprint}},
+		},
+		{
+			name:       "Untrusted identity",
+			identities: []api.SigningIdentity{{Name: "Dummy", SHA1: fingerprint, Reason: "CSSMERR_TP_NOT_TRUSTED"}},
+			err:        "the identity \"Dummy\" (" + fingerprint + ") is not valid: CSSMERR_TP_NOT_TRUSTED",
+		},
+		{
+			name: "Missing identity",
+			err:  ErrorIdentityNotFound.Error() + ": \"Apple Distribution: Dummy\" (" + fingerprint + ")",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when:
+			err := checkIdentity(tc.identities, c)
+
+			// then:
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+
+	// when:
+	err := checkIdentity(nil, c)
+
+	// then:
+	assert.True(t, errors.Is(err, ErrorIdentityNotFound))
+}
+
+func TestIdentityType(t *testing.T) {
+	cases := []struct {
+		cn   string
+		kind api.IdentityType
+	}{
+		{cn: "Apple Development: John Doe (ABCDE12345)", kind: api.IdentityAppleDevelopment},
+		{cn: "Apple Distribution: Dummy Ltd (ABCDE12345)", kind: api.IdentityAppleDistribution},
+		{cn: "Developer ID Application: Dummy Ltd (ABCDE12345)", kind: api.IdentityDeveloperID},
+		{cn: "iPhone Developer: John Doe (ABCDE12345)", kind: api.IdentityIPhoneDeveloper},
+		{cn: "iOS Development: Self Signer", kind: api.IdentityIPhoneDeveloper},
+		{cn: "iPhone Distribution: Dummy Ltd (ABCDE12345)", kind: api.IdentityIPhoneDistribution},
+		{cn: "Mac Installer", kind: api.IdentityUnknown},
+	}
+
+	for _, c := range cases {
+		t.Run(c.cn, func(t *testing.T) {
+			// setup:
+			cert := x509.Certificate{Subject: pkix.Name{CommonName: c.cn}}
+
+			// expect:
+			assert.Equal(t, c.kind, identityType(&cert))
+		})
+	}
+}
+
+func TestExpiresWithin(t *testing.T) {
+	// setup:
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert := x509.Certificate{NotAfter: now.Add(10 * 24 * time.Hour)}
+
+	// expect:
+	assert.True(t, expiresWithin(&cert, now, 30*24*time.Hour))
+	assert.False(t, expiresWithin(&cert, now, 5*24*time.Hour))
+	assert.False(t, expiresWithin(&cert, now, 0))
+}
+
 type errReader int
 
 func (errReader) Read(p []byte) (n int, err error) {
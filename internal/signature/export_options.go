@@ -42,6 +42,18 @@ func (s exportOptionsService) Compute() error {
 	// resoling the target configuration
 	s.cfg = s.API.SignatureService.GetConfiguration()
 
+	return s.compute(s.API.PathService.ExportPList())
+}
+
+// ComputeForMethod computes the export options of the distribution method from its signature
+// configuration, to the export plist of the method
+func (s exportOptionsService) ComputeForMethod(method string, cfg []api.TargetSignatureConfig) error {
+	s.cfg = &cfg
+
+	return s.compute(s.API.PathService.ExportPListFor(method))
+}
+
+func (s exportOptionsService) compute(dst string) error {
 	// create basic unpopulated export options object
 	res, err := s.createExportOptions()
 	if err != nil {
@@ -53,32 +65,109 @@ func (s exportOptionsService) Compute() error {
 		return NewSignatureError(err, ErrorExportOptions)
 	}
 
-	// enabled by default for AppStore signing method
+	// enabled by default for AppStore signing method, unless the base file tells otherwise
+	defaults := &api.ExportOptions{}
 	if res.Method == "app-store" {
-		res.UploadBitCode = true
-		res.UploadSymbols = true
-	} else {
-		// TODO: configurable via CLI
+		defaults.UploadBitcode = newBool(true)
+		defaults.UploadSymbols = newBool(true)
 	}
 
-	// encoding the struct to plist format
-	var buf bytes.Buffer
-	encoder := plist.NewEncoder(&buf)
-	if err := encoder.Encode(res); err != nil {
+	// merging the base export options file, the computed values and the overrides
+	content, err := s.merge(defaults, res)
+	if err != nil {
 		return NewSignatureError(err, ErrorExportOptions)
 	}
 
 	// and exporting it to the destination file
-	if err := s.exportToFile(&buf); err != nil {
+	if err := s.exportToFile(bytes.NewBuffer(content), dst); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (s exportOptionsService) exportToFile(buf *bytes.Buffer) error {
+// merge applies the defaults, the base exportOptions.plist, the computed export options and
+// finally the configured overrides, the resolved signing values winning over the base file.
+// The values are merged as dictionaries so that the keys the model does not know about are
+// kept
+func (s exportOptionsService) merge(defaults, computed *api.ExportOptions) ([]byte, error) {
+	cfg := s.API.Config.ExportOptions
+
+	res, err := toDict(defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Path != "" {
+		b, err := s.API.FileService.OpenAndReadFileContent(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		base := map[string]interface{}{}
+		if _, err := plist.Unmarshal(b, &base); err != nil {
+			return nil, err
+		}
+
+		mergeDict(res, base)
+	}
+
+	values, err := toDict(computed)
+	if err != nil {
+		return nil, err
+	}
+	mergeDict(res, values)
+
+	overrides, err := toDict(cfg.Overrides)
+	if err != nil {
+		return nil, err
+	}
+	mergeDict(res, overrides)
+
+	// The over-the-air manifest is not supported by the App Store distribution
+	if res["method"] == "app-store" {
+		delete(res, "manifest")
+	}
+
+	return plist.MarshalIndent(res, plist.XMLFormat, "\t")
+}
+
+// toDict converts the export options to a plist dictionary, without the omitted values
+func toDict(o interface{}) (map[string]interface{}, error) {
+	b, err := plist.Marshal(o, plist.XMLFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]interface{}{}
+	if _, err := plist.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// mergeDict sets the values of src into dst, the nested dictionaries being merged as well
+func mergeDict(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if sv, ok := v.(map[string]interface{}); ok {
+			if dv, ok := dst[k].(map[string]interface{}); ok {
+				mergeDict(dv, sv)
+				continue
+			}
+		}
+
+		dst[k] = v
+	}
+}
+
+func newBool(b bool) *bool {
+	return &b
+}
+
+func (s exportOptionsService) exportToFile(buf *bytes.Buffer, dst string) error {
 	// create
-	dir := filepath.Dir(s.API.PathService.ExportPList())
+	dir := filepath.Dir(dst)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		if err := os.MkdirAll(dir, os.ModeSticky|os.ModePerm); err != nil {
 			return NewSignatureError(err, ErrorExportOptions)
@@ -86,7 +175,7 @@ func (s exportOptionsService) exportToFile(buf *bytes.Buffer) error {
 	}
 
 	// creating the output file
-	f, err := os.Create(s.API.PathService.ExportPList())
+	f, err := os.Create(dst)
 	if err != nil {
 		return NewSignatureError(err, ErrorExportOptions)
 	}
@@ -129,11 +218,16 @@ func (s exportOptionsService) resolveProvisionings() map[string]string {
 	return res
 }
 
+func (s exportOptionsService) resolveMethodForProvisioning(p *api.ProvisioningProfile) string {
+	return ProvisioningMethod(p)
+}
+
+// ProvisioningMethod resolves the distribution method of the provisioning profile
 //  # if ProvisionedDevices: !nil & "get-task-allow": true -> development
 //  # if ProvisionedDevices: !nil & "get-task-allow": false -> ad-hoc
 //  # if ProvisionedDevices: nil & "ProvisionsAllDevices": "true" -> enterprise
 //  # if ProvisionedDevices: nil & ProvisionsAllDevices: nil -> app-store
-func (s exportOptionsService) resolveMethodForProvisioning(p *api.ProvisioningProfile) string {
+func ProvisioningMethod(p *api.ProvisioningProfile) string {
 	if p.ProvisionedDevices != nil {
 		if p.Entitlements.GetTaskAllow {
 			return "development"
@@ -2,9 +2,14 @@ package signature
 
 import (
 	"dothething/internal/api"
+	"dothething/internal/util"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
+	"howett.net/plist"
 )
 
 type exportOptionsPlistSuite struct {
@@ -72,3 +77,134 @@ func (s *exportOptionsPlistSuite) TestResolveMethodForProvisioning() {
 	}
 }
 */
+
+func (s *exportOptionsPlistSuite) TestMerge() {
+	// setup: a base file containing a key unknown to the model
+	dir, err := ioutil.TempDir("", "export")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "exportOptions.plist")
+	s.Require().NoError(ioutil.WriteFile(base, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>thinning</key>
+	<string>&lt;thin-for-all-variants&gt;</string>
+	<key>unknownKey</key>
+	<string>kept</string>
+	<key>uploadSymbols</key>
+	<false/>
+	<key>provisioningProfiles</key>
+	<dict>
+		<key>com.extension</key>
+		<string>EXT-UUID</string>
+	</dict>
+</dict>
+</plist>`), 0600))
+
+	s.API = &api.API{FileService: util.NewFileService(), Config: &api.Config{}}
+	s.API.Config.ExportOptions.Path = base
+	s.API.Config.ExportOptions.Overrides = api.ExportOptions{
+		StripSwiftSymbols: newBool(false),
+		Thinning:          "iPhone10,3",
+		Manifest:          &api.ExportManifest{AppURL: "https://example.com/app.ipa"},
+	}
+	s.subject = &exportOptionsService{API: s.API}
+
+	defaults := &api.ExportOptions{UploadBitcode: newBool(true), UploadSymbols: newBool(true)}
+	computed := &api.ExportOptions{
+		Method:              "ad-hoc",
+		ProvisioningProfile: map[string]string{"com.app": "APP-UUID"},
+	}
+
+	// when:
+	b, err := s.subject.merge(defaults, computed)
+	s.Require().NoError(err)
+
+	res := map[string]interface{}{}
+	_, err = plist.Unmarshal(b, &res)
+	s.Require().NoError(err)
+
+	// then: the overrides win over the computed values, the base file over the defaults
+	s.Equal(map[string]interface{}{
+		"method":            "ad-hoc",
+		"stripSwiftSymbols": false,
+		"thinning":          "iPhone10,3",
+		"unknownKey":        "kept",
+		"uploadBitcode":     true,
+		"uploadSymbols":     false,
+		"manifest":          map[string]interface{}{"appURL": "https://example.com/app.ipa"},
+		"provisioningProfiles": map[string]interface{}{
+			"com.app":       "APP-UUID",
+			"com.extension": "EXT-UUID",
+		},
+	}, res)
+}
+
+func (s *exportOptionsPlistSuite) TestMergeComputedOverBase() {
+	// setup: a base file of another method and profile
+	dir, err := ioutil.TempDir("", "export")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "exportOptions.plist")
+	s.Require().NoError(ioutil.WriteFile(base, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>method</key>
+	<string>app-store</string>
+	<key>teamID</key>
+	<string>BASETEAM</string>
+	<key>provisioningProfiles</key>
+	<dict>
+		<key>com.app</key>
+		<string>BASE-UUID</string>
+	</dict>
+</dict>
+</plist>`), 0600))
+
+	s.API = &api.API{FileService: util.NewFileService(), Config: &api.Config{}}
+	s.API.Config.ExportOptions.Path = base
+	s.subject = &exportOptionsService{API: s.API}
+
+	computed := &api.ExportOptions{
+		Method:              "ad-hoc",
+		TeamID:              "12345ABCDE",
+		ProvisioningProfile: map[string]string{"com.app": "APP-UUID"},
+	}
+
+	// when:
+	b, err := s.subject.merge(&api.ExportOptions{}, computed)
+	s.Require().NoError(err)
+
+	res := map[string]interface{}{}
+	_, err = plist.Unmarshal(b, &res)
+	s.Require().NoError(err)
+
+	// then: the resolved values win over the base file
+	s.Equal(map[string]interface{}{
+		"method":               "ad-hoc",
+		"teamID":               "12345ABCDE",
+		"provisioningProfiles": map[string]interface{}{"com.app": "APP-UUID"},
+	}, res)
+}
+
+func (s *exportOptionsPlistSuite) TestMergeWithoutManifestForAppStore() {
+	// setup:
+	s.API = &api.API{Config: &api.Config{}}
+	s.API.Config.ExportOptions.Overrides.Manifest = &api.ExportManifest{AppURL: "https://example.com/app.ipa"}
+	s.subject = &exportOptionsService{API: s.API}
+
+	// when:
+	b, err := s.subject.merge(&api.ExportOptions{}, &api.ExportOptions{Method: "app-store"})
+	s.Require().NoError(err)
+
+	res := map[string]interface{}{}
+	_, err = plist.Unmarshal(b, &res)
+	s.Require().NoError(err)
+
+	// then:
+	s.Equal(map[string]interface{}{"method": "app-store"}, res)
+}
@@ -0,0 +1,177 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"dothething/internal/api"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// EnvCertificatePasswordPrefix prefix of the environment variables mapping a p12 file name, or
+// the SHA-1 fingerprint of its certificate, to its password.
+// e.g. DOTHETHING_CERTIFICATE_PASSWORD_DIST_P12 for dist.p12
+const EnvCertificatePasswordPrefix = EnvCertificatePassword + "_"
+
+var (
+	fingerprintRegexp = regexp.MustCompile(`^[0-9A-F]{40}$`)
+	envKeyRegexp      = regexp.MustCompile(`[^A-Z0-9]`)
+)
+
+// passwordMap maps a p12 file name or the SHA-1 fingerprint of its certificate to its password
+type passwordMap map[string]string
+
+// loadPasswordMap loads the passwords from the YAML file at path, if any, and from the
+// environment, the environment taking precedence
+func loadPasswordMap(fs api.FileService, path string, environ []string) (passwordMap, error) {
+	res := passwordMap{}
+
+	if path != "" {
+		f, err := fs.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		b, err := ioutil.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+
+		var m map[string]string
+		if err := yaml.Unmarshal(b, &m); err != nil {
+			return nil, err
+		}
+
+		for k, v := range m {
+			res[normalizeKey(k)] = v
+		}
+	}
+
+	for _, e := range environ {
+		if !strings.HasPrefix(e, EnvCertificatePasswordPrefix) {
+			continue
+		}
+
+		kv := strings.SplitN(strings.TrimPrefix(e, EnvCertificatePasswordPrefix), "=", 2)
+		if len(kv) == 2 {
+			res[normalizeKey(kv[0])] = kv[1]
+		}
+	}
+
+	return res, nil
+}
+
+// normalizeKey formats the fingerprints in upper case without separators, file names being
+// kept as is
+func normalizeKey(key string) string {
+	k := strings.ToUpper(strings.Replace(key, ":", "", -1))
+	if fingerprintRegexp.MatchString(k) {
+		return k
+	}
+
+	return key
+}
+
+// envKey the environment variable suffix of the file name
+func envKey(name string) string {
+	return envKeyRegexp.ReplaceAllString(strings.ToUpper(name), "_")
+}
+
+// candidate a password to try, restricted to the certificate of the fingerprint it is mapped to
+type candidate struct {
+	fingerprint string
+	password    string
+}
+
+// candidates returns the passwords to try, in order, to decrypt the p12 file at path: the
+// password mapped to the file name, then the passwords mapped to fingerprints, and finally the
+// default password
+func (m passwordMap) candidates(path, fallback string) []candidate {
+	var res []candidate
+	seen := map[candidate]bool{}
+	add := func(c candidate) {
+		// A password tried for any certificate covers the fingerprint ones
+		if !seen[c] && !seen[candidate{password: c.password}] {
+			seen[c] = true
+			res = append(res, c)
+		}
+	}
+
+	name := filepath.Base(path)
+	if p, ok := m[name]; ok {
+		add(candidate{password: p})
+	} else if p, ok := m[envKey(name)]; ok {
+		add(candidate{password: p})
+	}
+
+	// Sorting the fingerprints to always try the passwords in the same order
+	var fingerprints []string
+	for k := range m {
+		if fingerprintRegexp.MatchString(k) {
+			fingerprints = append(fingerprints, k)
+		}
+	}
+	sort.Strings(fingerprints)
+
+	for _, k := range fingerprints {
+		add(candidate{fingerprint: k, password: m[k]})
+	}
+
+	add(candidate{password: fallback})
+
+	return res
+}
+
+// DecodeCertificateFile decodes the content of the p12 file at path, trying the passwords
+// mapped to its file name or to the fingerprint of its certificate before the default one
+func DecodeCertificateFile(a *api.API, path string, data []byte) (api.P12Certificate, error) {
+	cfg := a.Config.CodeSignOption
+	passwords, err := loadPasswordMap(a.FileService, cfg.CertificatePasswords, os.Environ())
+	if err != nil {
+		return api.P12Certificate{}, err
+	}
+
+	return decodeWithCandidates(a.CertificateService, data, passwords.candidates(path, cfg.CertificatePassword))
+}
+
+// decodeWithCandidates tries to decode the p12 content with each candidate password, the first
+// one decrypting the content being kept for the keychain import. The passwords mapped to a
+// fingerprint are only kept for the certificate of this fingerprint.
+func decodeWithCandidates(cs api.CertificateService, data []byte, candidates []candidate) (api.P12Certificate, error) {
+	var cert api.P12Certificate
+	err := ErrorFailedToDecryptPEM
+	for _, c := range candidates {
+		cert, err = cs.DecodeCertificate(bytes.NewReader(data), c.password)
+		if err == nil && c.fingerprint != "" && c.fingerprint != fingerprint(cert.Certificate) {
+			err = ErrorFailedToDecryptPEM
+			continue
+		}
+
+		if err != ErrorFailedToDecryptPEM {
+			cert.Password = c.password
+			return cert, err
+		}
+	}
+
+	return cert, err
+}
+
+// fingerprint the SHA-1 fingerprint of the certificate, in upper case without separators
+func fingerprint(c *x509.Certificate) string {
+	if c == nil {
+		return ""
+	}
+
+	sum := sha1.Sum(c.Raw)
+
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,113 @@
+package signature
+
+import (
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/util"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPasswordMap(t *testing.T) {
+	// setup:
+	dir, err := ioutil.TempDir("", "passwords")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "passwords.yml")
+	content := "dist.p12: from-file\n" +
+		"dev.p12: dev\n" +
+		"ab:cd:ef:01:23:45:67:89:ab:cd:ef:01:23:45:67:89:ab:cd:ef:01: fingerprint\n"
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	environ := []string{
+		"HOME=/Users/dummy",
+		"DOTHETHING_CERTIFICATE_PASSWORD=default",
+		"DOTHETHING_CERTIFICATE_PASSWORD_dist.p12=from-env",
+		"DOTHETHING_CERTIFICATE_PASSWORD_OTHER_P12=other=with=equals",
+	}
+
+	// when:
+	m, err := loadPasswordMap(util.NewFileService(), path, environ)
+
+	// then: the environment should take precedence
+	assert.NoError(t, err)
+	assert.Equal(t, passwordMap{
+		"dist.p12":  "from-env",
+		"dev.p12":   "dev",
+		"OTHER_P12": "other=with=equals",
+		"ABCDEF0123456789ABCDEF0123456789ABCDEF01": "fingerprint",
+	}, m)
+
+	// when: the file does not exist
+	_, err = loadPasswordMap(util.NewFileService(), filepath.Join(dir, "missing.yml"), nil)
+
+	// then:
+	assert.Error(t, err)
+
+	// when: no file is configured
+	m, err = loadPasswordMap(util.NewFileService(), "", nil)
+
+	// then:
+	assert.NoError(t, err)
+	assert.Empty(t, m)
+}
+
+func TestPasswordCandidates(t *testing.T) {
+	m := passwordMap{
+		"dist.p12": "dist",
+		"DEV_P12":  "dev",
+		"BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB": "second",
+		"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA": "first",
+	}
+
+	a := "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+	b := "BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB"
+	cases := []struct {
+		path     string
+		fallback string
+		expected []candidate
+	}{
+		{"/certs/dist.p12", "", []candidate{{password: "dist"}, {a, "first"}, {b, "second"}, {password: ""}}},
+		{"/certs/dev.p12", "first", []candidate{{password: "dev"}, {a, "first"}, {b, "second"}, {password: "first"}}},
+		{"/certs/unknown.p12", "dev", []candidate{{a, "first"}, {b, "second"}, {password: "dev"}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			assert.Equal(t, c.expected, m.candidates(c.path, c.fallback))
+		})
+	}
+}
+
+func TestResolveInFolderWithPasswords(t *testing.T) {
+	// setup: a folder containing the same identity with different passwords
+	data, err := ioutil.ReadFile("../../assets/Certificate.p12")
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "certificates")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "mapped.p12"), data, 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "unmapped.p12"), data, 0600))
+
+	passwords := filepath.Join(dir, "passwords.yml")
+	assert.NoError(t, ioutil.WriteFile(passwords, []byte("mapped.p12: p4ssword\n"), 0600))
+
+	a := &api.API{FileService: util.NewFileService(), Config: &api.Config{}}
+	a.Config.CodeSignOption.CertificatePasswords = passwords
+	a.Config.CodeSignOption.CertificatePassword = "wrong"
+
+	// when:
+	res := certService{a}.ResolveInFolder(context.Background(), dir)
+
+	// then: only the mapped file should have been decrypted
+	assert.Len(t, res, 1)
+	assert.Equal(t, filepath.Join(dir, "mapped.p12"), res[0].FilePath)
+	assert.Equal(t, "p4ssword", res[0].Password)
+}
@@ -11,6 +11,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 
@@ -29,19 +30,46 @@ var (
 // provisioningService implement the ProvisioningService interface
 type provisioningService struct {
 	*api.API
+	index    *profileIndex
+	installs *installSession
 }
 
 // NewProvisioningService create a new instance of the provisioning service
 func NewProvisioningService(api *api.API) api.ProvisioningService {
-	return provisioningService{api}
+	return provisioningService{
+		API:      api,
+		index:    newProfileIndex(defaultProfileIndexPath()),
+		installs: &installSession{},
+	}
+}
+
+// InstalledProfilesDir returns the folder where Xcode looks up the provisioning profiles
+func InstalledProfilesDir() (string, error) {
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return profilesDirIn(dir), nil
+}
+
+// profilesDirIn the provisioning profiles folder of the home folder
+func profilesDirIn(home string) string {
+	return filepath.Join(home, "Library", "MobileDevice", "Provisioning Profiles")
 }
 
 // Decode will decode the provisioning at the designated filepath
 func (p provisioningService) Decode(ctx context.Context, r io.Reader) (api.ProvisioningProfile, error) {
 	var pp api.ProvisioningProfile
 
+	// We keep the raw content to be able to install the provisioning later on
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return pp, ErrorParsingPublicKey
+	}
+
 	// First we decode the provisioning at path
-	data, err := p.decodeProvisioning(ctx, r)
+	data, err := p.decodeProvisioning(ctx, bytes.NewReader(raw))
 	if err != nil {
 		return pp, ErrorParsingPublicKey
 	}
@@ -60,6 +88,7 @@ func (p provisioningService) Decode(ctx context.Context, r io.Reader) (api.Provi
 	// For more convenience compute the bundle identifier without the teamID prefix.
 	pp.BundleIdentifier = strings.TrimSpace(strings.TrimPrefix(pp.Entitlements.AppID,
 		fmt.Sprintf("%s.", pp.Entitlements.TeamID)))
+	pp.Content = raw
 
 	return pp, err
 }
@@ -70,27 +99,43 @@ func (p provisioningService) Install(pp *api.ProvisioningProfile) error {
 		Str("BundleIdentifier", pp.BundleIdentifier).
 		Msg("Installing provisioning")
 
-	input, err := ioutil.ReadFile(pp.FilePath)
-	if err != nil {
-		return err
+	// The provisioning may not come from a file, in which case we use the decoded content
+	input := pp.Content
+	if len(input) == 0 {
+		b, err := ioutil.ReadFile(pp.FilePath)
+		if err != nil {
+			return err
+		}
+		input = b
 	}
 
-	// Retrieving the user home directory
-	dir, err := os.UserHomeDir()
+	// Retrieving the provisioning profiles folder
+	folder, err := p.profilesDir()
 	if err != nil {
 		return err
 	}
 
-	folder := fmt.Sprintf("%v/Library/MobileDevice/Provisioning Profiles/", dir)
 	if err := os.MkdirAll(folder, os.ModePerm); err != nil {
 		return err
 	}
 
 	// Formatting the provisioning path
-	fn := fmt.Sprintf("%v/%v.mobileprovision", folder, pp.UUID)
+	fn := filepath.Join(folder, pp.UUID+".mobileprovision")
+
+	// The profiles installed before the run are kept by the cleanup
+	_, err = os.Stat(fn)
+	existed := err == nil
 
 	// Writing the file
-	return ioutil.WriteFile(fn, input, os.ModePerm)
+	if err := ioutil.WriteFile(fn, input, os.ModePerm); err != nil {
+		return err
+	}
+
+	if !existed {
+		p.installs.record(fn)
+	}
+
+	return nil
 }
 
 // isProvisioningFile check if the candidate is a valid provisioning file by testing it's
@@ -143,37 +188,62 @@ func (p provisioningService) decodeRawProvisioning(
 	return &dpp, nil
 }
 
-// readProvisioningFile Read the provided file at path and try to decode it as provisioning
+// readProvisioningFile Read the provided file at path and try to decode it as provisioning,
+// the index being used to skip the decoding of the files which did not change
 func (p provisioningService) readProvisioningFile(
 	ctx context.Context,
 	path string,
 ) (*api.ProvisioningProfile, error) {
+	info, err := p.API.FileService.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if pp, ok := p.index.get(path, info); ok {
+		return pp, nil
+	}
+
 	// Open the file to a reader
 	f, err := p.API.FileService.Open(path)
 	if err != nil {
-		return nil, nil
+		return nil, err
 	}
-	defer f.Close()
 
 	// Read the content of the file
-	return p.decodeRawProvisioning(ctx, path, f)
+	pp, err := p.decodeRawProvisioning(ctx, path, f)
+	if err != nil {
+		return nil, err
+	}
+
+	p.index.put(path, info, pp)
+
+	return pp, nil
 }
 
 func (p provisioningService) decodingWorker(
 	id int,
 	wg *sync.WaitGroup,
+	mu *sync.Mutex,
 	ctx context.Context,
 	paths <-chan string,
 	res *[]*api.ProvisioningProfile,
+	seen map[string]bool,
 ) {
 	wg.Add(1)
 	for value := range paths {
 		dpp, err := p.readProvisioningFile(ctx, value)
+
+		mu.Lock()
+		seen[value] = true
 		if err != nil {
-			fmt.Println("error while decoding", err)
+			log.Warn().
+				Str("Path", value).
+				AnErr("Reason", err).
+				Msg("Skipping invalid provisioning profile")
 		} else {
 			*res = append(*res, dpp)
 		}
+		mu.Unlock()
 	}
 
 	wg.Done()
@@ -186,13 +256,20 @@ func (p provisioningService) ResolveProvisioningFilesInFolder(
 	root string,
 ) []*api.ProvisioningProfile {
 
+	// The index is keyed by absolute paths
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+
 	paths := make(chan string)
 	var res []*api.ProvisioningProfile
+	seen := map[string]bool{}
 
 	var wg sync.WaitGroup
+	var mu sync.Mutex
 	// Increment waitgroup counter and create go routines
 	for i := 0; i < 8; i++ {
-		go p.decodingWorker(i, &wg, ctx, paths, &res)
+		go p.decodingWorker(i, &wg, &mu, ctx, paths, &res, seen)
 	}
 
 	err := p.API.FileService.Walk(ctx, root, isProvisioningFile, paths, &wg)
@@ -202,6 +279,16 @@ func (p provisioningService) ResolveProvisioningFilesInFolder(
 	}
 	wg.Wait()
 
+	// The files removed since the last run are dropped from the index, only when the whole
+	// folder has been walked
+	if err == nil {
+		p.index.prune(root, seen)
+	}
+
+	if err := p.index.save(); err != nil {
+		log.Warn().AnErr("Reason", err).Msg("Failed to save the provisioning profiles index")
+	}
+
 	return res
 }
 
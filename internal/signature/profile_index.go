@@ -0,0 +1,179 @@
+package signature
+
+import (
+	"dothething/internal/api"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// profileIndexVersion is bumped whenever the layout of the cached metadata changes, the
+// previous index being discarded
+const profileIndexVersion = 1
+
+// profileIndex persists the decoded provisioning profiles metadata keyed by path, an entry
+// being invalidated as soon as the modification time or the size of the file changes
+type profileIndex struct {
+	mu      sync.Mutex
+	path    string
+	loaded  bool
+	dirty   bool
+	entries map[string]profileIndexEntry
+}
+
+// profileIndexEntry cached metadata of a provisioning profile file
+type profileIndexEntry struct {
+	ModTime time.Time
+	Size    int64
+	Profile api.ProvisioningProfile
+}
+
+// profileIndexFile on disk representation of the index
+type profileIndexFile struct {
+	Version int
+	Entries map[string]profileIndexEntry
+}
+
+// newProfileIndex create an index persisted at path, an empty path keeping the index in memory
+func newProfileIndex(path string) *profileIndex {
+	return &profileIndex{path: path, entries: map[string]profileIndexEntry{}}
+}
+
+// defaultProfileIndexPath the index is stored into the user cache directory
+func defaultProfileIndexPath() string {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(cache, "do-the-thing", "profiles.json")
+}
+
+// load reads the persisted index once, a missing or outdated index being ignored
+func (i *profileIndex) load() {
+	if i.loaded {
+		return
+	}
+	i.loaded = true
+
+	if i.path == "" {
+		return
+	}
+
+	b, err := ioutil.ReadFile(i.path)
+	if err != nil {
+		return
+	}
+
+	var f profileIndexFile
+	if err := json.Unmarshal(b, &f); err != nil || f.Version != profileIndexVersion {
+		return
+	}
+
+	if f.Entries != nil {
+		i.entries = f.Entries
+	}
+}
+
+// get returns the cached profile for the file, if it did not change since it has been indexed
+func (i *profileIndex) get(path string, info os.FileInfo) (*api.ProvisioningProfile, bool) {
+	if i == nil {
+		return nil, false
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.load()
+
+	e, ok := i.entries[path]
+	if !ok || e.Size != info.Size() || !e.ModTime.Equal(info.ModTime()) {
+		return nil, false
+	}
+
+	// The parsed certificates are not persisted
+	certs, err := parseRawX509Certificates(e.Profile.RawCertificates)
+	if err != nil {
+		return nil, false
+	}
+
+	pp := e.Profile
+	pp.Certificates = certs
+	pp.FilePath = path
+
+	return &pp, true
+}
+
+// put caches the decoded profile of the file
+func (i *profileIndex) put(path string, info os.FileInfo, pp *api.ProvisioningProfile) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.load()
+
+	i.entries[path] = profileIndexEntry{ModTime: info.ModTime(), Size: info.Size(), Profile: *pp}
+	i.dirty = true
+}
+
+// prune removes the entries of the files below root which have not been seen
+func (i *profileIndex) prune(root string, seen map[string]bool) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.load()
+
+	prefix := filepath.Clean(root) + string(filepath.Separator)
+	for path := range i.entries {
+		if strings.HasPrefix(path, prefix) && !seen[path] {
+			delete(i.entries, path)
+			i.dirty = true
+		}
+	}
+}
+
+// save persists the index if it changed
+func (i *profileIndex) save() error {
+	if i == nil {
+		return nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !i.dirty || i.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(profileIndexFile{Version: profileIndexVersion, Entries: i.entries})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(i.path), 0700); err != nil {
+		return err
+	}
+
+	// Write then rename, concurrent runs should never read a partial index
+	tmp := fmt.Sprintf("%v.%v.tmp", i.path, os.Getpid())
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, i.path); err != nil {
+		return err
+	}
+
+	i.dirty = false
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package signature
+
+import (
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/util"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileIndex(t *testing.T) {
+	// setup:
+	dir, err := ioutil.TempDir("", "profiles")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "dummy.mobileprovision")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("content"), 0600))
+	info, err := os.Stat(file)
+	assert.NoError(t, err)
+
+	index := newProfileIndex(filepath.Join(dir, "cache", "profiles.json"))
+	index.put(file, info, &api.ProvisioningProfile{UUID: "B5C2906D", Content: []byte("content")})
+	assert.NoError(t, index.save())
+
+	// when: the index is reloaded from the disk
+	reloaded := newProfileIndex(filepath.Join(dir, "cache", "profiles.json"))
+	pp, ok := reloaded.get(file, info)
+
+	// then: the metadata should be cached, without the raw content
+	assert.True(t, ok)
+	assert.Equal(t, "B5C2906D", pp.UUID)
+	assert.Equal(t, file, pp.FilePath)
+	assert.Empty(t, pp.Content)
+
+	// when: the file is modified
+	assert.NoError(t, os.Chtimes(file, time.Now(), info.ModTime().Add(time.Second)))
+	modified, err := os.Stat(file)
+	assert.NoError(t, err)
+	_, ok = reloaded.get(file, modified)
+
+	// then: the entry should be invalidated
+	assert.False(t, ok)
+
+	// when: the file is not found anymore in its folder
+	reloaded.prune(dir, map[string]bool{})
+	_, ok = reloaded.get(file, info)
+
+	// then:
+	assert.False(t, ok)
+}
+
+func TestResolveProvisioningFilesInFolderUsesIndex(t *testing.T) {
+	// setup:
+	dir, err := ioutil.TempDir("", "profiles")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	raw, err := ioutil.ReadAll(getSignedReaderData(validProvisioning))
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "valid.mobileprovision"), raw, 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "invalid.mobileprovision"), []byte("invalid"), 0600))
+
+	p := provisioningService{
+		API:   &api.API{FileService: util.NewFileService()},
+		index: newProfileIndex(filepath.Join(dir, "profiles.json")),
+	}
+
+	// when:
+	res := p.ResolveProvisioningFilesInFolder(context.Background(), dir)
+
+	// then: only the valid profile should be resolved and indexed
+	assert.Len(t, res, 1)
+	assert.Len(t, p.index.entries, 1)
+	assert.FileExists(t, filepath.Join(dir, "profiles.json"))
+
+	// when: resolving again
+	res = p.ResolveProvisioningFilesInFolder(context.Background(), dir)
+
+	// then: the cached profile should be returned with its certificates
+	assert.Len(t, res, 1)
+	assert.Equal(t, "B5C2906D-D6EE-476E-AF17-D99AE14644AA", res[0].UUID)
+	assert.NotNil(t, res[0].Certificates)
+}
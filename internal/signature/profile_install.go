@@ -0,0 +1,133 @@
+package signature
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// EnvHome the variable pointing the xcode commands to the isolated home folder
+const EnvHome = "HOME"
+
+// sharedHomeFiles the files of the user home linked into the isolated one, for the package
+// resolution, the caches and the Xcode defaults to keep working
+var sharedHomeFiles = []string{
+	".gitconfig",
+	".netrc",
+	".ssh",
+	filepath.Join("Library", "Caches"),
+	filepath.Join("Library", "Developer"),
+	filepath.Join("Library", "Preferences"),
+}
+
+// installSession the provisioning profiles installed by the run, to be removed once done
+type installSession struct {
+	mu sync.Mutex
+	// home the isolated home folder the profiles are installed into
+	home      string
+	installed []string
+}
+
+// record keeps the path of the profile installed by the run
+func (s *installSession) record(path string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.installed = append(s.installed, path)
+}
+
+// profilesDir resolves the folder the profiles are installed into. The isolated home folder
+// is created by the first install, the xcode commands run afterwards being pointed to it
+func (p provisioningService) profilesDir() (string, error) {
+	if !p.API.Config.CodeSignOption.IsolateProfiles || p.installs == nil {
+		return InstalledProfilesDir()
+	}
+
+	p.installs.mu.Lock()
+	defer p.installs.mu.Unlock()
+
+	if p.installs.home == "" {
+		home, err := ioutil.TempDir("", "do-the-thing-home")
+		if err != nil {
+			return "", err
+		}
+
+		if err := linkHomeFiles(home); err != nil {
+			os.RemoveAll(home)
+			return "", err
+		}
+
+		log.Warn().
+			Str("Home", home).
+			Msg("The xcode commands run with the isolated home folder, only the user caches, Xcode settings and credentials being linked into it")
+
+		p.installs.home = home
+		p.API.Exec.SetXCodeEnv(EnvHome, home)
+	}
+
+	return profilesDirIn(p.installs.home), nil
+}
+
+// linkHomeFiles links the shared files of the user home into the isolated home folder, the
+// links being removed with the folder without touching their targets
+func linkHomeFiles(home string) error {
+	user, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range sharedHomeFiles {
+		src := filepath.Join(user, f)
+		if _, err := os.Lstat(src); err != nil {
+			continue
+		}
+
+		dst := filepath.Join(home, f)
+		if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+			return err
+		}
+
+		if err := os.Symlink(src, dst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Cleanup removes the provisioning profiles installed by the run and the isolated home
+// folder, the profiles installed before the run being kept
+func (p provisioningService) Cleanup() error {
+	if p.installs == nil {
+		return nil
+	}
+
+	p.installs.mu.Lock()
+	defer p.installs.mu.Unlock()
+
+	var res error
+	for _, f := range p.installs.installed {
+		log.Info().Str("Path", f).Msg("Removing provisioning")
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) && res == nil {
+			res = err
+		}
+	}
+	p.installs.installed = nil
+
+	if p.installs.home != "" {
+		p.API.Exec.UnsetXCodeEnv(EnvHome)
+		if err := os.RemoveAll(p.installs.home); err != nil && res == nil {
+			res = err
+		}
+		p.installs.home = ""
+	}
+
+	return res
+}
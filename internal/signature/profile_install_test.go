@@ -0,0 +1,109 @@
+package signature
+
+import (
+	"dothething/internal/api"
+	"dothething/internal/utiltest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type profileInstallSuite struct {
+	suite.Suite
+	API     *api.API
+	exec    *utiltest.MockExecutor
+	home    string
+	oldHome string
+	subject provisioningService
+}
+
+func TestProfileInstallSuite(t *testing.T) {
+	suite.Run(t, new(profileInstallSuite))
+}
+
+func (s *profileInstallSuite) SetupTest() {
+	home, err := ioutil.TempDir("", "home")
+	s.Require().NoError(err)
+	s.home = home
+	s.oldHome = os.Getenv(EnvHome)
+	os.Setenv(EnvHome, home)
+
+	s.exec = new(utiltest.MockExecutor)
+	s.API = &api.API{Config: &api.Config{}, Exec: s.exec}
+	s.subject = provisioningService{API: s.API, installs: &installSession{}}
+}
+
+func (s *profileInstallSuite) TearDownTest() {
+	os.Setenv(EnvHome, s.oldHome)
+	os.RemoveAll(s.home)
+}
+
+func (s *profileInstallSuite) TestCleanupKeepsThePreviousProfiles() {
+	// setup: a profile installed before the run
+	dir := profilesDirIn(s.home)
+	s.Require().NoError(os.MkdirAll(dir, 0755))
+	previous := filepath.Join(dir, "PREVIOUS.mobileprovision")
+	s.Require().NoError(ioutil.WriteFile(previous, []byte("previous"), 0644))
+
+	// when:
+	s.Require().NoError(s.subject.Install(&api.ProvisioningProfile{UUID: "PREVIOUS", Content: []byte("previous")}))
+	s.Require().NoError(s.subject.Install(&api.ProvisioningProfile{UUID: "NEW", Content: []byte("new")}))
+
+	// then:
+	s.FileExists(filepath.Join(dir, "NEW.mobileprovision"))
+
+	// when:
+	err := s.subject.Cleanup()
+
+	// then:
+	s.NoError(err)
+	s.FileExists(previous)
+	s.NoFileExists(filepath.Join(dir, "NEW.mobileprovision"))
+}
+
+func (s *profileInstallSuite) TestIsolatedProfiles() {
+	// setup: the package credentials and the caches of the user
+	s.API.Config.CodeSignOption.IsolateProfiles = true
+	netrc := filepath.Join(s.home, ".netrc")
+	s.Require().NoError(ioutil.WriteFile(netrc, []byte("machine example.com"), 0600))
+	derivedData := filepath.Join(s.home, "Library", "Developer", "Xcode", "DerivedData")
+	s.Require().NoError(os.MkdirAll(derivedData, 0700))
+
+	var home string
+	s.exec.On("SetXCodeEnv", EnvHome, mock.Anything).Run(func(args mock.Arguments) {
+		home = args.String(1)
+	}).Once()
+
+	// when:
+	s.Require().NoError(s.subject.Install(&api.ProvisioningProfile{UUID: "FIRST", Content: []byte("first")}))
+	s.Require().NoError(s.subject.Install(&api.ProvisioningProfile{UUID: "SECOND", Content: []byte("second")}))
+
+	// then: the xcode commands are pointed to the home of the run, the user one being untouched
+	s.exec.AssertExpectations(s.T())
+	s.NotEqual(s.home, home)
+	s.FileExists(filepath.Join(profilesDirIn(home), "FIRST.mobileprovision"))
+	s.FileExists(filepath.Join(profilesDirIn(home), "SECOND.mobileprovision"))
+	s.NoDirExists(profilesDirIn(s.home))
+
+	// and: the user files are shared with the home of the run
+	b, err := ioutil.ReadFile(filepath.Join(home, ".netrc"))
+	s.NoError(err)
+	s.Equal("machine example.com", string(b))
+	s.DirExists(filepath.Join(home, "Library", "Developer", "Xcode", "DerivedData"))
+	s.NoFileExists(filepath.Join(home, ".ssh"))
+
+	// when:
+	s.exec.On("UnsetXCodeEnv", EnvHome).Once()
+	err = s.subject.Cleanup()
+
+	// then: the xcode commands are pointed back to the user home, its files being kept
+	s.NoError(err)
+	s.NoDirExists(home)
+	s.FileExists(netrc)
+	s.DirExists(derivedData)
+	s.exec.AssertExpectations(s.T())
+}
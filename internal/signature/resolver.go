@@ -28,13 +28,23 @@ func (r signatureResolver) Resolve(
 	ctx context.Context,
 	bundleIdentifier string,
 	platform pbx.PBXProductType,
+) (*api.SignatureConfiguration, error) {
+	return r.ResolveForMethod(ctx, bundleIdentifier, platform, "")
+}
+
+// ResolveForMethod does the same as Resolve, only considering the provisioning profiles of
+// the distribution method when provided
+func (r signatureResolver) ResolveForMethod(
+	ctx context.Context,
+	bundleIdentifier string,
+	platform pbx.PBXProductType,
+	method string,
 ) (*api.SignatureConfiguration, error) {
 	var err error
 	var res api.SignatureConfiguration
 
 	// resolving the candidates to match against
-	candidates := r.API.ProvisioningService.
-		ResolveProvisioningFilesInFolder(ctx, r.Config.CodeSignOption.Path)
+	candidates := filterByMethod(r.API.SigningAssetSource.ProvisioningProfiles(ctx), method)
 
 	// Matching the right provisioning file for the project bundle identifier configuration
 	if res.ProvisioningProfile, err = r.resolveProvisioningFileFor(
@@ -49,8 +59,8 @@ func (r signatureResolver) Resolve(
 	// The provisioning public key to match on
 	provisioningPublicKey := res.ProvisioningProfile.Certificates[0].Raw
 
-	// We iterate on all certificates found in the path
-	certs := r.API.CertificateService.ResolveInFolder(ctx, r.Config.CodeSignOption.Path)
+	// We iterate on all certificates provided by the signing asset sources
+	certs := r.API.SigningAssetSource.Certificates(ctx)
 
 	// And we try to find a matching certificate to the provisioning profile
 	if res.Cert, err = r.findMatchingCert(certs, provisioningPublicKey); err != nil {
@@ -60,6 +70,23 @@ func (r signatureResolver) Resolve(
 	return &res, nil
 }
 
+// filterByMethod keeps the provisioning profiles of the distribution method, all of them
+// being kept when no method is provided
+func filterByMethod(pps []*api.ProvisioningProfile, method string) []*api.ProvisioningProfile {
+	if method == "" {
+		return pps
+	}
+
+	var res []*api.ProvisioningProfile
+	for _, pp := range pps {
+		if ProvisioningMethod(pp) == method {
+			res = append(res, pp)
+		}
+	}
+
+	return res
+}
+
 // findMatchingCert will check if a matching certificate can be found into the list
 func (r signatureResolver) findMatchingCert(certs []*api.P12Certificate, pc []byte) (*api.P12Certificate, error) {
 	for _, c := range certs {
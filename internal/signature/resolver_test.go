@@ -210,3 +210,36 @@ func (s *SignatureResolverSuite) TestContains() {
 		})
 	}
 }
+
+func (s *SignatureResolverSuite) TestFilterByMethod() {
+	// setup:
+	all := true
+	development := &api.ProvisioningProfile{UUID: "DEV", ProvisionedDevices: &[]string{"UDID"}}
+	development.Entitlements.GetTaskAllow = true
+	adHoc := &api.ProvisioningProfile{UUID: "ADHOC", ProvisionedDevices: &[]string{"UDID"}}
+	enterprise := &api.ProvisioningProfile{UUID: "ENTERPRISE", ProvisionsAllDevices: &all}
+	appStore := &api.ProvisioningProfile{UUID: "APPSTORE"}
+	list := []*api.ProvisioningProfile{development, adHoc, enterprise, appStore}
+
+	cases := []struct {
+		method string
+		res    []*api.ProvisioningProfile
+	}{
+		{method: "", res: list},
+		{method: "development", res: []*api.ProvisioningProfile{development}},
+		{method: "ad-hoc", res: []*api.ProvisioningProfile{adHoc}},
+		{method: "enterprise", res: []*api.ProvisioningProfile{enterprise}},
+		{method: "app-store", res: []*api.ProvisioningProfile{appStore}},
+		{method: "developer-id"},
+	}
+
+	for _, c := range cases {
+		s.Run(c.method, func() {
+			// when:
+			res := filterByMethod(list, c.method)
+
+			// then:
+			s.Assert().Equal(c.res, res)
+		})
+	}
+}
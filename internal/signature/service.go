@@ -1,10 +1,16 @@
 package signature
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha1"
 	"dothething/internal/api"
+	"dothething/internal/util"
 	"dothething/internal/xcode/pbx"
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 
 	"github.com/rs/zerolog/log"
@@ -19,11 +25,13 @@ const (
 )
 
 func NewSignatureService(api *api.API) signatureService {
-	return signatureService{API: api}
+	return signatureService{API: api, imported: map[string]bool{}}
 }
 
 type signatureService struct {
 	*api.API
+	// imported the fingerprints of the certificates imported into the keychain by the run
+	imported map[string]bool
 }
 
 var cfg []api.TargetSignatureConfig
@@ -41,7 +49,7 @@ func (s signatureService) Run(ctx context.Context) error {
 
 	// Found configuration, installing it into a temporary keychain
 	log.Info().Msg("Found configuration")
-	err = s.API.KeyChain.Create(ctx, "dothething")
+	err = s.API.KeyChain.Create(ctx)
 	if err != nil {
 		log.Error().AnErr("Error", err).Msg("Failed to create keychain")
 		return err
@@ -127,21 +135,122 @@ func (a signatureService) applyTargetConfiguration(
 		return NewSignatureError(err, ErrorBuildSettingsConfiguration)
 	}
 
-	path, err := filepath.Abs(sc.Cert.FilePath)
-	if err != nil {
+	if err = a.ImportCertificate(ctx, sc.Cert); err != nil {
 		return NewSignatureError(err, ErrorCertificateImport)
 	}
 
-	if err = a.API.KeyChain.ImportCertificate(
+	return nil
+}
+
+// ImportCertificate imports the certificate into the keychain, only once per run as several
+// targets usually share the same identity. The identities found into the keychain are only
+// checked
+func (a signatureService) ImportCertificate(ctx context.Context, c *api.P12Certificate) error {
+	sum := sha1.Sum(c.Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+	if a.imported[fingerprint] {
+		return nil
+	}
+
+	if !c.InKeyChain {
+		if err := a.importCertificate(ctx, c); err != nil {
+			return err
+		}
+	}
+
+	// Failing before the build when the identity cannot sign
+	identities, err := a.API.KeyChain.Identities(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := checkIdentity(identities, c.Certificate); err != nil {
+		return err
+	}
+
+	a.imported[fingerprint] = true
+
+	return nil
+}
+
+func (a signatureService) importCertificate(ctx context.Context, c *api.P12Certificate) error {
+	path, cleanup, err := certificateFile(c)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	return a.API.KeyChain.ImportCertificate(
 		ctx,
 		path,
-		a.API.Config.CodeSignOption.CertificatePassword,
-		sc.Cert.Issuer.CommonName,
-	); err != nil {
-		return NewSignatureError(err, ErrorCertificateImport)
+		c.Password,
+		c.Issuer.CommonName,
+	)
+}
+
+// ForMethod resolves the signature configuration of the distribution method for each of the
+// targets resolved by Run, installing the provisioning profiles and importing the
+// certificates required to export the archive
+func (s signatureService) ForMethod(ctx context.Context, method string) ([]api.TargetSignatureConfig, error) {
+	var res []api.TargetSignatureConfig
+	for _, e := range cfg {
+		log.Info().Str("Target", e.TargetName).Str("Method", method).Msg("Resolving for method")
+
+		sc, err := s.API.
+			SignatureResolver.
+			ResolveForMethod(ctx, e.BundleIdentifier, e.Platform, method)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to resolve %v signature configuration for the bundle identifier \"%v\" (%v)",
+				method,
+				e.BundleIdentifier,
+				err,
+			)
+		}
+
+		if err := s.API.ProvisioningService.Install(sc.ProvisioningProfile); err != nil {
+			return nil, NewSignatureError(err, ErrorProvisioningInstall)
+		}
+
+		if err := s.ImportCertificate(ctx, sc.Cert); err != nil {
+			return nil, NewSignatureError(err, ErrorCertificateImport)
+		}
+
+		res = append(res, api.TargetSignatureConfig{
+			BundleIdentifier: e.BundleIdentifier,
+			Config:           sc,
+			Platform:         e.Platform,
+			TargetName:       e.TargetName,
+		})
 	}
 
-	return nil
+	return res, nil
+}
+
+// certificateFile resolves the path of the file to import for the certificate, writing its
+// content to a temporary file when it does not come from the file system
+func certificateFile(c *api.P12Certificate) (string, func(), error) {
+	if c.FilePath != "" {
+		path, err := filepath.Abs(c.FilePath)
+		return path, func() {}, err
+	}
+
+	// The keychain guess the format from the file extension
+	ext := ".p12"
+	if bytes.HasPrefix(c.Content, []byte("-----BEGIN")) {
+		ext = ".pem"
+	}
+
+	path, err := util.TempFilePath("certificate", ext)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := ioutil.WriteFile(path, c.Content, 0600); err != nil {
+		return "", nil, err
+	}
+
+	return path, func() { os.Remove(path) }, nil
 }
 
 func (a signatureService) configureBuildSettingsOfBuildConfiguration(
@@ -203,8 +312,10 @@ func (s signatureService) forTarget(
 	}
 
 	cfg = append(cfg, api.TargetSignatureConfig{
-		TargetName: t,
-		Config:     sc,
+		BundleIdentifier: bundleID,
+		Config:           sc,
+		Platform:         nt.ProductType,
+		TargetName:       t,
 	})
 
 	return nil
@@ -0,0 +1,155 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"dothething/internal/api"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type serviceMethodSuite struct {
+	suite.Suite
+	API        *api.API
+	cert       *api.P12Certificate
+	identities int
+	installed  []string
+	previous   []api.TargetSignatureConfig
+	subject    signatureService
+}
+
+func TestServiceMethodSuite(t *testing.T) {
+	suite.Run(t, new(serviceMethodSuite))
+}
+
+func (s *serviceMethodSuite) SetupTest() {
+	data, err := ioutil.ReadFile("../../assets/Certificate.p12")
+	s.Require().NoError(err)
+
+	c, err := certService{&api.API{}}.DecodeCertificate(bytes.NewReader(data), "p4ssword")
+	s.Require().NoError(err)
+	c.InKeyChain = true
+	s.cert = &c
+
+	s.identities = 0
+	s.installed = nil
+	s.previous = cfg
+	cfg = []api.TargetSignatureConfig{
+		{BundleIdentifier: "com.dummy.app", Platform: "iOS", TargetName: "App"},
+		{BundleIdentifier: "com.dummy.ext", Platform: "iOS", TargetName: "Extension"},
+	}
+
+	s.API = &api.API{Config: &api.Config{}}
+	s.API.KeyChain = methodKeyChain{s}
+	s.API.ProvisioningService = methodInstaller{s}
+	s.API.SignatureResolver = methodResolver{s}
+	s.subject = NewSignatureService(s.API)
+}
+
+func (s *serviceMethodSuite) TearDownTest() {
+	cfg = s.previous
+}
+
+// methodResolver resolves the ad-hoc profiles only
+type methodResolver struct{ s *serviceMethodSuite }
+
+func (r methodResolver) Resolve(ctx context.Context, bundleIdentifier, platform string) (*api.SignatureConfiguration, error) {
+	return r.ResolveForMethod(ctx, bundleIdentifier, platform, "")
+}
+
+func (r methodResolver) ResolveForMethod(
+	ctx context.Context,
+	bundleIdentifier string,
+	platform string,
+	method string,
+) (*api.SignatureConfiguration, error) {
+	if method != "ad-hoc" {
+		return nil, errors.New("no provisioning profile of the method")
+	}
+
+	return &api.SignatureConfiguration{
+		Cert:                r.s.cert,
+		ProvisioningProfile: &api.ProvisioningProfile{BundleIdentifier: bundleIdentifier, UUID: method + "-" + bundleIdentifier},
+	}, nil
+}
+
+// methodInstaller records the installed profiles
+type methodInstaller struct{ s *serviceMethodSuite }
+
+func (i methodInstaller) Cleanup() error { return nil }
+func (i methodInstaller) Decode(ctx context.Context, r io.Reader) (api.ProvisioningProfile, error) {
+	return api.ProvisioningProfile{}, nil
+}
+func (i methodInstaller) ResolveProvisioningFilesInFolder(ctx context.Context, root string) []*api.ProvisioningProfile {
+	return nil
+}
+func (i methodInstaller) Install(p *api.ProvisioningProfile) error {
+	i.s.installed = append(i.s.installed, p.UUID)
+	return nil
+}
+
+// methodKeyChain lists the identity of the certificate
+type methodKeyChain struct {
+	s *serviceMethodSuite
+}
+
+func (k methodKeyChain) Cleanup(ctx context.Context, dirs []string) ([]string, error) {
+	return nil, nil
+}
+func (k methodKeyChain) Create(ctx context.Context) error                           { return nil }
+func (k methodKeyChain) Delete(ctx context.Context) error                           { return nil }
+func (k methodKeyChain) GetPath() string                                            { return "" }
+func (k methodKeyChain) ImportIntermediates(ctx context.Context, path string) error { return nil }
+func (k methodKeyChain) ImportCertificate(ctx context.Context, path, password, name string) error {
+	return errors.New("the identity is already in the keychain")
+}
+func (k methodKeyChain) Identities(ctx context.Context) ([]api.SigningIdentity, error) {
+	k.s.identities++
+	return []api.SigningIdentity{{Name: "Dummy", SHA1: fingerprint(k.s.cert.Certificate)}}, nil
+}
+func (k methodKeyChain) IdentityCertificates(ctx context.Context) ([]*x509.Certificate, error) {
+	return nil, nil
+}
+
+func (s *serviceMethodSuite) TestForMethod() {
+	// when:
+	res, err := s.subject.ForMethod(context.Background(), "ad-hoc")
+
+	// then: the profiles of the method are installed, the shared identity checked once
+	s.Require().NoError(err)
+	s.Require().Len(res, 2)
+	s.Equal("App", res[0].TargetName)
+	s.Equal("ad-hoc-com.dummy.app", res[0].Config.ProvisioningProfile.UUID)
+	s.Equal("Extension", res[1].TargetName)
+	s.Equal("ad-hoc-com.dummy.ext", res[1].Config.ProvisioningProfile.UUID)
+	s.Equal([]string{"ad-hoc-com.dummy.app", "ad-hoc-com.dummy.ext"}, s.installed)
+	s.Equal(1, s.identities)
+}
+
+func (s *serviceMethodSuite) TestForMethodWithoutProfile() {
+	// when:
+	_, err := s.subject.ForMethod(context.Background(), "app-store")
+
+	// then: the reason of the failure is kept
+	s.EqualError(err, `failed to resolve app-store signature configuration for the bundle identifier "com.dummy.app" `+
+		`(no provisioning profile of the method)`)
+	s.Empty(s.installed)
+}
+
+func (s *serviceMethodSuite) TestImportedCertificatesArePerService() {
+	// setup:
+	_, err := s.subject.ForMethod(context.Background(), "ad-hoc")
+	s.Require().NoError(err)
+
+	// when: another run
+	_, err = NewSignatureService(s.API).ForMethod(context.Background(), "ad-hoc")
+
+	// then: the identity is checked again
+	s.NoError(err)
+	s.Equal(2, s.identities)
+}
@@ -0,0 +1,354 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"dothething/internal/api"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// EnvCertificates base64 encoded p12 files, separated by commas
+	EnvCertificates = "DOTHETHING_CERTIFICATES_BASE64"
+
+	// EnvCertificatePassword password of the p12 files provided through the environment
+	EnvCertificatePassword = "DOTHETHING_CERTIFICATE_PASSWORD"
+
+	// EnvProvisioningProfiles base64 encoded provisioning profiles, separated by commas
+	EnvProvisioningProfiles = "DOTHETHING_PROFILES_BASE64"
+)
+
+// NewSigningAssetSource create a new instance of the signing asset source aggregating all
+// the sources available for the configuration
+func NewSigningAssetSource(api *api.API) api.SigningAssetSource {
+	return sourceList{api}
+}
+
+// sourceList aggregates the configured signing asset sources
+type sourceList struct {
+	*api.API
+}
+
+// sources resolve the sources at call time, the configuration being populated after the
+// creation of the API client
+func (s sourceList) sources() []api.SigningAssetSource {
+	return []api.SigningAssetSource{
+		folderSource{API: s.API, root: s.API.Config.CodeSignOption.Path},
+		envSource{API: s.API, lookup: os.LookupEnv, environ: os.Environ},
+		keyPairSource{API: s.API},
+		repositorySource{API: s.API},
+		keychainSource{API: s.API},
+	}
+}
+
+// Certificates returns the certificates of all the sources
+func (s sourceList) Certificates(ctx context.Context) []*api.P12Certificate {
+	var res []*api.P12Certificate
+	for _, src := range s.sources() {
+		res = append(res, src.Certificates(ctx)...)
+	}
+
+	return res
+}
+
+// ProvisioningProfiles returns the provisioning profiles of all the sources
+func (s sourceList) ProvisioningProfiles(ctx context.Context) []*api.ProvisioningProfile {
+	var res []*api.ProvisioningProfile
+	for _, src := range s.sources() {
+		res = append(res, src.ProvisioningProfiles(ctx)...)
+	}
+
+	return res
+}
+
+// folderSource resolves the signing assets by walking a folder
+type folderSource struct {
+	*api.API
+	root string
+}
+
+// Certificates returns the p12 files found into the folder
+func (s folderSource) Certificates(ctx context.Context) []*api.P12Certificate {
+	if s.root == "" {
+		return nil
+	}
+
+	return s.API.CertificateService.ResolveInFolder(ctx, s.root)
+}
+
+// ProvisioningProfiles returns the provisioning profiles found into the folder
+func (s folderSource) ProvisioningProfiles(ctx context.Context) []*api.ProvisioningProfile {
+	if s.root == "" {
+		return nil
+	}
+
+	return s.API.ProvisioningService.ResolveProvisioningFilesInFolder(ctx, s.root)
+}
+
+// envSource resolves the signing assets from base64 encoded environment variables
+type envSource struct {
+	*api.API
+	lookup  func(key string) (string, bool)
+	environ func() []string
+}
+
+// Certificates decodes the p12 files provided through the environment
+func (s envSource) Certificates(ctx context.Context) []*api.P12Certificate {
+	password, ok := s.lookup(EnvCertificatePassword)
+	if !ok {
+		password = s.API.Config.CodeSignOption.CertificatePassword
+	}
+
+	passwords, err := loadPasswordMap(s.API.FileService, s.API.Config.CodeSignOption.CertificatePasswords, s.environ())
+	if err != nil {
+		log.Error().AnErr("Error", err).Msg("Failed to load the certificate passwords")
+		passwords = passwordMap{}
+	}
+
+	var res []*api.P12Certificate
+	for i, data := range s.values(EnvCertificates) {
+		name := fmt.Sprintf("$%v[%v]", EnvCertificates, i)
+		c, err := decodeWithCandidates(s.API.CertificateService, data, passwords.candidates(name, password))
+		if err != nil {
+			log.Warn().
+				Str("Source", name).
+				AnErr("Reason", err).
+				Msg("Skipping invalid signing identity")
+			continue
+		}
+
+		c.Content = data
+		res = append(res, &c)
+	}
+
+	return res
+}
+
+// ProvisioningProfiles decodes the provisioning profiles provided through the environment
+func (s envSource) ProvisioningProfiles(ctx context.Context) []*api.ProvisioningProfile {
+	var res []*api.ProvisioningProfile
+	for i, data := range s.values(EnvProvisioningProfiles) {
+		pp, err := s.API.ProvisioningService.Decode(ctx, bytes.NewReader(data))
+		if err != nil {
+			log.Warn().
+				Str("Source", fmt.Sprintf("$%v[%v]", EnvProvisioningProfiles, i)).
+				AnErr("Reason", err).
+				Msg("Skipping invalid provisioning profile")
+			continue
+		}
+
+		res = append(res, &pp)
+	}
+
+	return res
+}
+
+// values decodes the base64 payloads of the environment variable
+func (s envSource) values(key string) [][]byte {
+	v, ok := s.lookup(key)
+	if !ok {
+		return nil
+	}
+
+	var res [][]byte
+	for i, e := range strings.Split(v, ",") {
+		// The encoded payloads may be wrapped on several lines
+		e = strings.Join(strings.Fields(e), "")
+		if e == "" {
+			continue
+		}
+
+		data, err := base64.StdEncoding.DecodeString(e)
+		if err != nil {
+			log.Warn().
+				Str("Source", fmt.Sprintf("$%v[%v]", key, i)).
+				AnErr("Reason", err).
+				Msg("Failed to decode base64 value")
+			continue
+		}
+
+		res = append(res, data)
+	}
+
+	return res
+}
+
+// keyPairSource resolves the signing identity from separate certificate and private key files
+type keyPairSource struct {
+	*api.API
+}
+
+// Certificates decodes the configured certificate and private key pair
+func (s keyPairSource) Certificates(ctx context.Context) []*api.P12Certificate {
+	cfg := s.API.Config.CodeSignOption
+	if cfg.CertificateFile == "" || cfg.PrivateKeyFile == "" {
+		return nil
+	}
+
+	c, err := s.readKeyPair(cfg.CertificateFile, cfg.PrivateKeyFile, cfg.CertificatePassword)
+	if err != nil {
+		log.Warn().
+			Str("Certificate", cfg.CertificateFile).
+			Str("PrivateKey", cfg.PrivateKeyFile).
+			AnErr("Reason", err).
+			Msg("Skipping invalid signing identity")
+		return nil
+	}
+
+	return []*api.P12Certificate{c}
+}
+
+// ProvisioningProfiles the key pair does not provide any provisioning profile
+func (s keyPairSource) ProvisioningProfiles(ctx context.Context) []*api.ProvisioningProfile {
+	return nil
+}
+
+func (s keyPairSource) readKeyPair(certPath, keyPath, password string) (*api.P12Certificate, error) {
+	certData, err := readSourceFile(s.API.FileService, certPath)
+	if err != nil {
+		return nil, err
+	}
+
+	keyData, err := readSourceFile(s.API.FileService, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := decodeKeyPair(certData, keyData, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// readSourceFile reads the whole content of the file at path
+func readSourceFile(fs api.FileService, path string) ([]byte, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
+// repositorySource resolves the signing assets from the shared encrypted repository, the
+// decrypted content being only kept in memory
+type repositorySource struct {
+	*api.API
+}
+
+// Certificates decrypts the identities stored into the repository
+func (s repositorySource) Certificates(ctx context.Context) []*api.P12Certificate {
+	var res []*api.P12Certificate
+	for _, e := range s.entries(ctx, api.RepositoryCertificate) {
+		data, err := s.API.SigningRepository.Open(ctx, e)
+		if err != nil {
+			log.Warn().Str("File", e.File).AnErr("Reason", err).Msg("Failed to decrypt signing identity")
+			continue
+		}
+
+		c, err := decodePEMIdentity(data)
+		if err != nil {
+			log.Warn().Str("File", e.File).AnErr("Reason", err).Msg("Skipping invalid signing identity")
+			continue
+		}
+
+		res = append(res, &c)
+	}
+
+	return res
+}
+
+// ProvisioningProfiles decrypts the provisioning profiles stored into the repository
+func (s repositorySource) ProvisioningProfiles(ctx context.Context) []*api.ProvisioningProfile {
+	var res []*api.ProvisioningProfile
+	for _, e := range s.entries(ctx, api.RepositoryProfile) {
+		data, err := s.API.SigningRepository.Open(ctx, e)
+		if err != nil {
+			log.Warn().Str("File", e.File).AnErr("Reason", err).Msg("Failed to decrypt provisioning profile")
+			continue
+		}
+
+		pp, err := s.API.ProvisioningService.Decode(ctx, bytes.NewReader(data))
+		if err != nil {
+			log.Warn().Str("File", e.File).AnErr("Reason", err).Msg("Skipping invalid provisioning profile")
+			continue
+		}
+
+		res = append(res, &pp)
+	}
+
+	return res
+}
+
+// entries returns the unexpired entries of the kind, using the manifest to avoid decrypting
+// assets that could not be used anyway
+func (s repositorySource) entries(ctx context.Context, kind string) []api.RepositoryEntry {
+	if s.API.Config.CodeSignOption.Repository.URL == "" {
+		return nil
+	}
+
+	entries, err := s.API.SigningRepository.Entries(ctx)
+	if err != nil {
+		log.Error().AnErr("Error", err).Msg("Failed to read the signing repository")
+		return nil
+	}
+
+	var res []api.RepositoryEntry
+	now := time.Now()
+	for _, e := range entries {
+		if e.Kind != kind {
+			continue
+		}
+
+		if now.After(e.Expiration) {
+			log.Info().Str("File", e.File).Msg("Skipping expired signing asset")
+			continue
+		}
+
+		res = append(res, e)
+	}
+
+	return res
+}
+
+// keychainSource resolves the signing identities already into the existing or the login
+// keychain, the temporary keychain being empty before the imports
+type keychainSource struct {
+	*api.API
+}
+
+// Certificates returns the valid identities of the keychain, which need no import
+func (s keychainSource) Certificates(ctx context.Context) []*api.P12Certificate {
+	mode := s.API.Config.CodeSignOption.KeyChain.Mode
+	if mode == "" || mode == api.KeyChainTemporary {
+		return nil
+	}
+
+	certs, err := s.API.KeyChain.IdentityCertificates(ctx)
+	if err != nil {
+		log.Error().AnErr("Error", err).Msg("Failed to read the identities of the keychain")
+		return nil
+	}
+
+	var res []*api.P12Certificate
+	for _, c := range certs {
+		res = append(res, &api.P12Certificate{Certificate: c, InKeyChain: true, Type: identityType(c)})
+	}
+
+	return res
+}
+
+// ProvisioningProfiles the keychain does not provide any provisioning profile
+func (s keychainSource) ProvisioningProfiles(ctx context.Context) []*api.ProvisioningProfile {
+	return nil
+}
@@ -0,0 +1,179 @@
+package signature
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"dothething/internal/api"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type sourceSuite struct {
+	suite.Suite
+	API *api.API
+	env map[string]string
+}
+
+func TestSourceSuite(t *testing.T) {
+	suite.Run(t, new(sourceSuite))
+}
+
+func (s *sourceSuite) SetupTest() {
+	s.env = map[string]string{}
+	s.API = &api.API{Config: &api.Config{}}
+	s.API.CertificateService = certService{s.API}
+	s.API.ProvisioningService = provisioningService{API: s.API}
+}
+
+func (s *sourceSuite) lookup(key string) (string, bool) {
+	v, ok := s.env[key]
+	return v, ok
+}
+
+func (s *sourceSuite) environ() []string {
+	var res []string
+	for k, v := range s.env {
+		res = append(res, k+"="+v)
+	}
+
+	return res
+}
+
+func (s *sourceSuite) TestEnvSourceCertificates() {
+	// setup:
+	data, err := ioutil.ReadFile("../../assets/Certificate.p12")
+	s.Require().NoError(err)
+	s.env[EnvCertificates] = base64.StdEncoding.EncodeToString(data) + ",invalid"
+	s.env[EnvCertificatePassword] = "p4ssword"
+
+	// when:
+	res := envSource{API: s.API, lookup: s.lookup, environ: s.environ}.Certificates(context.Background())
+
+	// then: the invalid entry should have been skipped
+	s.Require().Len(res, 1)
+	s.Equal(data, res[0].Content)
+	s.Equal("p4ssword", res[0].Password)
+	s.Equal("", res[0].FilePath)
+}
+
+func (s *sourceSuite) TestEnvSourceCertificatesWithMappedPassword() {
+	// setup: the default password is wrong, the fingerprint one should be used
+	data, err := ioutil.ReadFile("../../assets/Certificate.p12")
+	s.Require().NoError(err)
+	s.env[EnvCertificates] = base64.StdEncoding.EncodeToString(data)
+	s.env[EnvCertificatePassword] = "wrong"
+	s.env[EnvCertificatePasswordPrefix+"2A:D5:06:5A:24:1A:F4:C2:F0:25:5A:93:43:04:DB:97:B9:78:0C:B7"] = "p4ssword"
+
+	// when:
+	res := envSource{API: s.API, lookup: s.lookup, environ: s.environ}.Certificates(context.Background())
+
+	// then:
+	s.Require().Len(res, 1)
+	s.Equal("p4ssword", res[0].Password)
+}
+
+func (s *sourceSuite) TestEnvSourceCertificatesWithPasswordOfAnotherFingerprint() {
+	// setup: the password decrypts the p12, but is mapped to another certificate
+	data, err := ioutil.ReadFile("../../assets/Certificate.p12")
+	s.Require().NoError(err)
+	s.env[EnvCertificates] = base64.StdEncoding.EncodeToString(data)
+	s.env[EnvCertificatePassword] = "wrong"
+	s.env[EnvCertificatePasswordPrefix+"0123456789ABCDEF0123456789ABCDEF01234567"] = "p4ssword"
+
+	// when:
+	res := envSource{API: s.API, lookup: s.lookup, environ: s.environ}.Certificates(context.Background())
+
+	// then: the certificate is skipped
+	s.Empty(res)
+}
+
+func (s *sourceSuite) TestEnvSourceProvisioningProfiles() {
+	// setup:
+	raw, err := ioutil.ReadAll(getSignedReaderData(validProvisioning))
+	s.Require().NoError(err)
+
+	// base64 payloads may be wrapped
+	enc := base64.StdEncoding.EncodeToString(raw)
+	s.env[EnvProvisioningProfiles] = enc[:10] + "\n" + enc[10:]
+
+	// when:
+	res := envSource{API: s.API, lookup: s.lookup, environ: s.environ}.ProvisioningProfiles(context.Background())
+
+	// then:
+	s.Require().Len(res, 1)
+	s.Equal("B5C2906D-D6EE-476E-AF17-D99AE14644AA", res[0].UUID)
+	s.Equal(raw, res[0].Content)
+}
+
+func (s *sourceSuite) TestEnvSourceWithoutVariables() {
+	// when:
+	src := envSource{API: s.API, lookup: s.lookup, environ: s.environ}
+
+	// then:
+	s.Empty(src.Certificates(context.Background()))
+	s.Empty(src.ProvisioningProfiles(context.Background()))
+}
+
+func (s *sourceSuite) TestDecodeKeyPair() {
+	// setup:
+	certPEM, keyDER := generateKeyPair(s.T())
+	other, _ := generateKeyPair(s.T())
+
+	// when:
+	c, err := decodeKeyPair(certPEM, keyDER, "")
+
+	// then:
+	s.NoError(err)
+	s.Equal(api.IdentityAppleDistribution, c.Type)
+	s.Contains(string(c.Content), "BEGIN PRIVATE KEY")
+
+	// when: the certificate does not match the key
+	_, err = decodeKeyPair(other, keyDER, "")
+
+	// then:
+	s.Equal(ErrorPrivateKeyMismatch, err)
+
+	// when: the key is invalid
+	_, err = decodeKeyPair(certPEM, []byte("invalid"), "")
+
+	// then:
+	s.Equal(ErrorMissingPrivateKey, err)
+}
+
+// generateKeyPair creates a PEM encoded code signing certificate and its DER encoded key
+func generateKeyPair(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Apple Distribution: Dummy Ltd (12345ABCDE)"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tpl, &tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyDER
+}
@@ -0,0 +1,107 @@
+package symbolicate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// ErrInvalidIPS the JSON report is not made of a JSON header line followed by a JSON body
+var ErrInvalidIPS = errors.New("Invalid .ips crash report, expecting a JSON header and a JSON body")
+
+// ipsImage a binary image of the JSON report
+type ipsImage struct {
+	UUID string `json:"uuid"`
+}
+
+// symbolicateIPS fills the symbol, symbolLocation, sourceFile and sourceLine of the frames of
+// the JSON report, the header being kept as is
+func symbolicateIPS(b []byte, r *resolver, w io.Writer) error {
+	i := bytes.IndexByte(b, '\n')
+	if i < 0 {
+		return ErrInvalidIPS
+	}
+	header, body := b[:i+1], b[i+1:]
+
+	var images struct {
+		UsedImages []ipsImage `json:"usedImages"`
+	}
+	if err := json.Unmarshal(body, &images); err != nil {
+		return ErrInvalidIPS
+	}
+
+	// Decoding the body generically to keep all its values
+	var report map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&report); err != nil {
+		return ErrInvalidIPS
+	}
+
+	var frames []interface{}
+	if threads, ok := report["threads"].([]interface{}); ok {
+		for _, t := range threads {
+			if t, ok := t.(map[string]interface{}); ok {
+				frames = append(frames, asSlice(t["frames"])...)
+			}
+		}
+	}
+	frames = append(frames, asSlice(report["lastExceptionBacktrace"])...)
+
+	for _, f := range frames {
+		if f, ok := f.(map[string]interface{}); ok {
+			symbolicateFrame(f, images.UsedImages, r)
+		}
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(report)
+}
+
+// symbolicateFrame resolves the image offset of the frame
+func symbolicateFrame(f map[string]interface{}, images []ipsImage, r *resolver) {
+	index, err := asUint(f["imageIndex"])
+	if err != nil || index >= uint64(len(images)) {
+		return
+	}
+
+	offset, err := asUint(f["imageOffset"])
+	if err != nil {
+		return
+	}
+
+	loc, ok := r.lookup(images[index].UUID, offset)
+	if !ok {
+		return
+	}
+
+	f["symbol"] = loc.Function
+	f["symbolLocation"] = loc.Offset
+	if loc.File != "" {
+		f["sourceFile"] = loc.File
+		f["sourceLine"] = loc.Line
+	}
+}
+
+func asSlice(v interface{}) []interface{} {
+	res, _ := v.([]interface{})
+	return res
+}
+
+func asUint(v interface{}) (uint64, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, strconv.ErrSyntax
+	}
+
+	return strconv.ParseUint(n.String(), 10, 64)
+}
@@ -0,0 +1,139 @@
+// Package symbolicate resolves the addresses of the crash reports to functions and source
+// lines, reading the debug symbols without Xcode
+package symbolicate
+
+import (
+	"bytes"
+	"context"
+	"dothething/internal/api"
+	"dothething/internal/macho"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+type symbolicator struct {
+	*api.API
+}
+
+// NewSymbolicator create a new instance of the crash reports symbolicator
+func NewSymbolicator(api *api.API) api.Symbolicator {
+	return symbolicator{api}
+}
+
+// Symbolicate detects the format of the report, the JSON .ips reports starting with their
+// JSON header
+func (s symbolicator) Symbolicate(ctx context.Context, report io.Reader, dsyms []string, w io.Writer) error {
+	b, err := ioutil.ReadAll(report)
+	if err != nil {
+		return err
+	}
+
+	r, err := newResolver(dsyms)
+	if err != nil {
+		return err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		return symbolicateIPS(b, r, w)
+	}
+
+	return symbolicateText(b, r, w)
+}
+
+// debugFile the binary holding the symbols of an architecture
+type debugFile struct {
+	cpu  string
+	path string
+}
+
+// resolver resolves the image offsets to locations, by image UUID
+type resolver struct {
+	files  map[string]debugFile
+	tables map[string]*symbolTable
+}
+
+// newResolver indexes the Mach-O files found into the folders by UUID, the dSYMs being
+// preferred over the binaries for the same UUID
+func newResolver(dirs []string) (*resolver, error) {
+	res := &resolver{files: map[string]debugFile{}, tables: map[string]*symbolTable{}}
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				return nil
+			}
+			defer f.Close()
+
+			m, err := macho.Parse(f)
+			if err != nil {
+				return nil
+			}
+
+			for _, a := range m.Archs {
+				if a.UUID == "" {
+					continue
+				}
+
+				if prev, ok := res.files[a.UUID]; ok && strings.Contains(prev.path, ".dSYM/") {
+					continue
+				}
+				res.files[a.UUID] = debugFile{cpu: a.CPU, path: path}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// lookup resolves the offset into the image of the UUID
+func (r *resolver) lookup(uuid string, offset uint64) (location, bool) {
+	uuid = normalizeUUID(uuid)
+
+	t, ok := r.tables[uuid]
+	if !ok {
+		if f, found := r.files[uuid]; found {
+			var err error
+			if t, err = loadSymbols(f.path, f.cpu); err != nil {
+				log.Warn().
+					Str("Path", f.path).
+					AnErr("Reason", err).
+					Msg("Failed to read the debug symbols")
+			}
+		}
+
+		// Caching the failures as well
+		r.tables[uuid] = t
+	}
+
+	if t == nil {
+		return location{}, false
+	}
+
+	return t.lookup(offset)
+}
+
+// normalizeUUID formats the UUID the way the Mach-O parser does, the crash reports using
+// lowercase or undashed UUIDs
+func normalizeUUID(uuid string) string {
+	u := strings.ToUpper(strings.ReplaceAll(uuid, "-", ""))
+	if len(u) != 32 {
+		return u
+	}
+
+	return fmt.Sprintf("%v-%v-%v-%v-%v", u[0:8], u[8:12], u[12:16], u[16:20], u[20:])
+}
@@ -0,0 +1,194 @@
+package symbolicate
+
+import (
+	"bytes"
+	"context"
+	"debug/macho"
+	"dothething/internal/api"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// program the crashing program, built for darwin to get a Mach-O binary with DWARF
+const program = `package main
+
+//go:noinline
+func crash(n int) int {
+	if n > 3 {
+		panic("boom")
+	}
+	return n * 2
+}
+
+func main() {
+	println(crash(1))
+}
+`
+
+const textReport = `Incident Identifier: 5E2F7A52-2C4E-4C8C-9E5A-1B2A3C4D5E6F
+Process:             Dummy [1234]
+
+Thread 0 Crashed:
+0   libsystem_kernel.dylib        	0x00000001c0d1a9e8 __pthread_kill + 8
+1   Dummy                         	0x%x 0x100a04000 + %v
+
+Binary Images:
+0x100a04000 - 0x100ffffff Dummy arm64  <%v> /private/var/containers/Bundle/Application/Dummy.app/Dummy
+0x1c0d00000 - 0x1c0d38fff libsystem_kernel.dylib arm64e  <0123456789abcdef0123456789abcdef> /usr/lib/system/libsystem_kernel.dylib
+`
+
+const ipsReport = `{"app_name":"Dummy","bug_type":"309","os_version":"iPhone OS 15.0"}
+{
+  "usedImages": [
+    {"base": 4305469440, "name": "Dummy", "uuid": "%v"},
+    {"base": 7530872832, "name": "libsystem_kernel.dylib", "uuid": "01234567-89ab-cdef-0123-456789abcdef"}
+  ],
+  "threads": [
+    {"triggered": true, "frames": [
+      {"imageIndex": 1, "imageOffset": 108008, "symbol": "__pthread_kill", "symbolLocation": 8},
+      {"imageIndex": 0, "imageOffset": %v}
+    ]}
+  ]
+}
+`
+
+type symbolicateSuite struct {
+	suite.Suite
+	dir     string
+	offset  uint64
+	subject symbolicator
+	uuid    string
+}
+
+func TestSymbolicateSuite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building a darwin binary is skipped in short mode")
+	}
+
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go tool is required to build a darwin binary")
+	}
+
+	suite.Run(t, new(symbolicateSuite))
+}
+
+func (s *symbolicateSuite) SetupSuite() {
+	dir, err := ioutil.TempDir("", "symbolicate")
+	s.Require().NoError(err)
+	s.dir = dir
+
+	src := filepath.Join(dir, "src")
+	s.Require().NoError(os.MkdirAll(src, 0755))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(src, "main.go"), []byte(program), 0644))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(src, "go.mod"), []byte("module dummy\n\ngo 1.14\n"), 0644))
+
+	bin := filepath.Join(dir, "dsyms", "Dummy")
+	cmd := exec.Command("go", "build", "-o", bin, ".")
+	cmd.Dir = src
+	cmd.Env = append(os.Environ(), "GOOS=darwin", "GOARCH=arm64", "CGO_ENABLED=0", "GOFLAGS=", "GOWORK=off")
+	out, err := cmd.CombinedOutput()
+	s.Require().NoError(err, string(out))
+
+	// The offset of the crash function into the image, 8 bytes after its start
+	f, err := macho.Open(bin)
+	s.Require().NoError(err)
+	defer f.Close()
+
+	for _, sym := range f.Symtab.Syms {
+		if sym.Name == "main.crash" {
+			s.offset = sym.Value - f.Segment("__TEXT").Addr + 8
+		}
+	}
+	s.Require().NotZero(s.offset)
+
+	for _, l := range f.Loads {
+		raw := l.Raw()
+		if f.ByteOrder.Uint32(raw) == 0x1b {
+			s.uuid = fmt.Sprintf("%x", raw[8:24])
+		}
+	}
+	s.Require().NotEmpty(s.uuid)
+
+	s.subject = symbolicator{&api.API{Config: &api.Config{}}}
+}
+
+func (s *symbolicateSuite) TearDownSuite() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *symbolicateSuite) TestSymbolicateText() {
+	// setup:
+	report := fmt.Sprintf(textReport, 0x100a04000+s.offset, s.offset, s.uuid)
+	var out bytes.Buffer
+
+	// when:
+	err := s.subject.Symbolicate(
+		context.Background(),
+		strings.NewReader(report),
+		[]string{filepath.Join(s.dir, "dsyms")},
+		&out,
+	)
+
+	// then: only the frame of the image with symbols should be rewritten
+	s.Require().NoError(err)
+	lines := strings.Split(out.String(), "\n")
+	s.Equal("0   libsystem_kernel.dylib        \t0x00000001c0d1a9e8 __pthread_kill + 8", lines[4])
+	s.Regexp(
+		regexp.MustCompile(`^1   Dummy\s+0x[0-9a-f]+ main\.crash \+ 8 \(main\.go:[4-9]\)$`),
+		lines[5],
+	)
+	s.Contains(lines[8], "Dummy arm64")
+}
+
+func (s *symbolicateSuite) TestSymbolicateIPS() {
+	// setup:
+	uuid := fmt.Sprintf("%v-%v-%v-%v-%v", s.uuid[0:8], s.uuid[8:12], s.uuid[12:16], s.uuid[16:20], s.uuid[20:])
+	report := fmt.Sprintf(ipsReport, uuid, s.offset)
+	var out bytes.Buffer
+
+	// when:
+	err := s.subject.Symbolicate(
+		context.Background(),
+		strings.NewReader(report),
+		[]string{filepath.Join(s.dir, "dsyms")},
+		&out,
+	)
+
+	// then: the header should be kept
+	s.Require().NoError(err)
+	res := out.String()
+	s.True(strings.HasPrefix(res, `{"app_name":"Dummy","bug_type":"309","os_version":"iPhone OS 15.0"}`+"\n"))
+
+	// and: the frames should be symbolicated
+	var body struct {
+		Threads []struct {
+			Frames []map[string]interface{} `json:"frames"`
+		} `json:"threads"`
+	}
+	s.Require().NoError(json.Unmarshal([]byte(res[strings.Index(res, "\n")+1:]), &body))
+
+	frames := body.Threads[0].Frames
+	s.Equal("__pthread_kill", frames[0]["symbol"])
+	s.Nil(frames[0]["sourceFile"])
+	s.Equal("main.crash", frames[1]["symbol"])
+	s.Equal(float64(8), frames[1]["symbolLocation"])
+	s.Equal("main.go", frames[1]["sourceFile"])
+	s.NotZero(frames[1]["sourceLine"])
+}
+
+func (s *symbolicateSuite) TestSymbolicateInvalidIPS() {
+	// when:
+	err := s.subject.Symbolicate(context.Background(), strings.NewReader("{}"), nil, ioutil.Discard)
+
+	// then:
+	s.Equal(ErrInvalidIPS, err)
+}
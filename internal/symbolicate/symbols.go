@@ -0,0 +1,222 @@
+package symbolicate
+
+import (
+	"debug/dwarf"
+	gomacho "debug/macho"
+	"dothething/internal/macho"
+	"errors"
+	"path"
+	"sort"
+)
+
+// The nlist type masks, only the symbols defined in a section of the binary being kept
+const (
+	nSect = 0x0e
+	nStab = 0xe0
+	nType = 0x0e
+)
+
+// location the symbolicated location of an address
+type location struct {
+	File     string
+	Function string
+	Line     int
+	// Offset the distance to the start of the function
+	Offset uint64
+}
+
+// function the address range of a function
+type function struct {
+	high uint64
+	low  uint64
+	name string
+}
+
+// unit a compile unit, the line tables being read on demand
+type unit struct {
+	entry  *dwarf.Entry
+	ranges [][2]uint64
+}
+
+// symbolTable the functions and line tables of a binary, or of its dSYM
+type symbolTable struct {
+	data  *dwarf.Data
+	funcs []function
+	// text the address the __TEXT segment is linked at, the images offsets being relative to it
+	text  uint64
+	units []unit
+}
+
+// loadSymbols reads the debug information of the architecture of the binary, the symbol table
+// being used when the binary has no debug information
+func loadSymbols(path, cpu string) (*symbolTable, error) {
+	f, c, err := macho.OpenArch(path, cpu)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	seg := f.Segment("__TEXT")
+	if seg == nil {
+		return nil, errors.New("No __TEXT segment found")
+	}
+
+	res := &symbolTable{text: seg.Addr}
+	if d, err := f.DWARF(); err == nil {
+		res.data = d
+		if err := res.readDWARF(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(res.funcs) == 0 {
+		res.readSymtab(f)
+	}
+
+	sort.Slice(res.funcs, func(i, j int) bool {
+		return res.funcs[i].low < res.funcs[j].low
+	})
+
+	return res, nil
+}
+
+// readDWARF reads the compile units and the functions of the debug information
+func (t *symbolTable) readDWARF() error {
+	r := t.data.Reader()
+	for {
+		e, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			return nil
+		}
+
+		switch e.Tag {
+		case dwarf.TagCompileUnit:
+			ranges, err := t.data.Ranges(e)
+			if err == nil {
+				t.units = append(t.units, unit{entry: e, ranges: ranges})
+			}
+
+		case dwarf.TagSubprogram:
+			ranges, err := t.data.Ranges(e)
+			if err != nil || len(ranges) == 0 {
+				continue
+			}
+
+			name := t.name(e)
+			for _, rg := range ranges {
+				t.funcs = append(t.funcs, function{low: rg[0], high: rg[1], name: name})
+			}
+		}
+	}
+}
+
+// name resolves the name of the function, the concrete instances referencing the declaration
+// holding it
+func (t *symbolTable) name(e *dwarf.Entry) string {
+	for i := 0; i < 4 && e != nil; i++ {
+		if n, ok := e.Val(dwarf.AttrName).(string); ok {
+			return n
+		}
+
+		if n, ok := e.Val(dwarf.AttrLinkageName).(string); ok {
+			return n
+		}
+
+		off, ok := e.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset)
+		if !ok {
+			if off, ok = e.Val(dwarf.AttrSpecification).(dwarf.Offset); !ok {
+				return ""
+			}
+		}
+
+		r := t.data.Reader()
+		r.Seek(off)
+		e, _ = r.Next()
+	}
+
+	return ""
+}
+
+// readSymtab reads the functions from the symbol table, each symbol extending to the next one
+func (t *symbolTable) readSymtab(f *gomacho.File) {
+	if f.Symtab == nil {
+		return
+	}
+
+	var syms []gomacho.Symbol
+	for _, s := range f.Symtab.Syms {
+		if s.Type&nStab == 0 && s.Type&nType == nSect {
+			syms = append(syms, s)
+		}
+	}
+
+	sort.Slice(syms, func(i, j int) bool {
+		return syms[i].Value < syms[j].Value
+	})
+
+	for i, s := range syms {
+		high := ^uint64(0)
+		if i+1 < len(syms) {
+			high = syms[i+1].Value
+		}
+
+		// The Apple toolchain prefixes the C symbols with an underscore
+		name := s.Name
+		if len(name) > 1 && name[0] == '_' {
+			name = name[1:]
+		}
+
+		t.funcs = append(t.funcs, function{low: s.Value, high: high, name: name})
+	}
+}
+
+// lookup resolves the offset relative to the start of the image
+func (t *symbolTable) lookup(offset uint64) (location, bool) {
+	addr := t.text + offset
+	i := sort.Search(len(t.funcs), func(i int) bool {
+		return t.funcs[i].low > addr
+	}) - 1
+
+	if i < 0 || addr >= t.funcs[i].high {
+		return location{}, false
+	}
+
+	res := location{Function: t.funcs[i].name, Offset: addr - t.funcs[i].low}
+	res.File, res.Line = t.line(addr)
+
+	return res, true
+}
+
+// line resolves the source file and line of the address, when known
+func (t *symbolTable) line(addr uint64) (string, int) {
+	for _, u := range t.units {
+		if !contains(u.ranges, addr) {
+			continue
+		}
+
+		lr, err := t.data.LineReader(u.entry)
+		if err != nil || lr == nil {
+			continue
+		}
+
+		var e dwarf.LineEntry
+		if err := lr.SeekPC(addr, &e); err == nil && e.File != nil {
+			return path.Base(e.File.Name), e.Line
+		}
+	}
+
+	return "", 0
+}
+
+func contains(ranges [][2]uint64, addr uint64) bool {
+	for _, r := range ranges {
+		if addr >= r[0] && addr < r[1] {
+			return true
+		}
+	}
+
+	return false
+}
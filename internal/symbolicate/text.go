@@ -0,0 +1,92 @@
+package symbolicate
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// 0x100a04000 - 0x100a0bfff Dummy arm64  <6f1b0c4e5d3a3b7a9c1e2f3a4b5c6d7e> /path/to/Dummy
+	textImageRegexp = regexp.MustCompile(
+		`^\s*0x([0-9a-fA-F]+)\s+-\s+0x([0-9a-fA-F]+)\s+\+?(.+?)\s+\S+\s+<([0-9a-fA-F]{32})>`,
+	)
+
+	// 0   Dummy                          0x0000000100a05f3c 0x100a04000 + 7996
+	textFrameRegexp = regexp.MustCompile(`^(\d+\s+.+?\s+0x([0-9a-fA-F]+)\s+)\S.*$`)
+)
+
+// textImage a binary image of the legacy text report
+type textImage struct {
+	high uint64
+	low  uint64
+	name string
+	uuid string
+}
+
+// symbolicateText rewrites the frames of the legacy text report, the frames of the images
+// without debug symbols being kept as is
+func symbolicateText(b []byte, r *resolver, w io.Writer) error {
+	var images []textImage
+	for _, l := range bytes.Split(b, []byte("\n")) {
+		m := textImageRegexp.FindSubmatch(l)
+		if m == nil {
+			continue
+		}
+
+		low, _ := strconv.ParseUint(string(m[1]), 16, 64)
+		high, _ := strconv.ParseUint(string(m[2]), 16, 64)
+		images = append(images, textImage{high: high, low: low, name: string(m[3]), uuid: string(m[4])})
+	}
+
+	bw := bufio.NewWriter(w)
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		l := sc.Text()
+		if m := textFrameRegexp.FindStringSubmatch(l); m != nil {
+			if loc, ok := lookupText(images, r, m[2]); ok {
+				l = m[1] + formatText(loc)
+			}
+		}
+
+		if _, err := fmt.Fprintln(bw, l); err != nil {
+			return err
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// lookupText resolves the address against the image containing it
+func lookupText(images []textImage, r *resolver, address string) (location, bool) {
+	addr, err := strconv.ParseUint(address, 16, 64)
+	if err != nil {
+		return location{}, false
+	}
+
+	for _, img := range images {
+		if addr >= img.low && addr <= img.high {
+			return r.lookup(img.uuid, addr-img.low)
+		}
+	}
+
+	return location{}, false
+}
+
+// formatText formats the location the way atos does
+func formatText(loc location) string {
+	res := fmt.Sprintf("%v + %v", loc.Function, loc.Offset)
+	if loc.File != "" {
+		res += fmt.Sprintf(" (%v:%v)", loc.File, loc.Line)
+	}
+
+	return res
+}
@@ -0,0 +1,117 @@
+package util
+
+import (
+	"dothething/internal/api"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	// EnvDeveloperDir the variable selecting the Xcode install of the developer tools
+	EnvDeveloperDir = "DEVELOPER_DIR"
+
+	// EnvKeyChain the variable exposing the keychain of the run to the build scripts
+	EnvKeyChain = "DOTHETHING_KEYCHAIN"
+
+	// Redacted replaces the values of the secrets
+	Redacted = "<redacted>"
+)
+
+// secretRegexp matches the names of the variables holding secrets
+var secretRegexp = regexp.MustCompile(`(?i)(PASSWORD|PASSPHRASE|SECRET|TOKEN|CREDENTIAL|PRIVATE|BASE64|(^|_)KEY$|AUTH)`)
+
+// envBuilder composes an environment, the variables keeping the order of their first
+// definition
+type envBuilder struct {
+	keys   []string
+	values map[string]string
+	// secrets the values masked by Redacted, whatever the name of their variable
+	secrets []string
+}
+
+// newEnvBuilder starts an environment from the inherited entries kept by the allow and deny
+// lists of patterns, like AWS_*, the values holding one of the secrets being redacted
+func newEnvBuilder(inherited, allow, deny, secrets []string) *envBuilder {
+	b := &envBuilder{values: map[string]string{}, secrets: secrets}
+	for _, e := range inherited {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+
+		if len(allow) > 0 && !matchesAny(kv[0], allow) {
+			continue
+		}
+
+		if matchesAny(kv[0], deny) {
+			continue
+		}
+
+		b.Set(kv[0], kv[1])
+	}
+
+	return b
+}
+
+// Set defines the variable, over the inherited one
+func (b *envBuilder) Set(key, value string) api.EnvBuilder {
+	if _, ok := b.values[key]; !ok {
+		b.keys = append(b.keys, key)
+	}
+	b.values[key] = value
+
+	return b
+}
+
+// Build returns the KEY=VALUE entries of the environment
+func (b *envBuilder) Build() []string {
+	res := make([]string, 0, len(b.keys))
+	for _, k := range b.keys {
+		res = append(res, k+"="+b.values[k])
+	}
+
+	return res
+}
+
+// Redacted returns the entries of the environment, the values of the secret variables and the
+// values holding a registered secret being masked
+func (b *envBuilder) Redacted() []string {
+	res := make([]string, 0, len(b.keys))
+	for _, k := range b.keys {
+		v := b.values[k]
+		if v != "" && (IsSecret(k) || b.holdsSecret(v)) {
+			v = Redacted
+		}
+		res = append(res, k+"="+v)
+	}
+
+	return res
+}
+
+// holdsSecret is true when the value contains one of the registered secrets
+func (b *envBuilder) holdsSecret(v string) bool {
+	for _, s := range b.secrets {
+		if strings.Contains(v, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsSecret is true for the variables holding secrets, like passwords and tokens
+func IsSecret(key string) bool {
+	return secretRegexp.MatchString(key)
+}
+
+// matchesAny is true when the variable name matches one of the glob patterns
+func matchesAny(key string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, err := path.Match(p, key); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -4,34 +4,175 @@ package util
 import (
 	"context"
 	"dothething/internal/api"
+	"os"
 	"os/exec"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/rs/zerolog/log"
 )
 
 type executor struct {
 	*api.API
+	mu      sync.Mutex
+	env     map[string]string
+	report  api.RunReport
+	secrets []string
 }
 
 // NewExecutor create a new instance of the implemented Cmd interface
 func NewExecutor(api *api.API) api.Executor {
-	return &executor{api}
+	return &executor{API: api, env: map[string]string{}}
 }
 
 // CommandContext run a command with context
 func (e *executor) CommandContext(ctx context.Context, cmd string, args ...string) api.Cmd {
 	log.Info().
 		Str("Cmd", cmd).
-		Strs("Args", args).
+		Strs("Args", e.redact(args)).
 		Msg("Running command with context")
 
-	return (*cmdWrapper)(exec.CommandContext(ctx, cmd, args...))
+	c := exec.CommandContext(ctx, cmd, args...)
+	c.Env = e.Environment().Build()
+
+	return (*cmdWrapper)(c)
+}
+
+// Environment starts the environment of a command from the inherited one, filtered by the
+// allow and deny lists, then applies the keychain variables and the configured ones
+func (e *executor) Environment() api.EnvBuilder {
+	return e.environment(nil)
+}
+
+// environment composes the environment, the variables of the tool being applied before the
+// keychain and the configured ones
+func (e *executor) environment(tool map[string]string) api.EnvBuilder {
+	var conf api.EnvConfig
+	if e.API.Config != nil {
+		conf = e.API.Config.Env
+	}
+
+	e.mu.Lock()
+	secrets := append([]string(nil), e.secrets...)
+	e.mu.Unlock()
+
+	b := newEnvBuilder(os.Environ(), conf.Allow, conf.Deny, secrets)
+	for _, k := range sortedKeys(tool) {
+		b.Set(k, tool[k])
+	}
+
+	if e.API.KeyChain != nil {
+		if p := e.API.KeyChain.GetPath(); p != "" {
+			b.Set(EnvKeyChain, p)
+		}
+	}
+
+	for _, kv := range conf.Vars {
+		if !strings.Contains(kv, "=") {
+			log.Warn().Str("Variable", kv).Msg("Ignoring the variable, expecting KEY=VALUE")
+			continue
+		}
+		setEntry(b, kv)
+	}
+
+	return b
+}
+
+// Redact masks the secrets, like the keychain passwords, in the logged arguments of the
+// commands
+func (e *executor) Redact(secrets ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, v := range secrets {
+		if v != "" {
+			e.secrets = append(e.secrets, v)
+		}
+	}
+}
+
+// redact the arguments, the secrets being masked
+func (e *executor) redact(args []string) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.secrets) == 0 {
+		return args
+	}
+
+	res := make([]string, len(args))
+	for i, a := range args {
+		for _, v := range e.secrets {
+			a = strings.ReplaceAll(a, v, Redacted)
+		}
+		res[i] = a
+	}
+
+	return res
+}
+
+// Report the run report, holding the environment of the last xcode command
+func (e *executor) Report() api.RunReport {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.report
+}
+
+// SetXCodeEnv defines an environment variable of the xcode commands created afterwards
+func (e *executor) SetXCodeEnv(key, value string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	log.Info().Str("Key", key).Str("Value", value).Msg("Setting xcode command environment")
+	e.env[key] = value
+}
+
+// UnsetXCodeEnv removes the variable defined through SetXCodeEnv
+func (e *executor) UnsetXCodeEnv(key string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.env, key)
+}
+
+// toolEnv the variables of the xcode commands, the developer folder of the install included
+func (e *executor) toolEnv(i *api.Install) map[string]string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	res := map[string]string{EnvDeveloperDir: i.DevPath}
+	for k, v := range e.env {
+		res[k] = v
+	}
+
+	return res
+}
+
+// setEntry sets the KEY=VALUE entry
+func setEntry(b api.EnvBuilder, kv string) {
+	p := strings.SplitN(kv, "=", 2)
+	if len(p) == 2 && p[0] != "" {
+		b.Set(p[0], p[1])
+	}
+}
+
+// sortedKeys the names of the variables, sorted
+func sortedKeys(m map[string]string) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+
+	return res
 }
 
 // CommandContext run a command with context
 func (e *executor) XCodeCommandContext(ctx context.Context, args ...string) (*api.Cmd, error) {
 	log.Info().
-		Strs("Args", args).
+		Strs("Args", e.redact(args)).
 		Msg("Running XCode command with context")
 
 	i, err := e.API.XcodeSelectService.Find(ctx)
@@ -39,9 +180,15 @@ func (e *executor) XCodeCommandContext(ctx context.Context, args ...string) (*ap
 		return nil, err
 	}
 
+	env := e.environment(e.toolEnv(i))
+
+	e.mu.Lock()
+	e.report.Environment = env.Redacted()
+	e.mu.Unlock()
+
 	// executing the command
 	cmd := e.CommandContext(ctx, "xcodebuild", args...)
-	cmd.SetEnv([]string{"DEVELOPER_DIR", i.DevPath})
+	cmd.SetEnv(env.Build())
 
 	return &cmd, nil
 }
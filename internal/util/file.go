@@ -70,6 +70,10 @@ func (f IoUtilFileService) IsDir(path string) (bool, error) {
 	return stat.IsDir(), nil
 }
 
+func (f IoUtilFileService) Stat(path string) (os.FileInfo, error) {
+	return os.Stat(path)
+}
+
 // TempFilePath Generate a temporary file path
 func TempFilePath(prefix, suffix string) (string, error) {
 	p, err := RandomFileName(prefix, suffix)
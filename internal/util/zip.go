@@ -0,0 +1,64 @@
+package util
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ZipFolder adds the content of the folder to the zip file, under the prefix when provided,
+// keeping the permissions and the symbolic links
+func ZipFolder(w *zip.Writer, src, prefix string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+
+		name := path.Join(prefix, filepath.ToSlash(rel))
+		if name == "." {
+			return nil
+		}
+
+		h, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		h.Name = name
+		if info.IsDir() {
+			h.Name += "/"
+		} else {
+			h.Method = zip.Deflate
+		}
+
+		e, err := w.CreateHeader(h)
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+
+			_, err = e.Write([]byte(target))
+			return err
+		}
+
+		r, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		_, err = io.Copy(e, r)
+		return err
+	})
+}
@@ -9,6 +9,8 @@ import (
 
 type MockExecutor struct {
 	mock.Mock
+	// Secrets the values masked through Redact
+	Secrets []string
 }
 
 // CommandContext allow to execute a command with Context
@@ -17,6 +19,44 @@ func (m *MockExecutor) CommandContext(ctx context.Context, cmd string, args ...s
 	return c.Get(0).(api.Cmd)
 }
 
+// Environment starts the environment of a command
+func (m *MockExecutor) Environment() api.EnvBuilder {
+	c := m.Called()
+	return c.Get(0).(api.EnvBuilder)
+}
+
+// Redact records the secrets
+func (m *MockExecutor) Redact(secrets ...string) {
+	m.Secrets = append(m.Secrets, secrets...)
+}
+
+// Report the run report
+func (m *MockExecutor) Report() api.RunReport {
+	c := m.Called()
+	return c.Get(0).(api.RunReport)
+}
+
+// SetXCodeEnv defines an environment variable of the xcode commands
+func (m *MockExecutor) SetXCodeEnv(key, value string) {
+	m.Called(key, value)
+}
+
+// UnsetXCodeEnv removes an environment variable of the xcode commands
+func (m *MockExecutor) UnsetXCodeEnv(key string) {
+	m.Called(key)
+}
+
+// XCodeCommandContext allow to execute a xcode command with Context
+func (m *MockExecutor) XCodeCommandContext(ctx context.Context, args ...string) (*api.Cmd, error) {
+	c := m.Called(ctx, args)
+	if c.Get(0) == nil {
+		return nil, c.Error(1)
+	}
+
+	cmd := c.Get(0).(api.Cmd)
+	return &cmd, c.Error(1)
+}
+
 func (m *MockExecutor) MockCommandContext(cmd string, args []string, res string, err error) {
 	c := new(MockExecutorCmd)
 	c.On("Output").Return(res, err)
@@ -61,3 +61,18 @@ func (f *MockFileService) IsDir(path string) (bool, error) {
 
 	return args.Bool(0), nil
 }
+
+func (f *MockFileService) Stat(path string) (os.FileInfo, error) {
+	args := f.Called(path)
+
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	a := args.Get(0)
+	info, ok := a.(os.FileInfo)
+	if !ok {
+		panic("Wrong type")
+	}
+	return info, nil
+}
@@ -0,0 +1,229 @@
+package utiltest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// NewMachO creates a thin arm64 Mach-O executable, with the code signature when provided
+func NewMachO(signature []byte) []byte {
+	return NewMachOWithUUID(0x0100000c, nil, signature)
+}
+
+// NewMachOWithUUID creates a thin Mach-O executable of the CPU type, with the LC_UUID and the
+// code signature when provided
+func NewMachOWithUUID(cpu uint32, uuid []byte, signature []byte) []byte {
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+
+	var cmds bytes.Buffer
+	ncmds := uint32(0)
+	if uuid != nil {
+		// LC_UUID: cmd, cmdsize, uuid
+		binary.Write(&cmds, le, []uint32{0x1b, 24})
+		cmds.Write(uuid)
+		ncmds++
+	}
+
+	if signature != nil {
+		// LC_CODE_SIGNATURE: cmd, cmdsize, dataoff, datasize
+		off := uint32(32 + cmds.Len() + 16)
+		binary.Write(&cmds, le, []uint32{0x1d, 16, off, uint32(len(signature))})
+		ncmds++
+	}
+
+	// mach_header_64: magic, cputype, cpusubtype, filetype (execute), ncmds, sizeofcmds,
+	// flags, reserved
+	binary.Write(&buf, le, []uint32{0xfeedfacf, cpu, 0, 2, ncmds, uint32(cmds.Len()), 0, 0})
+	buf.Write(cmds.Bytes())
+	buf.Write(signature)
+
+	return buf.Bytes()
+}
+
+// MachOSection the content of a section of a Mach-O binary
+type MachOSection struct {
+	Data    []byte
+	Name    string
+	Segment string
+}
+
+// NewMachOBinary creates a thin Mach-O binary of the CPU type and file type, with the raw
+// load commands and a segment for each of the sections
+func NewMachOBinary(cpu, filetype uint32, loads [][]byte, sections ...MachOSection) []byte {
+	le := binary.LittleEndian
+
+	var cmds bytes.Buffer
+	for _, l := range loads {
+		cmds.Write(l)
+	}
+
+	// A segment for each section, its data following the commands
+	const segmentSize = 72 + 80
+	off := uint64(32 + cmds.Len() + segmentSize*len(sections))
+	for _, s := range sections {
+		size := uint64(len(s.Data))
+
+		// segment_command_64: cmd, cmdsize, segname, vmaddr, vmsize, fileoff, filesize,
+		// maxprot, initprot, nsects, flags
+		binary.Write(&cmds, le, []uint32{0x19, segmentSize})
+		cmds.Write(name16(s.Segment))
+		binary.Write(&cmds, le, []uint64{0, size, off, size})
+		binary.Write(&cmds, le, []uint32{7, 7, 1, 0})
+
+		// section_64: sectname, segname, addr, size, offset, align, reloff, nreloc, flags,
+		// reserved1, reserved2, reserved3
+		cmds.Write(name16(s.Name))
+		cmds.Write(name16(s.Segment))
+		binary.Write(&cmds, le, []uint64{0, size})
+		binary.Write(&cmds, le, []uint32{uint32(off), 0, 0, 0, 0, 0, 0, 0})
+		off += size
+	}
+
+	var buf bytes.Buffer
+	ncmds := uint32(len(loads) + len(sections))
+	binary.Write(&buf, le, []uint32{0xfeedfacf, cpu, 0, filetype, ncmds, uint32(cmds.Len()), 0, 0})
+	buf.Write(cmds.Bytes())
+	for _, s := range sections {
+		buf.Write(s.Data)
+	}
+
+	return buf.Bytes()
+}
+
+// name16 pads the segment or section name to 16 bytes
+func name16(name string) []byte {
+	res := make([]byte, 16)
+	copy(res, name)
+
+	return res
+}
+
+// NewBuildVersionLoad creates a LC_BUILD_VERSION load command, the versions being encoded
+// as xxxx.yy.zz nibbles
+func NewBuildVersionLoad(platform, minos, sdk uint32) []byte {
+	return loadCommand(0x32, []uint32{platform, minos, sdk, 0}, "")
+}
+
+// NewDylibLoad creates a dylib load command, like LC_LOAD_DYLIB or LC_LOAD_WEAK_DYLIB
+func NewDylibLoad(cmd uint32, name string) []byte {
+	return loadCommand(cmd, []uint32{24, 0, 0x10000, 0x10000}, name)
+}
+
+// NewRpathLoad creates a LC_RPATH load command
+func NewRpathLoad(path string) []byte {
+	return loadCommand(0x8000001c, []uint32{12}, path)
+}
+
+// NewEncryptionLoad creates a LC_ENCRYPTION_INFO_64 load command
+func NewEncryptionLoad(cryptid uint32) []byte {
+	return loadCommand(0x2c, []uint32{0x4000, 0x1000, cryptid, 0}, "")
+}
+
+// loadCommand creates a load command of the fields and of the string following them, padded
+// to 8 bytes
+func loadCommand(cmd uint32, fields []uint32, s string) []byte {
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+
+	size := 8 + 4*len(fields)
+	if s != "" {
+		size += len(s) + 1
+	}
+	size = (size + 7) &^ 7
+
+	binary.Write(&buf, le, []uint32{cmd, uint32(size)})
+	binary.Write(&buf, le, fields)
+	buf.WriteString(s)
+	buf.Write(make([]byte, size-buf.Len()))
+
+	return buf.Bytes()
+}
+
+// NewStaticLibrary creates a BSD ar archive of the object files, with a symbol table
+func NewStaticLibrary(objects ...[]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("!<arch>\n")
+
+	members := append([][]byte{make([]byte, 8)}, objects...)
+	for i, m := range members {
+		name := fmt.Sprintf("object%v.o", i)
+		if i == 0 {
+			name = "__.SYMDEF SORTED"
+		}
+
+		// The long names are stored at the start of the content, padded to 8 bytes
+		n := []byte(name)
+		n = append(n, make([]byte, 8-len(n)%8)...)
+		fmt.Fprintf(&buf, "%-16s%-12d%-6d%-6d%-8o%-10d`\n", fmt.Sprintf("#1/%v", len(n)), 0, 0, 0, 0644, len(n)+len(m))
+		buf.Write(n)
+		buf.Write(m)
+		if (len(n)+len(m))%2 != 0 {
+			buf.WriteByte('\n')
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// NewFatMachO creates a universal binary of the thin binaries
+func NewFatMachO(cpus []uint32, slices ...[]byte) []byte {
+	var buf bytes.Buffer
+	be := binary.BigEndian
+
+	// fat_header: magic, nfat_arch, then the fat_arch of each slice: cputype, cpusubtype,
+	// offset, size, align
+	binary.Write(&buf, be, []uint32{0xcafebabe, uint32(len(slices))})
+	off := uint32(8 + 20*len(slices))
+	for i, s := range slices {
+		binary.Write(&buf, be, []uint32{cpus[i], 0, off, uint32(len(s)), 0})
+		off += uint32(len(s))
+	}
+
+	for _, s := range slices {
+		buf.Write(s)
+	}
+
+	return buf.Bytes()
+}
+
+// NewCodeSignature creates a code signature superblob with a code directory, the entitlements
+// and the CMS signature, an empty CMS signature standing for an ad-hoc signature
+func NewCodeSignature(identifier, teamID string, entitlements, cms []byte) []byte {
+	be := binary.BigEndian
+
+	// code directory, version 0x20200 supporting the team identifier
+	cd := make([]byte, 52)
+	be.PutUint32(cd[0:], 0xfade0c02)
+	be.PutUint32(cd[8:], 0x20200)
+	be.PutUint32(cd[20:], 52)
+	cd = append(append(cd, identifier...), 0)
+	be.PutUint32(cd[48:], uint32(len(cd)))
+	cd = append(append(cd, teamID...), 0)
+	be.PutUint32(cd[4:], uint32(len(cd)))
+
+	ent := make([]byte, 8)
+	be.PutUint32(ent[0:], 0xfade7171)
+	be.PutUint32(ent[4:], uint32(8+len(entitlements)))
+	ent = append(ent, entitlements...)
+
+	wrapper := make([]byte, 8)
+	be.PutUint32(wrapper[0:], 0xfade0b01)
+	be.PutUint32(wrapper[4:], uint32(8+len(cms)))
+	wrapper = append(wrapper, cms...)
+
+	// superblob: magic, length, count, then the index of the slots
+	header := make([]byte, 12+3*8)
+	be.PutUint32(header[0:], 0xfade0cc0)
+	be.PutUint32(header[4:], uint32(len(header)+len(cd)+len(ent)+len(wrapper)))
+	be.PutUint32(header[8:], 3)
+	be.PutUint32(header[12:], 0)
+	be.PutUint32(header[16:], uint32(len(header)))
+	be.PutUint32(header[20:], 5)
+	be.PutUint32(header[24:], uint32(len(header)+len(cd)))
+	be.PutUint32(header[28:], 0x10000)
+	be.PutUint32(header[32:], uint32(len(header)+len(cd)+len(ent)))
+
+	return append(append(append(header, cd...), ent...), wrapper...)
+}
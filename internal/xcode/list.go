@@ -1,29 +1,57 @@
 package xcode
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"dothething/internal/api"
 	"dothething/internal/util"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
 	logr "github.com/sirupsen/logrus"
 )
 
 const (
-	// MdFind spotlight search executable
-	MdFind = "mdfind"
+	// XCodeSelect the executable printing the selected developer folder
+	XCodeSelect = "xcode-select"
 
-	// XCodeBundleIdentifier What spotlight should look for to identify Xcode installs
-	BundleIdentifier = "kMDItemCFBundleIdentifier == 'com.apple.dt.Xcode'"
+	// FlagPrintPath print the path of the selected developer folder
+	FlagPrintPath = "-p"
+
+	// EnvDeveloperDir the variable overriding the selected developer folder
+	EnvDeveloperDir = "DEVELOPER_DIR"
+
+	// BundleIdentifier the bundle identifier of the Xcode application
+	BundleIdentifier = "com.apple.dt.Xcode"
 
 	// ContentPListFile path to the Info plist file in to the Xcode app bundle
 	ContentPListFile = "/Contents/Info.plist"
+
+	// VersionPListFile path to the version plist file in to the Xcode app bundle
+	VersionPListFile = "/Contents/version.plist"
+
+	// developerSuffix the developer folder of the Xcode app bundle
+	developerSuffix = "/Contents/Developer"
+
+	// appPattern the pattern of the Xcode applications of the search folders
+	appPattern = "Xcode*.app"
+
+	// betaBuild the first build number of the beta releases, like 15A5160n
+	betaBuild = 5000
 )
 
+// DefaultSearchPaths the folders searched for the Xcode installs when none is configured
+var DefaultSearchPaths = []string{"/Applications", "/Applications/" + appPattern}
+
+// buildRegexp splits the build number, like 15A240d, into the major version, the minor
+// letter, the build and the optional patch letter
+var buildRegexp = regexp.MustCompile(`^(\d+)([A-Z])(\d+)([a-z]?)$`)
+
 // listService Service to retrieve the list of xcode installation on the system
 type listService struct{ *api.API }
 
@@ -32,41 +60,84 @@ func NewXCodeListService(api *api.API) api.ListService {
 	return listService{api}
 }
 
-// List return all system XCode installation
+// List return all system XCode installation, found into the search paths, the DEVELOPER_DIR
+// and the folder selected by xcode-select, without duplicates
 func (s listService) List(ctx context.Context) ([]*api.Install, error) {
-	data, err := s.spotlightSearch(ctx)
-	if err != nil {
-		return nil, err
+	var result []*api.Install
+	seen := map[string]bool{}
+	for _, path := range s.candidates(ctx) {
+		key := path
+		if p, err := filepath.EvalSymlinks(path); err == nil {
+			key = p
+		}
+
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		i, err := s.parseEntry(path)
+		if err != nil {
+			logr.Error(err)
+			continue
+		}
+
+		if i != nil {
+			result = append(result, i)
+		}
 	}
 
-	return s.parseSpotlightSearchResult(bytes.NewReader(data))
+	return result, nil
 }
 
-func (s listService) spotlightSearch(ctx context.Context) ([]byte, error) {
-	return s.API.Exec.
-		CommandContext(ctx, MdFind, BundleIdentifier).
-		Output()
-}
+// candidates resolves the application paths of the search paths, the DEVELOPER_DIR and
+// xcode-select, in that order
+func (s listService) candidates(ctx context.Context) []string {
+	var res []string
 
-func (s listService) parseSpotlightSearchResult(reader io.Reader) ([]*api.Install, error) {
-	var result []*api.Install
-	scanner := bufio.NewScanner(reader)
+	paths := s.API.Config.XCodeSearchPaths
+	if len(paths) == 0 {
+		paths = DefaultSearchPaths
+	}
 
-	for scanner.Scan() {
-		path := scanner.Text()
-		i, err := s.parseSpotlightEntry(path)
+	for _, p := range paths {
+		matches, err := filepath.Glob(p)
 		if err != nil {
 			logr.Error(err)
 			continue
 		}
 
-		result = append(result, i)
+		for _, m := range matches {
+			if strings.HasSuffix(m, ".app") {
+				res = append(res, filepath.Clean(m))
+				continue
+			}
+
+			// A folder holding the applications
+			apps, _ := filepath.Glob(filepath.Join(m, appPattern))
+			res = append(res, apps...)
+		}
 	}
 
-	return result, nil
+	if dir, ok := os.LookupEnv(EnvDeveloperDir); ok && dir != "" {
+		res = append(res, appPath(dir))
+	}
+
+	if b, err := s.API.Exec.CommandContext(ctx, XCodeSelect, FlagPrintPath).Output(); err == nil {
+		if dir := strings.TrimSpace(string(b)); dir != "" {
+			res = append(res, appPath(dir))
+		}
+	}
+
+	return res
+}
+
+// appPath the application path of the developer folder
+func appPath(dir string) string {
+	return strings.TrimSuffix(filepath.Clean(dir), developerSuffix)
 }
 
-func (s listService) parseSpotlightEntry(path string) (*api.Install, error) {
+func (s listService) parseEntry(path string) (*api.Install, error) {
 	if valid, err := s.validate(path); err != nil || !valid {
 		return nil, err
 	}
@@ -91,7 +162,22 @@ func (s listService) resolveXcode(path string) (*api.Install, error) {
 		return nil, err
 	}
 
-	return s.resolveInstall(path, bytes.NewReader(fb))
+	i, err := s.resolveInstall(path, bytes.NewReader(fb))
+	if err != nil || i == nil {
+		return i, err
+	}
+
+	// The beta releases being named after their channel, like Xcode-beta.app, unless the
+	// build number of version.plist tells otherwise
+	i.Beta = strings.Contains(strings.ToLower(filepath.Base(path)), "beta")
+	i.GM = !i.Beta
+	if vb, err := s.API.FileService.OpenAndReadFileContent(filepath.Join(path, VersionPListFile)); err == nil {
+		if err := resolveVersion(i, bytes.NewReader(vb)); err != nil {
+			logr.Error(err)
+		}
+	}
+
+	return i, nil
 }
 
 func (s listService) resolveInstall(path string, r io.ReadSeeker) (*api.Install, error) {
@@ -100,15 +186,52 @@ func (s listService) resolveInstall(path string, r io.ReadSeeker) (*api.Install,
 		return nil, err
 	}
 
+	// Skipping the other applications of the search paths
+	if info.BundleIdentifier != "" && info.BundleIdentifier != BundleIdentifier {
+		return nil, nil
+	}
+
 	return &api.Install{
-		DevPath:       fmt.Sprintf("%v/Contents/Developer", path),
+		DevPath:       fmt.Sprintf("%v%v", path, developerSuffix),
 		Path:          path,
 		Version:       info.Version,
 		BundleVersion: info.BundleVersion,
 	}, nil
 }
 
+// resolveVersion fills the build number of the install and whether it is a beta release
+func resolveVersion(i *api.Install, r io.ReadSeeker) error {
+	var v versionPlist
+	if err := util.DecodeFile(r, &v); err != nil {
+		return err
+	}
+
+	i.Build = v.ProductBuildVersion
+	i.Beta = i.Beta || isBetaBuild(v.ProductBuildVersion)
+	i.GM = !i.Beta
+
+	return nil
+}
+
+// isBetaBuild is true for the builds of the beta range, like 15A5160n, the releases having
+// lower build numbers, like 15A240d
+func isBetaBuild(build string) bool {
+	m := buildRegexp.FindStringSubmatch(build)
+	if m == nil {
+		return false
+	}
+
+	n, err := strconv.Atoi(m[3])
+	return err == nil && n >= betaBuild
+}
+
 type infoPlist struct {
-	BundleVersion string `plist:"CFBundleVersion"`
-	Version       string `plist:"CFBundleShortVersionString"`
+	BundleIdentifier string `plist:"CFBundleIdentifier"`
+	BundleVersion    string `plist:"CFBundleVersion"`
+	Version          string `plist:"CFBundleShortVersionString"`
+}
+
+// versionPlist the content of the version.plist of the Xcode app bundle
+type versionPlist struct {
+	ProductBuildVersion string `plist:"ProductBuildVersion"`
 }
@@ -2,12 +2,19 @@ package xcode
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"dothething/internal/api"
 	"dothething/internal/util"
+	"dothething/internal/utiltest"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -95,3 +102,73 @@ func (s *XCodeListTestSuite) TestResolveInstall() {
 		})
 	}
 }
+
+func xcodeVersionPListFile(build string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+	<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+	<plist version="1.0">
+		<dict>
+			<key>ProductBuildVersion</key>
+			<string>%v</string>
+		</dict>
+	</plist>`, build)
+}
+
+func newXcodeApp(t *testing.T, path, version, build string) {
+	require.NoError(t, os.MkdirAll(filepath.Join(path, "Contents"), 0755))
+	require.NoError(t, ioutil.WriteFile(path+ContentPListFile, []byte(xcodeContentPListFile(version)), 0644))
+	require.NoError(t, ioutil.WriteFile(path+VersionPListFile, []byte(xcodeVersionPListFile(build)), 0644))
+}
+
+func TestList(t *testing.T) {
+	// setup: a release linked as Xcode.app, a beta, an application of a custom folder and the
+	// one selected by xcode-select
+	dir, err := ioutil.TempDir("", "Applications")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	newXcodeApp(t, filepath.Join(dir, "Xcode-15.0.app"), "15.0", "15A240d")
+	require.NoError(t, os.Symlink(filepath.Join(dir, "Xcode-15.0.app"), filepath.Join(dir, "Xcode.app")))
+	newXcodeApp(t, filepath.Join(dir, "Xcode-beta.app"), "15.1", "15C5028h")
+	newXcodeApp(t, filepath.Join(dir, "Custom", "Xcode-14.3.app"), "14.3", "14E222b")
+	newXcodeApp(t, filepath.Join(dir, "Selected.app"), "14.2", "14C18")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "Simulator.app"), 0755))
+
+	exec := new(utiltest.MockExecutor)
+	exec.MockCommandContext(XCodeSelect, []string{FlagPrintPath}, filepath.Join(dir, "Selected.app", "Contents", "Developer")+"\n", nil)
+
+	os.Setenv(EnvDeveloperDir, filepath.Join(dir, "Xcode-15.0.app", "Contents", "Developer"))
+	defer os.Unsetenv(EnvDeveloperDir)
+
+	a := &api.API{
+		Config:      &api.Config{XCodeSearchPaths: []string{dir, filepath.Join(dir, "Custom", "*.app")}},
+		Exec:        exec,
+		FileService: util.NewFileService(),
+	}
+
+	// when:
+	res, err := NewXCodeListService(a).List(context.Background())
+
+	// then: the installs are deduplicated by path, the symbolic link included
+	require.NoError(t, err)
+	var builds []string
+	for _, i := range res {
+		builds = append(builds, i.Build)
+	}
+	assert.Equal(t, []string{"15A240d", "15C5028h", "14E222b", "14C18"}, builds)
+
+	assert.Equal(t, filepath.Join(dir, "Xcode-15.0.app", "Contents", "Developer"), res[0].DevPath)
+	assert.True(t, res[0].GM)
+	assert.False(t, res[0].Beta)
+	assert.True(t, res[1].Beta)
+	assert.False(t, res[1].GM)
+	assert.Equal(t, "14.2", res[3].BundleVersion)
+}
+
+func TestIsBetaBuild(t *testing.T) {
+	assert.False(t, isBetaBuild("15A240d"))
+	assert.False(t, isBetaBuild("14C18"))
+	assert.True(t, isBetaBuild("15A5160n"))
+	assert.True(t, isBetaBuild("15C5028h"))
+	assert.False(t, isBetaBuild("invalid"))
+}
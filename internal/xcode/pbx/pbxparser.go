@@ -5,9 +5,13 @@ func (prj PBXProjRaw) Parse() PBXProject {
 
 	var tgs []NativeTarget
 
-	for _, tgt := range prj.GetRoot().Targets.GetList(prj) {
+	root := prj.GetRoot()
+	for _, tgt := range root.Targets.GetList(prj) {
 		tgs = append(tgs, c.ToNativeTarget(tgt))
 	}
 
-	return PBXProject{Targets: tgs}
+	return PBXProject{
+		BuildConfigurationList: c.ToXCConfigurationList(root.BuildConfigurationList.Get(prj)),
+		Targets:                tgs,
+	}
 }
@@ -5,7 +5,9 @@ import (
 )
 
 type PBXProject struct {
-	Targets []NativeTarget
+	// BuildConfigurationList the project level build configurations, inherited by the targets
+	BuildConfigurationList XCConfigurationList
+	Targets                []NativeTarget
 }
 
 func (p PBXProject) FindTargetByName(name string) (NativeTarget, error) {
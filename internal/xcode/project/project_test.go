@@ -83,6 +83,13 @@ func TestParsing(t *testing.T) {
 
 	// and:
 	assert.NoError(t, err)
+
+	// and: the project configurations
+	cfg, err := pj.BuildConfigurationList.FindConfiguration("Release")
+	assert.NoError(t, err)
+	assert.Equal(t, "iphoneos", cfg.BuildSettings["SDKROOT"])
+	assert.Equal(t, "8.0", cfg.BuildSettings["IPHONEOS_DEPLOYMENT_TARGET"])
+	assert.Equal(t, "Release", pj.BuildConfigurationList.DefaultConfigurationName)
 }
 
 func makeTarget(name string,
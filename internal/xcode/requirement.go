@@ -0,0 +1,163 @@
+package xcode
+
+import (
+	"bufio"
+	"bytes"
+	"dothething/internal/api"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+const (
+	// VersionFile the file of the project root holding the Xcode requirement
+	VersionFile = ".xcode-version"
+
+	// RequirementLatest selects the latest release installed, the default requirement
+	RequirementLatest = "latest"
+)
+
+// The release channels of the requirements
+const (
+	ChannelBeta    = "beta"
+	ChannelRelease = "release"
+)
+
+var (
+	// channelRegexp matches the channel selectors, like "15.1 beta 2", "15.1-beta", "15.0 RC"
+	// or "15.0 Release Candidate", the beta number being ignored as the installs do not
+	// carry it
+	channelRegexp = regexp.MustCompile(`(?i)^(.*?)[\s-]*\b(beta|rc|release candidate|gm)(?:[\s.]*\d+)?$`)
+
+	// versionRegexp splits a version into its numeric part and its pre-release part
+	versionRegexp = regexp.MustCompile(`^(\d+(?:\.\d+){0,2})[\s-]*(.*)$`)
+
+	// nonAlphaNumRegexp the characters to strip from the pre-release parts
+	nonAlphaNumRegexp = regexp.MustCompile(`[^0-9A-Za-z]+`)
+)
+
+// requirement the Xcode install to select, either an exact build number, a version range or
+// the latest install, restricted to a release channel
+type requirement struct {
+	build   string
+	channel string
+	r       semver.Range
+}
+
+// parseRequirement parses the requirement, like 15A240d, "15.0.1", ">=14.3.0 <15.0.0",
+// "15.1 beta" or "latest", the empty requirement selecting the latest release
+func parseRequirement(req string) (requirement, error) {
+	req = strings.TrimSpace(req)
+	if buildRegexp.MatchString(req) {
+		return requirement{build: req}, nil
+	}
+
+	var res requirement
+	if m := channelRegexp.FindStringSubmatch(req); m != nil {
+		req = strings.TrimSpace(m[1])
+		res.channel = ChannelRelease
+		if strings.EqualFold(m[2], ChannelBeta) {
+			res.channel = ChannelBeta
+		}
+	}
+
+	switch {
+	case req == "" || strings.EqualFold(req, RequirementLatest):
+		if res.channel == "" {
+			res.channel = ChannelRelease
+		}
+		return res, nil
+
+	case strings.ContainsAny(req[:1], "<>=!~^"):
+		r, err := semver.ParseRange(req)
+		if err != nil {
+			return res, err
+		}
+		res.r = r
+
+	default:
+		// A plain version, like 15.0, matching this version only
+		v, err := parseVersion(req)
+		if err != nil {
+			return res, err
+		}
+		res.r = func(o semver.Version) bool {
+			return o.Major == v.Major && o.Minor == v.Minor && o.Patch == v.Patch
+		}
+	}
+
+	// The ranges select the releases unless a beta is required
+	if res.channel == "" {
+		res.channel = ChannelRelease
+	}
+
+	return res, nil
+}
+
+// matches is true when the install fulfills the requirement
+func (q requirement) matches(i *api.Install, isMatching func(*api.Install, semver.Range) (bool, error)) (bool, error) {
+	if q.build != "" {
+		return i.Build == q.build, nil
+	}
+
+	if q.channel == ChannelRelease && i.Beta {
+		return false, nil
+	}
+
+	if q.channel == ChannelBeta && !i.Beta {
+		return false, nil
+	}
+
+	if q.r == nil {
+		return true, nil
+	}
+
+	return isMatching(i, q.r)
+}
+
+// parseVersion parses the version tolerantly, like 15.0, the pre-release part of the beta
+// versions, like "15.1 beta 2", being kept as a semver pre-release
+func parseVersion(s string) (semver.Version, error) {
+	m := versionRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return semver.ParseTolerant(s)
+	}
+
+	v, err := semver.ParseTolerant(m[1])
+	if err != nil {
+		return v, err
+	}
+
+	if pre := strings.Trim(nonAlphaNumRegexp.ReplaceAllString(m[2], "."), "."); pre != "" {
+		for _, p := range strings.Split(pre, ".") {
+			prv, err := semver.NewPRVersion(p)
+			if err != nil {
+				return v, err
+			}
+			v.Pre = append(v.Pre, prv)
+		}
+	}
+
+	return v, nil
+}
+
+// readVersionFile reads the requirement of the .xcode-version file of the project root, the
+// first line not being a comment
+func readVersionFile(project string) string {
+	b, err := ioutil.ReadFile(filepath.Join(filepath.Dir(project), VersionFile))
+	if err != nil {
+		return ""
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		if l := strings.TrimSpace(sc.Text()); l != "" && !strings.HasPrefix(l, "#") {
+			return l
+		}
+	}
+
+	return ""
+}
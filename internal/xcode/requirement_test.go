@@ -0,0 +1,99 @@
+package xcode
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dothething/internal/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRequirement(t *testing.T) {
+	release := api.Install{Version: "15.0", Build: "15A240d"}
+	patch := api.Install{Version: "15.0.1", Build: "15A507"}
+	beta := api.Install{Version: "15.1", Build: "15C5028h", Beta: true}
+
+	cases := []struct {
+		req     string
+		matches []api.Install
+	}{
+		{req: "", matches: []api.Install{release, patch}},
+		{req: "latest", matches: []api.Install{release, patch}},
+		{req: "latest beta", matches: []api.Install{beta}},
+		{req: "15A240d", matches: []api.Install{release}},
+		{req: "15C5028h", matches: []api.Install{beta}},
+		{req: "15.0", matches: []api.Install{release}},
+		{req: "15.0 RC", matches: []api.Install{release}},
+		{req: "15.0 Release Candidate", matches: []api.Install{release}},
+		{req: ">=15.0.0", matches: []api.Install{release, patch}},
+		{req: "15.1 beta 2", matches: []api.Install{beta}},
+		{req: "15.1-beta", matches: []api.Install{beta}},
+		{req: "beta", matches: []api.Install{beta}},
+		{req: "14.3", matches: nil},
+	}
+
+	s := &selectService{}
+	for _, c := range cases {
+		t.Run(c.req, func(t *testing.T) {
+			// when:
+			r, err := parseRequirement(c.req)
+			require.NoError(t, err)
+
+			// then:
+			var res []api.Install
+			for _, i := range []api.Install{release, patch, beta} {
+				i := i
+				ok, err := r.matches(&i, s.isMatchingRequirement)
+				require.NoError(t, err)
+				if ok {
+					res = append(res, i)
+				}
+			}
+			assert.Equal(t, c.matches, res)
+		})
+	}
+
+	// when:
+	_, err := parseRequirement("wrong")
+
+	// then:
+	assert.Error(t, err)
+}
+
+func TestParseVersion(t *testing.T) {
+	v, err := parseVersion("15.1 beta 2")
+	require.NoError(t, err)
+	assert.Equal(t, "15.1.0-beta.2", v.String())
+
+	v, err = parseVersion("15")
+	require.NoError(t, err)
+	assert.Equal(t, "15.0.0", v.String())
+
+	_, err = parseVersion("toto")
+	assert.Error(t, err)
+}
+
+func TestReadVersionFile(t *testing.T) {
+	// setup:
+	dir, err := ioutil.TempDir("", "project")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	project := filepath.Join(dir, "Dummy.xcodeproj")
+
+	// when: the file is missing
+	res := readVersionFile(project)
+
+	// then:
+	assert.Empty(t, res)
+
+	// when:
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, VersionFile), []byte("# Xcode of the CI\n\n15.0.1\n"), 0644))
+	res = readVersionFile(project)
+
+	// then:
+	assert.Equal(t, "15.0.1", res)
+}
@@ -5,6 +5,8 @@ import (
 	"dothething/internal/api"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/blang/semver"
 	"github.com/rs/zerolog/log"
@@ -28,19 +30,23 @@ func NewSelectService(api *api.API) api.SelectService {
 	return selectService{api}
 }
 
-// Find allow to resolve a XCode install by required vesion
+// Find allow to resolve a XCode install by required vesion, the requirement of the
+// configuration prevailing over the one of the .xcode-version file of the project
 func (s selectService) Find(ctx context.Context) (*api.Install, error) {
+	req := s.API.Config.XCodeVersion
+	if req == "" {
+		req = readVersionFile(s.API.Config.Path)
+	}
+
 	log.Info().
-		Str("Requirement", s.API.Config.XCodeVersion).
+		Str("Requirement", req).
 		Msg("Finding XCode installation")
 
-	r, err := semver.ParseRange(s.API.Config.XCodeVersion)
+	r, err := parseRequirement(req)
 	if err != nil {
-		fmt.Println("err :::", err)
 		return nil, fmt.Errorf("%v (%v)", ErrParsing, err)
 	}
 
-
 	// Find a equal match
 	target, err := s.findMatch(ctx, r, s.isMatchingRequirement)
 
@@ -53,7 +59,7 @@ func (s selectService) Find(ctx context.Context) (*api.Install, error) {
 }
 
 func (s *selectService) isMatchingRequirement(i *api.Install, r semver.Range) (bool, error) {
-	v, err := semver.ParseTolerant(i.Version)
+	v, err := parseVersion(i.Version)
 	if err != nil {
 		return false, err
 	}
@@ -63,7 +69,7 @@ func (s *selectService) isMatchingRequirement(i *api.Install, r semver.Range) (b
 
 func (s *selectService) findMatch(
 	ctx context.Context,
-	r semver.Range,
+	r requirement,
 	valid func(install *api.Install, r semver.Range) (bool, error),
 ) (*api.Install, error) {
 	// Resolve the list of candidates
@@ -75,8 +81,7 @@ func (s *selectService) findMatch(
 	// Iterate on installs
 	var installs []*api.Install
 	for _, install := range list {
-		res, err := valid(install, r)
-		fmt.Println("res,", res, err)
+		res, err := r.matches(install, valid)
 		if err != nil {
 			log.Err(err)
 			continue
@@ -99,21 +104,57 @@ func (s *selectService) findMatch(
 }
 
 func sortInstalls(installs []*api.Install) {
-	sort.Slice(installs, func(i, j int) bool {
+	sort.SliceStable(installs, func(i, j int) bool {
 		return compareInstall(installs[i], installs[j])
 	})
 }
 
+// compareInstall is true when the first install is more recent, the releases coming before
+// the betas of the same version, then the higher build numbers
 func compareInstall(i1 *api.Install, i2 *api.Install) bool {
-	v1, err := semver.Parse(i1.Version)
+	v1, err := parseVersion(i1.Version)
 	if err != nil {
 		return false
 	}
 
-	v2, err := semver.Parse(i2.Version)
+	v2, err := parseVersion(i2.Version)
 	if err != nil {
 		return false
 	}
 
-	return v1.GT(v2)
+	if !v1.Equals(v2) {
+		return v1.GT(v2)
+	}
+
+	if i1.Beta != i2.Beta {
+		return !i1.Beta
+	}
+
+	return compareBuild(i1.Build, i2.Build) > 0
+}
+
+// compareBuild compares the build numbers, like 15A240d and 15A5160n, by major version,
+// minor letter, build and patch letter
+func compareBuild(b1, b2 string) int {
+	m1, m2 := buildRegexp.FindStringSubmatch(b1), buildRegexp.FindStringSubmatch(b2)
+	if m1 == nil || m2 == nil {
+		return strings.Compare(b1, b2)
+	}
+
+	for i := 1; i < len(m1); i++ {
+		n1, err1 := strconv.Atoi(m1[i])
+		n2, err2 := strconv.Atoi(m2[i])
+		switch {
+		case err1 == nil && err2 == nil && n1 != n2:
+			if n1 > n2 {
+				return 1
+			}
+			return -1
+
+		case (err1 != nil || err2 != nil) && m1[i] != m2[i]:
+			return strings.Compare(m1[i], m2[i])
+		}
+	}
+
+	return 0
 }
@@ -4,6 +4,9 @@ import (
 	"context"
 	"dothething/internal/api"
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/blang/semver"
@@ -139,6 +142,64 @@ func TestCompareInstall(t *testing.T) {
 	}
 }
 
+func (s *selectSuite) TestFindFromVersionFile() {
+	// setup: the project requiring a build number, the configuration being empty
+	dir, err := ioutil.TempDir("", "project")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, VersionFile), []byte("15A240d\n"), 0644))
+	s.API.Config.Path = filepath.Join(dir, "Dummy.xcodeproj")
+
+	release := api.Install{Version: "15.0", Build: "15A240d"}
+	beta := api.Install{Version: "15.1", Build: "15C5028h", Beta: true}
+	s.ls.On("List", mock.Anything).Return([]*api.Install{&beta, &release}, nil)
+
+	// when:
+	i, err := s.subject.Find(context.Background())
+
+	// then:
+	s.NoError(err)
+	s.Equal(&release, i)
+}
+
+func (s *selectSuite) TestFindLatestByDefault() {
+	// setup:
+	release := api.Install{Version: "15.0", Build: "15A240d"}
+	patch := api.Install{Version: "15.0.1", Build: "15A507"}
+	beta := api.Install{Version: "15.1", Build: "15C5028h", Beta: true}
+	s.ls.On("List", mock.Anything).Return([]*api.Install{&release, &beta, &patch}, nil)
+	s.API.Config.Path = filepath.Join(os.TempDir(), "missing", "Dummy.xcodeproj")
+
+	// when:
+	i, err := s.subject.Find(context.Background())
+
+	// then: the betas are only selected on demand
+	s.NoError(err)
+	s.Equal(&patch, i)
+
+	// when:
+	s.API.Config.XCodeVersion = "latest beta"
+	i, err = s.subject.Find(context.Background())
+
+	// then:
+	s.NoError(err)
+	s.Equal(&beta, i)
+}
+
+func TestCompareBetaInstall(t *testing.T) {
+	// setup:
+	release := api.Install{Version: "15.1", Build: "15C65"}
+	beta := api.Install{Version: "15.1", Build: "15C5028h", Beta: true}
+	older := api.Install{Version: "15.1 beta 2", Build: "15C5042i", Beta: true}
+
+	// then:
+	assert.True(t, compareInstall(&release, &beta))
+	assert.False(t, compareInstall(&beta, &release))
+	assert.True(t, compareInstall(&beta, &older))
+	assert.True(t, compareBuild("15A507", "15A240d") > 0)
+	assert.True(t, compareBuild("15C65", "15A507") > 0)
+}
+
 func TestSortInstalls(t *testing.T) {
 	// setup:
 	cases := []*api.Install{}
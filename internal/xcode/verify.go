@@ -0,0 +1,254 @@
+package xcode
+
+import (
+	"context"
+	"dothething/internal/api"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// FlagShowSDKs lists the SDKs of the install
+	FlagShowSDKs = "-showsdks"
+
+	// SimCtl the simulators utility of the install
+	SimCtl = "simctl"
+
+	// The kinds of the install checks
+	CheckRuntime = "runtime"
+	CheckSDK     = "sdk"
+)
+
+// platformSettings the deployment target build settings, with the SDK and the simulator
+// runtime platform they require
+var platformSettings = []struct {
+	setting string
+	sdk     string
+	runtime string
+}{
+	{setting: "IPHONEOS_DEPLOYMENT_TARGET", sdk: "iphoneos", runtime: "iOS"},
+	{setting: "MACOSX_DEPLOYMENT_TARGET", sdk: "macosx"},
+	{setting: "TVOS_DEPLOYMENT_TARGET", sdk: "appletvos", runtime: "tvOS"},
+	{setting: "WATCHOS_DEPLOYMENT_TARGET", sdk: "watchos", runtime: "watchOS"},
+	{setting: "XROS_DEPLOYMENT_TARGET", sdk: "xros", runtime: "visionOS"},
+}
+
+// sdk a SDK of xcodebuild -showsdks -json
+type sdk struct {
+	CanonicalName string `json:"canonicalName"`
+	DisplayName   string `json:"displayName"`
+	Platform      string `json:"platform"`
+	SDKVersion    string `json:"sdkVersion"`
+}
+
+// simulatorRuntime a runtime of simctl list runtimes -j
+type simulatorRuntime struct {
+	Identifier  string `json:"identifier"`
+	IsAvailable bool   `json:"isAvailable"`
+	Name        string `json:"name"`
+	Platform    string `json:"platform"`
+	Version     string `json:"version"`
+}
+
+// verifyService checks the installs against the requirements of the project
+type verifyService struct{ *api.API }
+
+// NewVerifyService create a new instance of the install verification service
+func NewVerifyService(api *api.API) api.VerifyService {
+	return verifyService{api}
+}
+
+// Requirements resolves the SDKs and the simulator runtimes of the deployment targets of the
+// configuration of the targets, over the project one, and the runtime of the configured
+// simulator destination
+func (s verifyService) Requirements(ctx context.Context) (api.InstallRequirements, error) {
+	var res api.InstallRequirements
+
+	if s.API.Config.Path != "" {
+		p, err := s.API.XCodeProjectService.Parse(ctx)
+		if err != nil {
+			return res, err
+		}
+
+		// The targets inherit the settings of the project configuration of the same name
+		project := map[string]map[string]string{}
+		for _, c := range p.Pbx.BuildConfigurationList.BuildConfiguration {
+			project[c.Name] = c.BuildSettings
+		}
+
+		for _, t := range p.Pbx.Targets {
+			for _, c := range t.BuildConfigurationList.BuildConfiguration {
+				if s.API.Config.Configuration != "" && c.Name != s.API.Config.Configuration {
+					continue
+				}
+
+				settings := map[string]string{}
+				for k, v := range project[c.Name] {
+					settings[k] = v
+				}
+				for k, v := range c.BuildSettings {
+					settings[k] = v
+				}
+
+				addSettingsRequirements(&res, settings)
+			}
+		}
+	}
+
+	d := s.API.Config.Destination
+	if strings.HasSuffix(d.Platform, " Simulator") {
+		res.Runtimes = addRequirement(res.Runtimes, api.PlatformRequirement{
+			MinimumVersion: d.OS,
+			Name:           strings.TrimSuffix(d.Platform, " Simulator"),
+		})
+	}
+
+	return res, nil
+}
+
+// addSettingsRequirements adds the requirements of the deployment targets of the SDK of the
+// build settings, all of them when the SDK is not set
+func addSettingsRequirements(res *api.InstallRequirements, settings map[string]string) {
+	root := settings["SDKROOT"]
+	var found bool
+	for _, p := range platformSettings {
+		v := settings[p.setting]
+		if v == "" || (root != "" && root != p.sdk) {
+			continue
+		}
+
+		found = true
+		res.SDKs = addRequirement(res.SDKs, api.PlatformRequirement{MinimumVersion: v, Name: p.sdk})
+		if p.runtime != "" {
+			res.Runtimes = addRequirement(res.Runtimes, api.PlatformRequirement{MinimumVersion: v, Name: p.runtime})
+		}
+	}
+
+	if !found && root != "" {
+		res.SDKs = addRequirement(res.SDKs, api.PlatformRequirement{Name: root})
+	}
+}
+
+// addRequirement adds the requirement, keeping the highest minimum version by name
+func addRequirement(list []api.PlatformRequirement, r api.PlatformRequirement) []api.PlatformRequirement {
+	for i, l := range list {
+		if !strings.EqualFold(l.Name, r.Name) {
+			continue
+		}
+
+		if atLeast(r.MinimumVersion, l.MinimumVersion) {
+			list[i].MinimumVersion = r.MinimumVersion
+		}
+
+		return list
+	}
+
+	return append(list, r)
+}
+
+// Verify checks that the install provides the SDKs and the available simulator runtimes of
+// the requirements
+func (s verifyService) Verify(
+	ctx context.Context,
+	i *api.Install,
+	r api.InstallRequirements,
+) (api.InstallVerification, error) {
+	res := api.InstallVerification{Install: i}
+
+	var sdks []sdk
+	if len(r.SDKs) > 0 {
+		b, err := s.API.Exec.
+			CommandContext(ctx, filepath.Join(i.DevPath, "usr", "bin", Cmd), FlagShowSDKs, FlagJSON).
+			Output()
+		if err != nil {
+			return res, fmt.Errorf("failed to list the SDKs of %v (%v)", i.Path, err)
+		}
+
+		if err := json.Unmarshal(b, &sdks); err != nil {
+			return res, fmt.Errorf("failed to parse the SDKs of %v (%v)", i.Path, err)
+		}
+	}
+
+	var runtimes struct {
+		Runtimes []simulatorRuntime `json:"runtimes"`
+	}
+	if len(r.Runtimes) > 0 {
+		b, err := s.API.Exec.
+			CommandContext(ctx, filepath.Join(i.DevPath, "usr", "bin", SimCtl), "list", "runtimes", "-j").
+			Output()
+		if err != nil {
+			return res, fmt.Errorf("failed to list the simulator runtimes of %v (%v)", i.Path, err)
+		}
+
+		if err := json.Unmarshal(b, &runtimes); err != nil {
+			return res, fmt.Errorf("failed to parse the simulator runtimes of %v (%v)", i.Path, err)
+		}
+	}
+
+	for _, req := range r.SDKs {
+		c := api.InstallCheck{Kind: CheckSDK, Requirement: formatRequirement(req)}
+		for _, sdk := range sdks {
+			if strings.EqualFold(sdk.Platform, req.Name) && atLeast(sdk.SDKVersion, req.MinimumVersion) {
+				c.Found, c.OK = sdk.CanonicalName, true
+				break
+			}
+		}
+		res.Checks = append(res.Checks, c)
+	}
+
+	for _, req := range r.Runtimes {
+		c := api.InstallCheck{Kind: CheckRuntime, Requirement: formatRequirement(req)}
+		for _, rt := range runtimes.Runtimes {
+			if rt.IsAvailable && strings.EqualFold(rt.Platform, req.Name) && atLeast(rt.Version, req.MinimumVersion) {
+				c.Found, c.OK = rt.Name, true
+				break
+			}
+		}
+		res.Checks = append(res.Checks, c)
+	}
+
+	return res, nil
+}
+
+// ParsePlatformRequirement parses a requirement like "iOS 17.0" or "iphoneos"
+func ParsePlatformRequirement(s string) api.PlatformRequirement {
+	f := strings.Fields(s)
+	if len(f) < 2 {
+		return api.PlatformRequirement{Name: strings.TrimSpace(s)}
+	}
+
+	return api.PlatformRequirement{
+		MinimumVersion: f[len(f)-1],
+		Name:           strings.Join(f[:len(f)-1], " "),
+	}
+}
+
+func formatRequirement(r api.PlatformRequirement) string {
+	if r.MinimumVersion == "" {
+		return r.Name
+	}
+
+	return fmt.Sprintf("%v >= %v", r.Name, r.MinimumVersion)
+}
+
+// atLeast is true when the version is greater than or equal to the minimum version, any
+// version matching an empty minimum
+func atLeast(version, minimum string) bool {
+	if minimum == "" {
+		return true
+	}
+
+	v, err := parseVersion(version)
+	if err != nil {
+		return false
+	}
+
+	m, err := parseVersion(minimum)
+	if err != nil {
+		return false
+	}
+
+	return v.GTE(m)
+}
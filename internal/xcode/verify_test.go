@@ -0,0 +1,141 @@
+package xcode
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"dothething/internal/api"
+	"dothething/internal/utiltest"
+	"dothething/internal/xcode/pbx"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const showSDKs = `[
+  {"canonicalName": "iphoneos17.0", "displayName": "iOS 17.0", "platform": "iphoneos", "sdkVersion": "17.0"},
+  {"canonicalName": "iphonesimulator17.0", "displayName": "Simulator - iOS 17.0", "platform": "iphonesimulator", "sdkVersion": "17.0"},
+  {"canonicalName": "macosx14.0", "displayName": "macOS 14.0", "platform": "macosx", "sdkVersion": "14.0"}
+]`
+
+const listRuntimes = `{
+  "runtimes": [
+    {"identifier": "com.apple.CoreSimulator.SimRuntime.iOS-16-4", "isAvailable": true, "name": "iOS 16.4", "platform": "iOS", "version": "16.4"},
+    {"identifier": "com.apple.CoreSimulator.SimRuntime.iOS-17-0", "isAvailable": false, "name": "iOS 17.0", "platform": "iOS", "version": "17.0"}
+  ]
+}`
+
+func TestVerify(t *testing.T) {
+	// setup:
+	i := &api.Install{Path: "/Applications/Xcode.app", DevPath: "/Applications/Xcode.app/Contents/Developer"}
+	exec := new(utiltest.MockExecutor)
+	exec.MockCommandContext(filepath.Join(i.DevPath, "usr", "bin", Cmd), []string{FlagShowSDKs, FlagJSON}, showSDKs, nil)
+	exec.MockCommandContext(filepath.Join(i.DevPath, "usr", "bin", SimCtl), []string{"list", "runtimes", "-j"}, listRuntimes, nil)
+
+	subject := NewVerifyService(&api.API{Config: &api.Config{}, Exec: exec})
+
+	// when:
+	res, err := subject.Verify(context.Background(), i, api.InstallRequirements{
+		SDKs: []api.PlatformRequirement{
+			{Name: "iphoneos", MinimumVersion: "15.0"},
+			{Name: "xros"},
+		},
+		Runtimes: []api.PlatformRequirement{
+			{Name: "iOS", MinimumVersion: "16.0"},
+			{Name: "iOS", MinimumVersion: "17.0"},
+		},
+	})
+
+	// then: the unavailable runtimes are not matched
+	require.NoError(t, err)
+	assert.Equal(t, []api.InstallCheck{
+		{Kind: CheckSDK, Requirement: "iphoneos >= 15.0", Found: "iphoneos17.0", OK: true},
+		{Kind: CheckSDK, Requirement: "xros"},
+		{Kind: CheckRuntime, Requirement: "iOS >= 16.0", Found: "iOS 16.4", OK: true},
+		{Kind: CheckRuntime, Requirement: "iOS >= 17.0"},
+	}, res.Checks)
+	assert.False(t, res.OK())
+}
+
+func TestRequirements(t *testing.T) {
+	// setup:
+	var res api.InstallRequirements
+
+	// when: an iOS target, a macOS one and a target inheriting its SDK
+	addSettingsRequirements(&res, map[string]string{"SDKROOT": "iphoneos", "IPHONEOS_DEPLOYMENT_TARGET": "15.0"})
+	addSettingsRequirements(&res, map[string]string{"SDKROOT": "macosx", "IPHONEOS_DEPLOYMENT_TARGET": "16.0"})
+	addSettingsRequirements(&res, map[string]string{"IPHONEOS_DEPLOYMENT_TARGET": "16.0"})
+
+	// then: the highest deployment target is kept
+	assert.Equal(t, []api.PlatformRequirement{
+		{Name: "iphoneos", MinimumVersion: "16.0"},
+		{Name: "macosx"},
+	}, res.SDKs)
+	assert.Equal(t, []api.PlatformRequirement{{Name: "iOS", MinimumVersion: "16.0"}}, res.Runtimes)
+}
+
+// verifyProject parses the same project
+type verifyProject struct{ pbx.PBXProject }
+
+func (p verifyProject) Parse(ctx context.Context) (api.Project, error) {
+	return api.Project{Pbx: p.PBXProject}, nil
+}
+
+func TestRequirementsOfProject(t *testing.T) {
+	// setup: the deployment target set on the project, a target overriding it
+	configurations := func(debug, release map[string]string) pbx.XCConfigurationList {
+		return pbx.XCConfigurationList{BuildConfiguration: []pbx.XCBuildConfiguration{
+			{Name: "Debug", BuildSettings: debug},
+			{Name: "Release", BuildSettings: release},
+		}}
+	}
+	a := &api.API{Config: &api.Config{Path: "Dummy.xcodeproj", Configuration: "Release"}}
+	a.XCodeProjectService = verifyProject{pbx.PBXProject{
+		BuildConfigurationList: configurations(
+			map[string]string{"SDKROOT": "iphoneos", "IPHONEOS_DEPLOYMENT_TARGET": "17.0"},
+			map[string]string{"SDKROOT": "iphoneos", "IPHONEOS_DEPLOYMENT_TARGET": "15.0"},
+		),
+		Targets: []pbx.NativeTarget{
+			{Name: "App", BuildConfigurationList: configurations(
+				map[string]string{},
+				map[string]string{"PRODUCT_NAME": "App"},
+			)},
+			{Name: "Mac", BuildConfigurationList: configurations(
+				map[string]string{},
+				map[string]string{"SDKROOT": "macosx", "MACOSX_DEPLOYMENT_TARGET": "13.0"},
+			)},
+		},
+	}}
+
+	// when:
+	res, err := NewVerifyService(a).Requirements(context.Background())
+
+	// then:
+	require.NoError(t, err)
+	assert.Equal(t, []api.PlatformRequirement{
+		{Name: "iphoneos", MinimumVersion: "15.0"},
+		{Name: "macosx", MinimumVersion: "13.0"},
+	}, res.SDKs)
+	assert.Equal(t, []api.PlatformRequirement{{Name: "iOS", MinimumVersion: "15.0"}}, res.Runtimes)
+}
+
+func TestRequirementsOfDestination(t *testing.T) {
+	// setup:
+	subject := NewVerifyService(&api.API{Config: &api.Config{
+		Destination: api.Destination{Platform: "iOS Simulator", OS: "17.0"},
+	}})
+
+	// when:
+	res, err := subject.Requirements(context.Background())
+
+	// then:
+	require.NoError(t, err)
+	assert.Equal(t, []api.PlatformRequirement{{Name: "iOS", MinimumVersion: "17.0"}}, res.Runtimes)
+	assert.Empty(t, res.SDKs)
+}
+
+func TestParsePlatformRequirement(t *testing.T) {
+	assert.Equal(t, api.PlatformRequirement{Name: "iOS", MinimumVersion: "17.0"}, ParsePlatformRequirement("iOS 17.0"))
+	assert.Equal(t, api.PlatformRequirement{Name: "iphoneos"}, ParsePlatformRequirement("iphoneos"))
+}